@@ -0,0 +1,301 @@
+// Package audit keeps an append-only log of the destructive actions portik takes
+// (kill, restart, reload and their TUI counterparts): who did what to which
+// process, how, and with what result.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/config"
+	"github.com/pratik-anurag/portik/internal/eventlog"
+	"github.com/pratik-anurag/portik/internal/model"
+	"github.com/pratik-anurag/portik/internal/sys"
+)
+
+// Actions.
+const (
+	Kill    = "kill"
+	Signal  = "signal" // kill --signal other than TERM
+	Restart = "restart"
+	Reload  = "reload"
+	Stop    = "stop" // container stop from a batch kill
+)
+
+// Entry is one action. Field names are part of the log format; add, never rename.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Host      string    `json:"host,omitempty"`
+	User      string    `json:"user"`                // who ran portik
+	SudoUser  string    `json:"sudo_user,omitempty"` // who ran sudo portik
+	Source    string    `json:"source"`              // cli|tui
+	Action    string    `json:"action"`
+	Port      int       `json:"port,omitempty"`
+	Proto     string    `json:"proto,omitempty"`
+	PID       int32     `json:"pid,omitempty"`
+	PIDs      []int32   `json:"pids,omitempty"` // --tree/--pgid members
+	Owner     string    `json:"owner,omitempty"`
+	OwnerUser string    `json:"owner_user,omitempty"`
+	Cmdline   string    `json:"cmdline,omitempty"`
+	Container string    `json:"container,omitempty"`
+	Method    string    `json:"method,omitempty"` // SIGTERM, SIGHUP to pgid 12, systemctl restart x.service, ...
+	Force     bool      `json:"force,omitempty"`
+	OK        bool      `json:"ok"`
+	Result    string    `json:"result,omitempty"`
+	Details   string    `json:"details,omitempty"`
+}
+
+// For starts an entry for an action on the listener l of rep.
+func For(action, source string, rep model.Report, l model.Listener) Entry {
+	return Entry{
+		Source:    source,
+		Action:    action,
+		Port:      rep.Port,
+		Proto:     rep.Proto,
+		PID:       l.PID,
+		Owner:     l.ProcName,
+		OwnerUser: l.User,
+		Cmdline:   l.Cmdline,
+		Container: rep.Docker.ContainerName,
+	}
+}
+
+// Done records the outcome of the action.
+func (e Entry) Done(res sys.ActionResult) Entry {
+	e.OK = res.ExitCode == 0
+	e.Result = res.Summary
+	e.Details = res.Details
+	return e
+}
+
+// SharedPath is the machine-wide log used by default when it is writable, so that
+// "who killed the Postgres on 5432" can be answered across users. An administrator
+// enables it once for a group of operators, with a setgid directory so the log
+// belongs to that group (sticky, so members cannot delete it):
+//
+//	install -d -m 3770 -g portik /var/log/portik
+//	install -m 0620 -g portik /dev/null /var/log/portik/audit.jsonl
+//	chattr +a /var/log/portik/audit.jsonl   # append-only, even for its owner
+const SharedPath = "/var/log/portik/audit.jsonl"
+
+// sharedMode lets the group append but not read or rewrite a shared log portik
+// creates; only the file system can forbid truncating it (chattr +a).
+const sharedMode = 0o620
+
+// Path returns the audit log: $PORTIK_AUDIT_LOG, audit.path from config.yaml,
+// SharedPath when this user can append to it, or ~/.portik/audit.jsonl.
+func Path() (string, error) {
+	if p := strings.TrimSpace(os.Getenv("PORTIK_AUDIT_LOG")); p != "" {
+		return p, nil
+	}
+	if cfg, err := config.Load(); err == nil && cfg.Audit.Path != "" {
+		return cfg.Audit.Path, nil
+	}
+	if appendable(SharedPath) {
+		return SharedPath, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".portik", "audit.jsonl"), nil
+}
+
+// appendable reports whether path can be appended to, or created in its
+// existing directory.
+func appendable(path string) bool {
+	if f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0); err == nil {
+		_ = f.Close()
+		return true
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".probe-*")
+	if err != nil {
+		return false
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+	return true
+}
+
+// Record stamps e with the time, host and invoking user and appends it as one line.
+// The file is only ever opened for appending. With audit.syslog the entry is also
+// sent to syslog; a syslog failure is returned only if the file write succeeded.
+func Record(e Entry) error {
+	p, err := Path()
+	if err != nil {
+		return err
+	}
+	e = stamp(e)
+	err = Append(p, e)
+	if cfg, cerr := config.Load(); cerr == nil && cfg.Audit.Syslog {
+		if serr := toSyslog(e); err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+func stamp(e Entry) Entry {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Host == "" {
+		e.Host, _ = os.Hostname()
+	}
+	if e.User == "" {
+		if u, err := user.Current(); err == nil {
+			e.User = u.Username
+		}
+		e.SudoUser = os.Getenv("SUDO_USER")
+	}
+	return e
+}
+
+// toSyslog sends e to the local syslog as an eventlog "action" entry.
+func toSyslog(e Entry) error {
+	s, err := eventlog.NewSyslogSink("")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return s.Write(syslogEntry(e))
+}
+
+// syslogEntry renders e as an event log entry: the target in the usual fields, who
+// did it and the outcome in Msg.
+func syslogEntry(e Entry) eventlog.Entry {
+	who := e.User
+	if e.SudoUser != "" {
+		who = e.SudoUser + " (sudo " + e.User + ")"
+	}
+	msg := who + " " + e.Action
+	if e.Method != "" {
+		msg += " (" + e.Method + ")"
+	}
+	msg += ": " + e.Result
+	out := eventlog.Entry{
+		Time: e.Time, Level: eventlog.LevelInfo, Event: eventlog.Action, Msg: msg, Host: e.Host,
+		Port: e.Port, Proto: e.Proto, PID: e.PID, Process: e.Owner, User: e.OwnerUser,
+		Container: e.Container, Kind: e.Action, Details: e.Cmdline,
+	}
+	if !e.OK {
+		out.Level, out.Error = eventlog.LevelWarn, e.Details
+	}
+	return out
+}
+
+// Append writes e to the log at path.
+func Append(path string, e Entry) error {
+	b, err := json.Marshal(stamp(e))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	_, statErr := os.Stat(path)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if errors.Is(statErr, os.ErrNotExist) && !underHome(path) {
+		// a shared log must stay appendable for the group that did not create it
+		// (the umask would otherwise leave it 0644), but never world-writable
+		_ = f.Chmod(sharedMode)
+	}
+	// one write per line: O_APPEND keeps concurrent writers from interleaving
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func underHome(path string) bool {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(home, path)
+	return err == nil && !strings.HasPrefix(rel, "..")
+}
+
+// Read returns every entry in the log at path, oldest first. A missing log is
+// empty; unparsable lines (a torn write) are skipped.
+func Read(path string) ([]Entry, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []Entry
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for sc.Scan() {
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) == nil && !e.Time.IsZero() {
+			out = append(out, e)
+		}
+	}
+	return out, sc.Err()
+}
+
+// Query selects entries. Empty fields match everything; strings compare
+// case-insensitively.
+type Query struct {
+	Since  time.Time
+	Until  time.Time // zero = now
+	User   string    // invoking user or sudo user
+	Action string
+	Port   int
+	PID    int32
+	Owner  string // process or container name
+	Failed bool   // only actions that failed
+}
+
+func (q Query) Match(e Entry) bool {
+	if e.Time.Before(q.Since) || !q.Until.IsZero() && e.Time.After(q.Until) {
+		return false
+	}
+	if q.User != "" && !strings.EqualFold(e.User, q.User) && !strings.EqualFold(e.SudoUser, q.User) {
+		return false
+	}
+	if q.Action != "" && !strings.EqualFold(e.Action, q.Action) {
+		return false
+	}
+	if q.Port != 0 && e.Port != q.Port {
+		return false
+	}
+	if q.PID != 0 && e.PID != q.PID {
+		return false
+	}
+	if q.Owner != "" && !strings.EqualFold(e.Owner, q.Owner) && !strings.EqualFold(e.Container, q.Owner) {
+		return false
+	}
+	if q.Failed && e.OK {
+		return false
+	}
+	return true
+}
+
+// Filter returns the entries matching q, oldest first.
+func Filter(entries []Entry, q Query) []Entry {
+	var out []Entry
+	for _, e := range entries {
+		if q.Match(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/model"
+	"github.com/pratik-anurag/portik/internal/sys"
+)
+
+func TestAppendReadQuery(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SUDO_USER", "alice")
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	t.Setenv("PORTIK_AUDIT_LOG", path)
+	if p, err := Path(); err != nil || p != path {
+		t.Fatalf("Path() = %q, %v", p, err)
+	}
+
+	rep := model.Report{Port: 5432, Proto: "tcp", Docker: model.DockerMap{ContainerName: "db"}}
+	l := model.Listener{PID: 42, ProcName: "postgres", User: "postgres", Cmdline: "postgres -D /data"}
+	kill := For(Kill, "cli", rep, l)
+	kill.Method, kill.Force = "SIGTERM, SIGKILL after 5s", true
+	if err := Record(kill.Done(sys.ActionResult{Summary: "Process terminated"})); err != nil {
+		t.Fatal(err)
+	}
+	rep.Port = 8080
+	restart := For(Restart, "tui", rep, model.Listener{PID: 7, ProcName: "node"})
+	if err := Record(restart.Done(sys.ActionResult{ExitCode: 1, Summary: "Failed to start process"})); err != nil {
+		t.Fatal(err)
+	}
+	// a log outside the home directory is shared: its group appends, nobody else
+	if st, err := os.Stat(path); err != nil || st.Mode().Perm() != 0o620 {
+		t.Fatalf("shared log mode: %v, %v", st.Mode(), err)
+	}
+	// a torn trailing line from a crashed writer is skipped
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	_, _ = f.WriteString(`{"time":"2024-`)
+	_ = f.Close()
+
+	all, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("want 2 entries, got %+v", all)
+	}
+	e := all[0]
+	if e.User == "" || e.SudoUser != "alice" || e.Host == "" || !e.OK || !e.Force || e.Container != "db" ||
+		e.OwnerUser != "postgres" || e.Cmdline != "postgres -D /data" || time.Since(e.Time) > time.Minute {
+		t.Fatalf("entry not stamped/filled: %+v", e)
+	}
+
+	for name, tc := range map[string]struct {
+		q    Query
+		want int
+	}{
+		"all":          {Query{}, 2},
+		"sudo user":    {Query{User: "ALICE"}, 2},
+		"port":         {Query{Port: 5432}, 1},
+		"owner is ctr": {Query{Owner: "db"}, 2},
+		"action":       {Query{Action: "restart"}, 1},
+		"failed":       {Query{Failed: true}, 1},
+		"pid":          {Query{PID: 42}, 1},
+		"window":       {Query{Since: time.Now().Add(time.Hour)}, 0},
+	} {
+		if got := Filter(all, tc.q); len(got) != tc.want {
+			t.Errorf("%s: got %d entries, want %d", name, len(got), tc.want)
+		}
+	}
+}
+
+func TestSyslogEntry(t *testing.T) {
+	e := Entry{Time: time.Now(), User: "root", SudoUser: "alice", Action: Kill, Port: 5432, Proto: "tcp", PID: 42,
+		Owner: "postgres", Method: "SIGTERM", OK: false, Result: "Failed to kill process", Details: "still alive after SIGKILL"}
+	got := syslogEntry(e)
+	if got.Msg != "alice (sudo root) kill (SIGTERM): Failed to kill process" || got.Level != "warn" ||
+		got.Port != 5432 || got.PID != 42 || got.Process != "postgres" || got.Error == "" {
+		t.Fatalf("syslog entry: %+v", got)
+	}
+}
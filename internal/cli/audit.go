@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/audit"
+	"github.com/pratik-anurag/portik/internal/render"
+	"github.com/pratik-anurag/portik/internal/sys"
+)
+
+// audited appends e with the outcome res to the audit log. A write failure is
+// reported but does not change the command's result: the action already happened.
+func audited(e audit.Entry, res sys.ActionResult) {
+	if err := audit.Record(e.Done(res)); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: audit log:", err)
+	}
+}
+
+// portik audit [--since 7d] [--until 1h] [--user U] [--action kill] [--port P] [--pid N] [--owner NAME] [--failed] [--json]
+func runAudit(args []string) int {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	var sinceStr, untilStr string
+	var q audit.Query
+	var pid, limit int
+	var jsonOut bool
+	fs.StringVar(&sinceStr, "since", "7d", "how far back: 24h|7d|30d")
+	fs.StringVar(&untilStr, "until", "", "end of the window, as an age (e.g. 1h)")
+	fs.StringVar(&q.User, "user", "", "invoking user (or the user behind sudo)")
+	fs.StringVar(&q.Action, "action", "", "kill|signal|restart|reload|stop")
+	fs.IntVar(&q.Port, "port", 0, "port acted on")
+	fs.IntVar(&pid, "pid", 0, "target pid")
+	fs.StringVar(&q.Owner, "owner", "", "target process or container name")
+	fs.BoolVar(&q.Failed, "failed", false, "only actions that failed")
+	fs.IntVar(&limit, "limit", 0, "show only the newest N entries")
+	fs.BoolVar(&jsonOut, "json", false, "output JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	since, err := parseSince(sinceStr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "audit: invalid --since")
+		return 2
+	}
+	q.Since = time.Now().Add(-since)
+	if untilStr != "" {
+		d, err := parseSince(untilStr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "audit: invalid --until")
+			return 2
+		}
+		q.Until = time.Now().Add(-d)
+	}
+	q.PID = int32(pid)
+
+	path, err := audit.Path()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	all, err := audit.Read(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	entries := audit.Filter(all, q)
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(map[string]any{"log": path, "entries": entries})
+		return 0
+	}
+	if len(entries) == 0 {
+		fmt.Printf("No matching actions in %s.\n", path)
+		return 0
+	}
+	fmt.Print(render.Audit(entries))
+	return 0
+}
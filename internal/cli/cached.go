@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/daemon"
+	"github.com/pratik-anurag/portik/internal/history"
+	"github.com/pratik-anurag/portik/internal/inspect"
+	"github.com/pratik-anurag/portik/internal/model"
+)
+
+// cacheMaxAge is how old a daemon report may be to be shown as current; the
+// daemon re-inspects older ones before answering.
+const cacheMaxAge = 2 * time.Second
+
+// daemonClient returns a client for the running daemon, or nil when there is none
+// (or --no-daemon was given).
+func daemonClient(c *commonFlags) *daemon.Client {
+	if c.NoDaemon {
+		return nil
+	}
+	cl, err := daemon.Connect()
+	if err != nil {
+		return nil
+	}
+	return cl
+}
+
+// lookupPort returns the daemon's cached report when it monitors port with compatible
+// options and the report is at most cacheMaxAge old, and otherwise inspects live and
+// records the result. The daemon records its
+// own polls, so cached reports are not recorded again.
+func lookupPort(port int, c *commonFlags) (model.Report, *daemon.CachedReport, error) {
+	if cl := daemonClient(c); cl != nil {
+		if cr, ok := cl.Report(port, c.Proto, c.Docker, cacheMaxAge); ok {
+			return cr.Report, &cr, nil
+		}
+	}
+	rep, err := inspect.InspectPort(port, c.Proto, inspect.Options{EnableDocker: c.Docker, IncludeConnections: false})
+	if err != nil {
+		return rep, nil, err
+	}
+	_ = history.Record(rep)
+	return rep, nil, nil
+}
+
+// cachedReports returns the daemon's reports for whichever of ports it serves, at
+// most cacheMaxAge old; the rest are left to a live scan.
+func cachedReports(ports []int, c *commonFlags) map[int]model.Report {
+	cl := daemonClient(c)
+	if cl == nil {
+		return nil
+	}
+	var served []int
+	for _, p := range ports {
+		if cl.Serves(p, c.Proto, c.Docker) {
+			served = append(served, p)
+		}
+	}
+	if len(served) == 0 {
+		return nil
+	}
+	m, err := cl.Reports(served, c.Proto, cacheMaxAge)
+	if err != nil {
+		return nil
+	}
+	out := make(map[int]model.Report, len(m))
+	for p, cr := range m {
+		if cr.Age() <= cacheMaxAge {
+			out[p] = cr.Report
+		}
+	}
+	return out
+}
+
+func cachedNote(cr *daemon.CachedReport) string {
+	return fmt.Sprintf("(from portik daemon, inspected %s ago; --no-daemon for a live check)\n", cr.Age().Round(time.Second))
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pratik-anurag/portik/internal/compose"
+	"github.com/pratik-anurag/portik/internal/render"
+)
+
+// portik compose [-f compose.yaml] [-p name]
+// Lists every published port of a compose project and whether `docker compose up` can bind it.
+func runCompose(args []string) int {
+	fs := flag.NewFlagSet("compose", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	var file string
+	var project string
+	var envFile string
+	var jsonOut bool
+	var noDocker bool
+	var color string
+
+	fs.StringVar(&file, "f", "", "compose file (default: compose.yaml, compose.yml, docker-compose.yml, docker-compose.yaml)")
+	fs.StringVar(&file, "file", "", "compose file (alias of -f)")
+	fs.StringVar(&project, "p", "", "project name (default: name: in the file, else directory name)")
+	fs.StringVar(&project, "project-name", "", "project name (alias of -p)")
+	fs.StringVar(&envFile, "env-file", "", "env file for interpolation (default: .env next to the compose file)")
+	fs.BoolVar(&jsonOut, "json", false, "output JSON")
+	fs.BoolVar(&noDocker, "no-docker", false, "do not call docker (built-in parser, no container mapping)")
+	fs.StringVar(&color, "color", "auto", "color: auto|always|never")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 && file == "" {
+		file = fs.Arg(0)
+	}
+
+	p, err := compose.Load(compose.LoadOptions{
+		File:        file,
+		ProjectName: project,
+		EnvFile:     envFile,
+		NoDocker:    noDocker,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "compose:", err)
+		return 1
+	}
+
+	rows := compose.Check(p, compose.CheckOptions{Docker: !noDocker})
+	conflicts := compose.Conflicts(p, rows)
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(map[string]any{
+			"project":   p,
+			"rows":      rows,
+			"conflicts": conflicts,
+		})
+	} else {
+		fmt.Print(render.Compose(p, rows, conflicts, renderOptions(&commonFlags{Color: color})))
+	}
+
+	if len(conflicts) > 0 {
+		return 1
+	}
+	return 0
+}
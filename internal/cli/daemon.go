@@ -1,85 +1,799 @@
 package cli
 
 import (
+	"cmp"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"net"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/pratik-anurag/portik/internal/config"
+	"github.com/pratik-anurag/portik/internal/daemon"
+	"github.com/pratik-anurag/portik/internal/eventlog"
 	"github.com/pratik-anurag/portik/internal/history"
+	"github.com/pratik-anurag/portik/internal/hooks"
 	"github.com/pratik-anurag/portik/internal/inspect"
+	"github.com/pratik-anurag/portik/internal/model"
+	"github.com/pratik-anurag/portik/internal/ports"
 	"github.com/pratik-anurag/portik/internal/render"
 )
 
-func runDaemon(args []string) int {
-	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+// daemonConfig holds the monitoring flags shared by `daemon`, `daemon start`,
+// `daemon reload` and `daemon install`.
+type daemonConfig struct {
+	c           *commonFlags
+	portsStr    string
+	intervalStr string
+	configPath  string
+	all         bool
+	includeStr  string
+	excludeStr  string
+	quiet       bool
+	alerts      bool
+	noAPI       bool
+	metricsAddr string
+	detect      history.DetectOptions
+	hooks       *hooks.Config
+	logFile     string
+	logMaxSize  int
+	logKeep     int
+	logLevel    string
+	syslog      bool
+	journald    bool
+
+	policies []daemon.Policy
+	file     string // daemon.yaml in use; "" with --ports
+	stamp    string // file's mtime and size when it was loaded
+
+	discover *daemon.Policy // with --all, the policy of every discovered port (Port unset)
+	filter   ports.Filter
+}
+
+func newDaemonFlagSet(name string) (*flag.FlagSet, *daemonConfig) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
-	c := parseCommon(fs)
+	d := &daemonConfig{c: parseCommon(fs), detect: history.DefaultDetectOptions()}
+	fs.StringVar(&d.portsStr, "ports", "", "comma-separated ports to monitor (e.g., 5432,6379,8080); default: ~/.portik/daemon.yaml")
+	fs.StringVar(&d.intervalStr, "interval", "30s", "poll interval (with --ports or --all)")
+	fs.StringVar(&d.configPath, "config", "", "per-port policies file (default ~/.portik/daemon.yaml; reloaded when it changes)")
+	fs.BoolVar(&d.all, "all", false, "discover and track every listening port (one socket enumeration per interval)")
+	fs.StringVar(&d.includeStr, "include", "", "with --all, only track these ports (e.g., 3000-9999)")
+	fs.StringVar(&d.excludeStr, "exclude", "", "with --all, never track these ports (e.g., 22,631)")
+	fs.BoolVar(&d.quiet, "quiet", false, "do not print periodic status (only errors and alerts)")
+	fs.BoolVar(&d.noAPI, "no-api", false, "do not serve the local API on ~/.portik/daemon.sock (takes effect on restart)")
+	fs.StringVar(&d.metricsAddr, "metrics-addr", "", "serve Prometheus metrics on this address (e.g. 127.0.0.1:9567; takes effect on restart)")
+	fs.BoolVar(&d.alerts, "alerts", false, "raise alerts for flapping, crash loops, squatters and first-seen owners (with --ports or --all)")
+	fs.IntVar(&d.detect.FlapChanges, "flap-changes", d.detect.FlapChanges, "owner changes within --flap-window that count as flapping")
+	fs.DurationVar(&d.detect.FlapWindow, "flap-window", d.detect.FlapWindow, "window for --flap-changes")
+	fs.IntVar(&d.detect.CrashRestarts, "crash-restarts", d.detect.CrashRestarts, "quick restarts of the same command that count as a crash loop")
+	d.hooks = addHookFlags(fs)
+	fs.StringVar(&d.logFile, "log-file", "", "append the structured event log (NDJSON) to this file; - for stdout")
+	fs.IntVar(&d.logMaxSize, "log-max-size", 10, "rotate --log-file when it reaches this many MB (0 = never)")
+	fs.IntVar(&d.logKeep, "log-keep", 5, "rotated --log-file copies to keep")
+	fs.StringVar(&d.logLevel, "log-level", eventlog.LevelInfo, "lowest event log level: info|warn|error")
+	fs.BoolVar(&d.syslog, "syslog", false, "send the event log to the local syslog (RFC 5424)")
+	fs.BoolVar(&d.journald, "journald", false, "send the event log to journald (native protocol)")
+	return fs, d
+}
+
+// validate builds the per-port policies, from --ports and the flags or from the
+// config file, or the discovery policy for --all.
+func (d *daemonConfig) validate() error {
+	if d.metricsAddr != "" {
+		if _, _, err := net.SplitHostPort(d.metricsAddr); err != nil {
+			return fmt.Errorf("invalid --metrics-addr: %v", err)
+		}
+	}
+	if !eventlog.ValidLevel(d.logLevel) {
+		return fmt.Errorf("invalid --log-level %q (info|warn|error)", d.logLevel)
+	}
+	if d.logMaxSize < 0 || d.logKeep < 1 {
+		return errors.New("--log-max-size must be >= 0 and --log-keep >= 1")
+	}
+	if d.logFile != "" && d.logFile != "-" {
+		abs, err := filepath.Abs(d.logFile)
+		if err != nil {
+			return err
+		}
+		d.logFile = abs
+	}
+	d.policies, d.file, d.stamp, d.discover = nil, "", "", nil
+	if d.all {
+		if d.portsStr != "" || d.configPath != "" {
+			return errors.New("--all discovers ports; do not combine it with --ports or --config")
+		}
+		interval, err := time.ParseDuration(d.intervalStr)
+		if err != nil || interval < time.Second {
+			return errors.New("invalid --interval")
+		}
+		if d.filter, err = ports.ParseFilter(d.includeStr, d.excludeStr); err != nil {
+			return fmt.Errorf("invalid --include/--exclude: %v", err)
+		}
+		if d.c.Proto != "tcp" && d.c.Proto != "udp" {
+			return fmt.Errorf("invalid --proto %q (tcp|udp)", d.c.Proto)
+		}
+		hookOpt, err := d.hooks.Options()
+		if err != nil {
+			return err
+		}
+		d.discover = &daemon.Policy{
+			Target: daemon.Target{Proto: d.c.Proto, Docker: d.c.Docker, IntervalSec: interval.Seconds()},
+			Spec:   "all", Alerts: d.alerts, Hooks: *d.hooks, HookOpt: hookOpt,
+		}
+		return nil
+	}
+	if d.includeStr != "" || d.excludeStr != "" {
+		return errors.New("--include and --exclude need --all")
+	}
+	if d.portsStr != "" {
+		if d.configPath != "" {
+			return errors.New("use either --ports or --config")
+		}
+		if ports, err := parsePortsList(d.portsStr); err != nil || len(ports) == 0 {
+			return fmt.Errorf("invalid --ports: %v", err)
+		}
+		if interval, err := time.ParseDuration(d.intervalStr); err != nil || interval < time.Second {
+			return errors.New("invalid --interval")
+		}
+		if _, err := d.hooks.Options(); err != nil {
+			return err
+		}
+		f := daemon.File{
+			Interval: d.intervalStr, Proto: d.c.Proto, Docker: d.c.Docker, Alerts: d.alerts, Hooks: *d.hooks,
+			Ports: []daemon.Entry{{Ports: d.portsStr}},
+		}
+		pols, err := f.Policies()
+		if err != nil {
+			return err
+		}
+		d.policies = pols
+		return nil
+	}
+
+	if d.configPath != "" {
+		// saved options must not depend on the directory the daemon starts in
+		abs, err := filepath.Abs(d.configPath)
+		if err != nil {
+			return err
+		}
+		d.configPath = abs
+	}
+	path := d.configPath
+	if path == "" {
+		def, err := daemon.ConfigPath()
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(def); err != nil {
+			return errors.New("missing --ports (e.g., --ports 5432,6379) or ~/.portik/daemon.yaml")
+		}
+		path = def
+	}
+	stamp := fileStamp(path)
+	pols, err := daemon.LoadFile(path)
+	if err != nil {
+		return err
+	}
+	d.policies, d.file, d.stamp = pols, path, stamp
+	return nil
+}
+
+// describe says what d monitors.
+func (d *daemonConfig) describe() string {
+	if p := d.discover; p != nil {
+		s := fmt.Sprintf("every listening %s port every %s", p.Proto, p.Interval())
+		if d.includeStr != "" {
+			s += ", include " + d.includeStr
+		}
+		if d.excludeStr != "" {
+			s += ", exclude " + d.excludeStr
+		}
+		return s
+	}
+	src := "--ports"
+	if d.file != "" {
+		src = d.file
+	}
+	return fmt.Sprintf("%d ports from %s", len(d.policies), src)
+}
+
+// openEventLog opens the event log sinks d asks for.
+func (d *daemonConfig) openEventLog() (*eventlog.Logger, error) {
+	var sinks []eventlog.Sink
+	fail := func(err error) (*eventlog.Logger, error) {
+		for _, s := range sinks {
+			s.Close()
+		}
+		return nil, err
+	}
+	if d.logFile != "" {
+		s, err := eventlog.NewFileSink(d.logFile, int64(d.logMaxSize)<<20, d.logKeep)
+		if err != nil {
+			return fail(err)
+		}
+		sinks = append(sinks, s)
+	}
+	if d.syslog {
+		s, err := eventlog.NewSyslogSink("")
+		if err != nil {
+			return fail(err)
+		}
+		sinks = append(sinks, s)
+	}
+	if d.journald {
+		s, err := eventlog.NewJournaldSink("")
+		if err != nil {
+			return fail(err)
+		}
+		sinks = append(sinks, s)
+	}
+	return eventlog.New(d.logLevel, func(err error) { daemonLogf("event log: %v", err) }, sinks...), nil
+}
 
-	var portsStr string
-	var intervalStr string
-	var quiet bool
-	fs.StringVar(&portsStr, "ports", "", "comma-separated ports to monitor (e.g., 5432,6379,8080)")
-	fs.StringVar(&intervalStr, "interval", "30s", "poll interval")
-	fs.BoolVar(&quiet, "quiet", false, "do not print periodic status (only errors)")
+// fileStamp identifies a version of the file at path ("" when it cannot be read).
+func fileStamp(path string) string {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", fi.ModTime().UnixNano(), fi.Size())
+}
+
+// flagArgs serializes every flag (except skip) that differs from its default, so the
+// options can be saved and re-parsed later.
+func flagArgs(fs *flag.FlagSet, skip ...string) []string {
+	var out []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if slices.Contains(skip, f.Name) {
+			return
+		}
+		if f.Value.String() != f.DefValue {
+			out = append(out, "--"+f.Name+"="+f.Value.String())
+		}
+	})
+	return out
+}
 
+func runDaemon(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "run":
+			return runDaemonForeground(args[1:])
+		case "start":
+			return runDaemonStart(args[1:])
+		case "stop":
+			return runDaemonStop(args[1:])
+		case "status":
+			return runDaemonStatus(args[1:])
+		case "reload":
+			return runDaemonReload(args[1:])
+		case "install":
+			return runDaemonInstall(args[1:])
+		}
+	}
+	return runDaemonForeground(args)
+}
+
+func daemonLogf(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, "%s portik daemon: %s\n", time.Now().Format(time.RFC3339), fmt.Sprintf(format, a...))
+}
+
+// runDaemonForeground polls the configured ports until SIGTERM/SIGINT. SIGHUP re-reads
+// --options-file (when given) and config.yaml.
+func runDaemonForeground(args []string) int {
+	fs, d := newDaemonFlagSet("daemon")
+	var pidfile string
+	var optionsFile string
+	fs.StringVar(&pidfile, "pidfile", "", "write the daemon pid to this file")
+	fs.StringVar(&optionsFile, "options-file", "", "read daemon flags from this file (re-read on SIGHUP)")
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
-	if portsStr == "" {
-		fmt.Fprintln(os.Stderr, "daemon: missing --ports (e.g., --ports 5432,6379)")
-		return 2
+	if optionsFile != "" {
+		loaded, err := loadDaemonConfig(optionsFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "daemon:", err)
+			return 2
+		}
+		d = loaded
 	}
-	ports, err := parsePortsList(portsStr)
-	if err != nil || len(ports) == 0 {
-		fmt.Fprintln(os.Stderr, "daemon: invalid --ports:", err)
+	if err := d.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "daemon:", err)
 		return 2
 	}
-	interval, err := time.ParseDuration(intervalStr)
-	if err != nil || interval < time.Second {
-		fmt.Fprintln(os.Stderr, "daemon: invalid --interval")
-		return 2
+	if pidfile != "" {
+		if err := daemon.WritePid(pidfile); err != nil {
+			fmt.Fprintln(os.Stderr, "daemon:", err)
+			return 1
+		}
+		defer daemon.RemovePid(pidfile)
 	}
 
-	fmt.Fprintf(os.Stderr, "portik daemon: monitoring %d ports every %s (history at ~/.portik/history.json)\n", len(ports), interval)
+	events, err := d.openEventLog()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "daemon: event log:", err)
+		return 1
+	}
+	r := &daemonRunner{srv: daemon.NewServer(), metrics: daemon.NewMetrics(), ports: map[string]*portState{}, dispatchers: map[string]*hooks.Dispatcher{}}
+	r.srv.Refresh = r.refresh
+	r.configure(d, events)
+	if d.metricsAddr != "" {
+		l, err := net.Listen("tcp", d.metricsAddr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "daemon: metrics:", err)
+			return 1
+		}
+		defer l.Close()
+		go func() {
+			if err := r.metrics.Serve(l); err != nil {
+				daemonLogf("metrics: %v", err)
+			}
+		}()
+		daemonLogf("metrics on http://%s/metrics", l.Addr())
+	}
+	if !d.noAPI {
+		if l := listenAPI(); l != nil {
+			defer l.Close()
+			go func() {
+				if err := r.srv.Serve(l); err != nil {
+					daemonLogf("api: %v", err)
+				}
+			}()
+		}
+	}
 
-	type last struct{ sig string }
-	lastByPort := map[int]last{}
+	logPolicies("monitoring", d)
+	r.logEvent(eventlog.Entry{Event: eventlog.Daemon, Msg: "started: monitoring " + d.describe()})
 
-	t := time.NewTicker(interval)
-	defer t.Stop()
+	// compact history segments in the background
+	go func() {
+		ct := time.NewTicker(10 * time.Minute)
+		defer ct.Stop()
+		for range ct.C {
+			if err := history.Compact(); err != nil {
+				fmt.Fprintln(os.Stderr, "error: history compaction:", err)
+			}
+		}
+	}()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	term := make(chan os.Signal, 1)
+	signal.Notify(term, syscall.SIGTERM, os.Interrupt)
 
-	runOnce := func() {
-		for _, p := range ports {
-			rep, err := inspect.InspectPort(p, c.Proto, inspect.Options{EnableDocker: c.Docker, IncludeConnections: false})
+	// reload re-reads --options-file (when given) and the config file, keeping the
+	// current policies when the new ones are invalid.
+	reload := func() {
+		failed := func(err error) {
+			daemonLogf("reload failed, keeping previous options: %v", err)
+			r.logEvent(eventlog.Entry{Event: eventlog.Daemon, Level: eventlog.LevelError, Msg: "reload failed", Error: err.Error()})
+		}
+		next := d
+		if optionsFile != "" {
+			loaded, err := loadDaemonConfig(optionsFile)
 			if err != nil {
-				fmt.Fprintln(os.Stderr, "error:", err)
-				continue
+				failed(err)
+				return
 			}
-			_ = history.Record(rep)
-			sig := rep.Signature()
-			prev := lastByPort[p]
-			if sig != prev.sig {
-				lastByPort[p] = last{sig: sig}
-				if quiet {
-					continue
-				}
-				if c.JSON {
-					enc := json.NewEncoder(os.Stdout)
-					enc.SetIndent("", "  ")
-					_ = enc.Encode(rep)
-				} else {
-					fmt.Print(render.Who(rep, renderOptions(c)))
-					fmt.Println("---")
+			next = loaded
+		} else {
+			cp := *d
+			next = &cp
+		}
+		if err := next.validate(); err != nil {
+			failed(err)
+			return
+		}
+		events, err := next.openEventLog()
+		if err != nil {
+			failed(fmt.Errorf("event log: %w", err))
+			return
+		}
+		d = next
+		r.configure(d, events)
+		logPolicies("reloaded: monitoring", d)
+		r.logEvent(eventlog.Entry{Event: eventlog.Daemon, Msg: "reloaded: monitoring " + d.describe()})
+	}
+
+	// Each port is polled on its own interval; the ticker only sets the resolution.
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+
+	seen := d.stamp
+	r.poll()
+	for {
+		select {
+		case <-t.C:
+			if d.file != "" {
+				if st := fileStamp(d.file); st != "" && st != seen {
+					seen = st
+					daemonLogf("%s changed", d.file)
+					reload()
 				}
 			}
+			r.poll()
+		case <-hup:
+			if _, err := config.Load(); err != nil {
+				daemonLogf("config: %v", err)
+			}
+			reload()
+			seen = d.stamp
+			r.poll()
+		case sig := <-term:
+			daemonLogf("received %s, flushing history", sig)
+			r.closeHooks()
+			if err := history.Compact(); err != nil {
+				fmt.Fprintln(os.Stderr, "error: history compaction:", err)
+			}
+			daemonLogf("stopped")
+			r.logEvent(eventlog.Entry{Event: eventlog.Daemon, Msg: "stopped"})
+			r.closeEvents()
+			return 0
 		}
 	}
+}
 
-	runOnce()
-	for {
-		<-t.C
-		runOnce()
+// logPolicies logs what d monitors, one line per config entry.
+func logPolicies(what string, d *daemonConfig) {
+	daemonLogf("%s %s (history in ~/.portik/history/)", what, d.describe())
+	for _, line := range daemon.Summary(d.policies) {
+		daemonLogf("  %s", line)
+	}
+}
+
+// listenAPI opens ~/.portik/daemon.sock, logging (not failing) when it cannot.
+func listenAPI() net.Listener {
+	path, err := daemon.SockPath()
+	if err == nil {
+		var l net.Listener
+		if l, err = daemon.Listen(path); err == nil {
+			daemonLogf("api listening on %s", path)
+			return l
+		}
+	}
+	daemonLogf("api disabled: %v", err)
+	return nil
+}
+
+// daemonRunner polls the monitored ports and feeds the API cache. The API can ask for
+// a port to be refreshed between polls, so polling is serialized by mu.
+type daemonRunner struct {
+	mu      sync.Mutex
+	d       *daemonConfig
+	srv     *daemon.Server
+	metrics *daemon.Metrics
+	ports   map[string]*portState // by port/proto
+	targets []daemon.Target       // sorted
+	events  *eventlog.Logger
+
+	// one dispatcher per distinct hook configuration, so ports sharing it share
+	// its queue and rate limit
+	dispatchers map[string]*hooks.Dispatcher
+
+	// with --all
+	disc         *discoverer
+	nextDiscover time.Time
+	discovered   bool // the first enumeration is done
+}
+
+// portState is the per-port state of the runner. Detectors survive reloads so a
+// reload does not re-fire events.
+type portState struct {
+	pol      daemon.Policy
+	next     time.Time
+	hooks    *hooks.Dispatcher // nil without on_change/webhook
+	detector hooks.Detector    // hook events
+	events   hooks.Detector    // event log entries and policy alerts
+}
+
+// configure applies d, switching to the events logger (the previous one is closed).
+func (r *daemonRunner) configure(d *daemonConfig, events *eventlog.Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.d = d
+	if r.events != nil {
+		r.events.Close()
+	}
+	r.events = events
+	pols := d.policies
+	if tmpl := d.discover; tmpl != nil {
+		if r.disc == nil || r.disc.proto != tmpl.Proto {
+			r.disc, r.discovered = newDiscoverer(tmpl.Proto, tmpl.Docker, true, d.filter), false
+		}
+		r.disc.docker = tmpl.Docker
+		r.disc.retain(d.filter)
+		r.nextDiscover = time.Time{}
+		for _, p := range r.disc.ports() {
+			pols = append(pols, discovered(*tmpl, p))
+		}
+	} else {
+		r.disc = nil
+	}
+
+	prev, reuse := r.ports, r.dispatchers
+	r.ports, r.dispatchers = map[string]*portState{}, map[string]*hooks.Dispatcher{}
+	for _, pol := range pols {
+		r.track(pol, prev[pol.Key()], reuse)
+	}
+	for k, old := range reuse {
+		if r.dispatchers[k] == nil {
+			go old.Close(30 * time.Second) // let queued events drain
+		}
+	}
+	r.retarget()
+}
+
+func discovered(tmpl daemon.Policy, port int) daemon.Policy {
+	tmpl.Port = port
+	return tmpl
+}
+
+// track starts monitoring pol, keeping the detectors of st (when the port was already
+// monitored) and reusing a dispatcher from reuse when its hook config is unchanged.
+// r.mu must be held.
+func (r *daemonRunner) track(pol daemon.Policy, st *portState, reuse map[string]*hooks.Dispatcher) {
+	if st == nil {
+		st = &portState{events: hooks.Detector{MinSeverity: "warn"}}
+	}
+	st.pol, st.next = pol, time.Time{}
+	st.detector.MinSeverity = pol.Hooks.Severity
+	st.hooks = nil
+	if pol.HookOpt.Enabled() {
+		b, _ := json.Marshal(pol.Hooks)
+		k := string(b)
+		if r.dispatchers[k] == nil {
+			if r.dispatchers[k] = reuse[k]; r.dispatchers[k] == nil {
+				opt := pol.HookOpt
+				opt.Logf = func(format string, a ...any) { daemonLogf("hooks: "+format, a...) }
+				r.dispatchers[k] = hooks.NewDispatcher(opt)
+			}
+		}
+		st.hooks = r.dispatchers[k]
+	}
+	r.ports[pol.Key()] = st
+}
+
+// retarget publishes the monitored ports to the API and metrics. r.mu must be held.
+func (r *daemonRunner) retarget() {
+	r.targets = r.targets[:0]
+	for _, st := range r.ports {
+		r.targets = append(r.targets, st.pol.Target)
+	}
+	slices.SortFunc(r.targets, func(a, b daemon.Target) int {
+		return cmp.Or(cmp.Compare(a.Port, b.Port), strings.Compare(a.Proto, b.Proto))
+	})
+	r.srv.Configure(r.targets)
+	r.metrics.Configure(r.targets)
+}
+
+// logEvent writes e to the event log from outside a poll.
+func (r *daemonRunner) logEvent(e eventlog.Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events.Log(e)
+}
+
+func (r *daemonRunner) closeEvents() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events.Close()
+	r.events = nil
+}
+
+func (r *daemonRunner) closeHooks() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, dp := range r.dispatchers {
+		dp.Close(5 * time.Second)
+	}
+	r.dispatchers = map[string]*hooks.Dispatcher{}
+	for _, st := range r.ports {
+		st.hooks = nil
+	}
+}
+
+// poll inspects the ports that are due, or runs a discovery cycle with --all.
+func (r *daemonRunner) poll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if r.disc != nil {
+		if !now.Before(r.nextDiscover) {
+			r.nextDiscover = now.Add(r.d.discover.Interval())
+			r.discover()
+		}
+		return
+	}
+	for _, t := range r.targets {
+		st := r.ports[t.Key()]
+		if now.Before(st.next) {
+			continue
+		}
+		st.next = now.Add(t.Interval())
+		r.pollPort(st)
+	}
+}
+
+// discover enumerates the listening sockets once, starts tracking new ports and
+// stops tracking the ones that were released. r.mu must be held.
+func (r *daemonRunner) discover() {
+	start := time.Now()
+	reps, appeared, gone, err := r.disc.scan()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error: discovery:", err)
+		r.events.Log(eventlog.Entry{Event: eventlog.ProbeFailed, Level: eventlog.LevelError, Proto: r.disc.proto, Msg: "discovery failed", Error: err.Error()})
+		return
+	}
+	took := time.Since(start)
+	tmpl := *r.d.discover
+	for _, p := range appeared {
+		r.track(discovered(tmpl, p), nil, r.dispatchers)
+		if r.discovered {
+			// the port was free until now, so its first owner is a change
+			st, free := r.ports[fmt.Sprintf("%d/%s", p, tmpl.Proto)], model.Report{Port: p, Proto: tmpl.Proto}
+			st.detector.Events(free)
+			st.events.Events(free)
+		}
+	}
+	if !r.discovered {
+		r.discovered = true
+		daemonLogf("discovered %d listening %s ports", len(appeared), tmpl.Proto)
+		appeared = nil
+	}
+	for _, rep := range reps {
+		st := r.ports[fmt.Sprintf("%d/%s", rep.Port, rep.Proto)]
+		if st == nil {
+			continue
+		}
+		if slices.Contains(appeared, rep.Port) {
+			daemonLogf("%d/%s appeared (%s)", rep.Port, rep.Proto, history.OwnerLabel(history.EventFromReport(rep)))
+		}
+		r.observe(st, rep, took)
+	}
+	for _, p := range gone {
+		daemonLogf("%d/%s disappeared", p, tmpl.Proto)
+		delete(r.ports, fmt.Sprintf("%d/%s", p, tmpl.Proto))
+	}
+	if len(appeared) > 0 || len(gone) > 0 || len(r.targets) != len(r.ports) {
+		r.retarget()
+	}
+}
+
+func (r *daemonRunner) refresh(t daemon.Target) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if st, ok := r.ports[t.Key()]; ok {
+		r.pollPort(st)
+	}
+}
+
+// pollPort inspects one port (with connections, so the cache can serve the TUI).
+// r.mu must be held.
+func (r *daemonRunner) pollPort(st *portState) {
+	pol := st.pol
+	start := time.Now()
+	rep, err := inspect.InspectPort(pol.Port, pol.Proto, inspect.Options{EnableDocker: pol.Docker, IncludeConnections: true})
+	if err != nil {
+		r.metrics.ObserveError(pol.Port, pol.Proto, time.Since(start))
+		fmt.Fprintln(os.Stderr, "error:", err)
+		r.events.Log(eventlog.Entry{Event: eventlog.ProbeFailed, Level: eventlog.LevelError, Port: pol.Port, Proto: pol.Proto, Msg: "inspection failed", Error: err.Error()})
+		return
+	}
+	r.observe(st, rep, time.Since(start))
+}
+
+// observe applies the port's policy to rep, records it and reports changes. r.mu
+// must be held.
+func (r *daemonRunner) observe(st *portState, rep model.Report, took time.Duration) {
+	d, c, pol := r.d, r.d.c, st.pol
+	rep.Diagnostics = append(rep.Diagnostics, pol.Check(rep)...)
+	r.metrics.Observe(rep, took)
+	_ = history.Record(rep)
+	for _, ev := range st.events.Events(rep) {
+		r.events.Log(logEntry(ev))
+		if ev.Event == hooks.EventDiagnostic && daemon.IsPolicyDiagnostic(ev.Diagnostic.Kind) {
+			policyAlert(rep, *ev.Diagnostic, c.JSON)
+		}
+	}
+	if st.hooks != nil {
+		for _, ev := range st.detector.Events(rep) {
+			st.hooks.Send(ev)
+		}
+	}
+	ch, changed := r.srv.Update(rep)
+	if !changed {
+		return
+	}
+	if ch.From != "" {
+		r.metrics.OwnerChanged(pol.Port, pol.Proto, ch.From, ch.To)
+	}
+	if pol.Alerts {
+		raiseAlerts(rep.Port, rep.Proto, rep.Generated, d.detect, c.JSON, r.events)
+	}
+	if d.quiet {
+		return
+	}
+	if c.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(rep)
+	} else {
+		fmt.Print(render.Who(rep, renderOptions(c)))
+		fmt.Println("---")
+	}
+}
+
+type alert struct {
+	Port       int               `json:"port"`
+	Proto      string            `json:"proto"`
+	Pattern    *history.Pattern  `json:"pattern,omitempty"`
+	Diagnostic *model.Diagnostic `json:"diagnostic,omitempty"` // a policy violation
+}
+
+// raiseAlerts prints (and logs to events) the anomaly patterns triggered by the event
+// recorded at `at`.
+func raiseAlerts(port int, proto string, at time.Time, opt history.DetectOptions, jsonOut bool, events *eventlog.Logger) {
+	evs, err := history.ReadKey(fmt.Sprintf("%d/%s", port, proto))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return
+	}
+	for _, p := range history.DetectAnomalies(evs, at, opt) {
+		events.Log(eventlog.Entry{
+			Event: eventlog.Anomaly, Level: eventlog.LevelWarn, Port: port, Proto: proto,
+			Kind: p.Kind, Msg: p.Summary, Details: strings.Join(p.Evidence, "; "),
+		})
+		if jsonOut {
+			_ = json.NewEncoder(os.Stdout).Encode(struct {
+				Alert alert `json:"alert"`
+			}{alert{Port: port, Proto: proto, Pattern: &p}})
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "ALERT %d/%s [%s] %s\n", port, proto, p.Kind, p.Summary)
+		for _, ev := range p.Evidence {
+			fmt.Fprintf(os.Stderr, "  %s\n", ev)
+		}
+	}
+}
+
+// policyAlert prints a newly raised policy diagnostic (unexpected owner, threshold).
+func policyAlert(rep model.Report, dg model.Diagnostic, jsonOut bool) {
+	if jsonOut {
+		_ = json.NewEncoder(os.Stdout).Encode(struct {
+			Alert alert `json:"alert"`
+		}{alert{Port: rep.Port, Proto: rep.Proto, Diagnostic: &dg}})
+		return
+	}
+	fmt.Fprintf(os.Stderr, "ALERT %d/%s [%s] %s\n", rep.Port, rep.Proto, dg.Kind, dg.Summary)
+	if dg.Details != "" {
+		fmt.Fprintf(os.Stderr, "  %s\n", dg.Details)
+	}
+}
+
+// logEntry converts a change event into an event log entry.
+func logEntry(ev hooks.Event) eventlog.Entry {
+	e := eventlog.Entry{Time: ev.At, OldOwner: ev.OldOwner, NewOwner: ev.NewOwner}
+	switch ev.Event {
+	case hooks.EventPortFreed:
+		e.Event, e.Msg = eventlog.PortFreed, fmt.Sprintf("%d/%s released by %s", ev.Port, ev.Proto, ev.OldOwner)
+	case hooks.EventDiagnostic:
+		dg := ev.Diagnostic
+		e.Event, e.Msg, e.Kind, e.Severity, e.Details = eventlog.DiagRaised, dg.Summary, dg.Kind, dg.Severity, dg.Details
+		e.Level = eventlog.LevelWarn
+		if dg.Severity == "error" {
+			e.Level = eventlog.LevelError
+		}
+	default:
+		e.Event, e.Msg = eventlog.OwnerChanged, fmt.Sprintf("%d/%s: %s -> %s", ev.Port, ev.Proto, ev.OldOwner, ev.NewOwner)
 	}
+	return eventlog.ForReport(e, ev.Report)
 }
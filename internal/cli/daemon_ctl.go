@@ -0,0 +1,335 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/daemon"
+	"github.com/pratik-anurag/portik/internal/pidctl"
+)
+
+func loadDaemonConfig(path string) (*daemonConfig, error) {
+	o, err := daemon.LoadOptions(path)
+	if err != nil {
+		return nil, err
+	}
+	fs, d := newDaemonFlagSet("daemon")
+	fs.SetOutput(new(strings.Builder))
+	if err := fs.Parse(o.Args); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return d, nil
+}
+
+// daemonPaths resolves pidfile, log and options locations under ~/.portik.
+func daemonPaths() (pid, log, opts string, err error) {
+	if pid, err = daemon.PidPath(); err != nil {
+		return
+	}
+	if log, err = daemon.LogPath(); err != nil {
+		return
+	}
+	opts, err = daemon.OptionsPath()
+	return
+}
+
+// portik daemon start [--ports 5432,6379 [--interval 30s] [--docker] | --config FILE | --all] ...
+func runDaemonStart(args []string) int {
+	fs, d := newDaemonFlagSet("daemon start")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	pidPath, logPath, optsPath, err := daemonPaths()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	if pid, ok := daemon.Running(pidPath); ok {
+		fmt.Fprintf(os.Stderr, "daemon: already running (pid %d); use `portik daemon reload` to change options\n", pid)
+		return 1
+	}
+
+	saved := flagArgs(fs)
+	fromFlags := len(saved) > 0
+	if !fromFlags {
+		// no flags: restart with the options saved by the previous start
+		if o, err := daemon.LoadOptions(optsPath); err == nil {
+			saved = o.Args
+			prev, _ := loadDaemonConfig(optsPath)
+			d = prev
+		}
+	}
+	if d == nil {
+		fmt.Fprintln(os.Stderr, "daemon start: invalid saved options in", optsPath)
+		return 2
+	}
+	if err := d.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "daemon start:", err)
+		return 2
+	}
+	if fromFlags {
+		saved = flagArgs(fs) // validate resolved --config and --log-file
+	}
+	if err := daemon.SaveOptions(optsPath, daemon.Options{Args: saved}); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	logf, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	defer logf.Close()
+
+	cmd := exec.Command(exe, "daemon", "run", "--options-file", optsPath, "--pidfile", pidPath)
+	cmd.Stdout = logf
+	cmd.Stderr = logf
+	cmd.Stdin = nil
+	pidctl.Detach(cmd)
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, "daemon start:", err)
+		return 1
+	}
+	pid := cmd.Process.Pid
+	_ = cmd.Process.Release()
+
+	if err := daemon.WaitPidfile(pidPath, pid, 5*time.Second); err != nil {
+		fmt.Fprintf(os.Stderr, "daemon start: %v (see %s)\n", err, logPath)
+		return 1
+	}
+	fmt.Printf("portik daemon started (pid %d), monitoring %s\n", pid, d.describe())
+	for _, line := range daemon.Summary(d.policies) {
+		fmt.Printf("  %s\n", line)
+	}
+	fmt.Printf("log: %s\n", logPath)
+	return 0
+}
+
+// portik daemon stop [--timeout 10s]
+func runDaemonStop(args []string) int {
+	fs := flag.NewFlagSet("daemon stop", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	var timeout time.Duration
+	fs.DurationVar(&timeout, "timeout", 10*time.Second, "how long to wait for the daemon to exit")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	pidPath, _, _, err := daemonPaths()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	pid, ok := daemon.Running(pidPath)
+	if !ok {
+		_ = os.Remove(pidPath)
+		fmt.Println("portik daemon is not running")
+		return 0
+	}
+	if err := pidctl.Stop(pid); err != nil {
+		fmt.Fprintln(os.Stderr, "daemon stop:", err)
+		return 1
+	}
+	if !pidctl.WaitExit(pid, timeout) {
+		fmt.Fprintf(os.Stderr, "daemon stop: pid %d still running after %s\n", pid, timeout)
+		return 1
+	}
+	_ = os.Remove(pidPath)
+	fmt.Printf("portik daemon stopped (pid %d)\n", pid)
+	return 0
+}
+
+type daemonStatus struct {
+	Running bool      `json:"running"`
+	PID     int       `json:"pid,omitempty"`
+	Since   time.Time `json:"since,omitzero"`
+	Args    []string  `json:"args,omitempty"`
+	Log     string    `json:"log"`
+	Pidfile string    `json:"pidfile"`
+	API     string    `json:"api,omitempty"` // socket, when the API answers
+}
+
+// portik daemon status [--json]
+func runDaemonStatus(args []string) int {
+	fs := flag.NewFlagSet("daemon status", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	var jsonOut bool
+	fs.BoolVar(&jsonOut, "json", false, "output JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	pidPath, logPath, optsPath, err := daemonPaths()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	st := daemonStatus{Log: logPath, Pidfile: pidPath}
+	if pid, ok := daemon.Running(pidPath); ok {
+		st.Running, st.PID = true, pid
+		if fi, err := os.Stat(pidPath); err == nil {
+			st.Since = fi.ModTime()
+		}
+		if _, err := daemon.Connect(); err == nil {
+			st.API, _ = daemon.SockPath()
+		}
+	}
+	if o, err := daemon.LoadOptions(optsPath); err == nil {
+		st.Args = o.Args
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(st)
+	} else if st.Running {
+		fmt.Printf("portik daemon is running (pid %d, up %s)\n", st.PID, time.Since(st.Since).Round(time.Second))
+		fmt.Printf("options: %s\n", strings.Join(st.Args, " "))
+		fmt.Printf("log: %s\n", st.Log)
+		if st.API != "" {
+			fmt.Printf("api: %s\n", st.API)
+		}
+	} else {
+		fmt.Println("portik daemon is not running")
+	}
+	if !st.Running {
+		return 3 // LSB "program is not running"
+	}
+	return 0
+}
+
+// portik daemon reload [--ports ...] [other daemon flags]
+// Flags given here are merged into the saved options before signalling the daemon.
+func runDaemonReload(args []string) int {
+	pidPath, _, optsPath, err := daemonPaths()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	if len(args) > 0 {
+		o, _ := daemon.LoadOptions(optsPath)
+		fs, d := newDaemonFlagSet("daemon reload")
+		fs.SetOutput(new(strings.Builder))
+		if err := fs.Parse(o.Args); err != nil {
+			fmt.Fprintln(os.Stderr, "daemon reload: invalid saved options:", err)
+			return 2
+		}
+		fs.SetOutput(os.Stderr)
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if err := d.validate(); err != nil {
+			fmt.Fprintln(os.Stderr, "daemon reload:", err)
+			return 2
+		}
+		if err := daemon.SaveOptions(optsPath, daemon.Options{Args: flagArgs(fs)}); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return 1
+		}
+	}
+	pid, ok := daemon.Running(pidPath)
+	if !ok {
+		fmt.Fprintln(os.Stderr, "daemon reload: daemon is not running")
+		return 1
+	}
+	if err := daemon.Reload(pid); err != nil {
+		fmt.Fprintln(os.Stderr, "daemon reload:", err)
+		return 1
+	}
+	fmt.Printf("portik daemon reloading (pid %d)\n", pid)
+	return 0
+}
+
+// portik daemon install [--systemd|--launchd] [--print] [--force] [daemon flags]
+func runDaemonInstall(args []string) int {
+	fs, d := newDaemonFlagSet("daemon install")
+	var systemd, launchd, printOnly, force bool
+	fs.BoolVar(&systemd, "systemd", false, "generate a systemd user unit (default on Linux)")
+	fs.BoolVar(&launchd, "launchd", false, "generate a launchd agent plist (default on macOS)")
+	fs.BoolVar(&printOnly, "print", false, "print the unit instead of writing it")
+	fs.BoolVar(&force, "force", false, "overwrite an existing unit")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	kind := daemon.UnitSystemd
+	switch {
+	case systemd && launchd:
+		fmt.Fprintln(os.Stderr, "daemon install: choose one of --systemd or --launchd")
+		return 2
+	case launchd, !systemd && runtime.GOOS == "darwin":
+		kind = daemon.UnitLaunchd
+	}
+
+	pidPath, logPath, optsPath, err := daemonPaths()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	skip := []string{"systemd", "launchd", "print", "force"}
+	monitor := flagArgs(fs, skip...)
+	fromFlags := len(monitor) > 0
+	if !fromFlags {
+		if o, err := daemon.LoadOptions(optsPath); err == nil {
+			monitor = o.Args
+			d, _ = loadDaemonConfig(optsPath)
+		}
+	}
+	if d == nil {
+		fmt.Fprintln(os.Stderr, "daemon install: invalid saved options in", optsPath)
+		return 2
+	}
+	if err := d.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "daemon install:", err)
+		return 2
+	}
+	if fromFlags {
+		monitor = flagArgs(fs, skip...) // validate resolved --config and --log-file
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	argv := append([]string{exe, "daemon", "run", "--pidfile", pidPath}, monitor...)
+	unit := daemon.SystemdUnit(argv, logPath)
+	if kind == daemon.UnitLaunchd {
+		unit = daemon.LaunchdPlist(argv, logPath)
+	}
+	if printOnly {
+		fmt.Print(unit)
+		return 0
+	}
+
+	path, err := daemon.UnitPath(kind)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	if _, err := os.Stat(path); err == nil && !force {
+		fmt.Fprintf(os.Stderr, "daemon install: %s exists (use --force to overwrite)\n", path)
+		return 1
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	if err := os.WriteFile(path, []byte(unit), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	fmt.Printf("Wrote %s\n", path)
+	fmt.Printf("Enable with: %s\n", daemon.UnitHint(kind, path))
+	return 0
+}
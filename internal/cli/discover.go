@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/pratik-anurag/portik/internal/inspect"
+	"github.com/pratik-anurag/portik/internal/model"
+	"github.com/pratik-anurag/portik/internal/ports"
+)
+
+// discoverer tracks every listening port of one proto across enumerations, for
+// `daemon --all` and `watch --all`.
+type discoverer struct {
+	proto  string
+	docker bool
+	conns  bool // collect connections too (daemon metrics and thresholds)
+	filter ports.Filter
+	known  map[int]bool
+}
+
+func newDiscoverer(proto string, docker, conns bool, filter ports.Filter) *discoverer {
+	return &discoverer{proto: proto, docker: docker, conns: conns, filter: filter, known: map[int]bool{}}
+}
+
+// scan enumerates the listening sockets once. It returns a report for every selected
+// listening port plus, for known ports no longer listed, a per-port inspection that
+// confirms they are free; appeared and gone list the ports that started or stopped
+// being tracked.
+func (dv *discoverer) scan() (reps []model.Report, appeared, gone []int, err error) {
+	found, err := inspect.Discover(dv.proto, inspect.Options{EnableDocker: dv.docker, IncludeConnections: dv.conns})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	listed := map[int]bool{}
+	for _, rep := range found {
+		if !dv.filter.Match(rep.Port) {
+			continue
+		}
+		listed[rep.Port] = true
+		if !dv.known[rep.Port] {
+			dv.known[rep.Port] = true
+			appeared = append(appeared, rep.Port)
+		}
+		reps = append(reps, rep)
+	}
+	for _, p := range slices.Sorted(maps.Keys(dv.known)) {
+		if listed[p] {
+			continue
+		}
+		rep, err := inspect.InspectPort(p, dv.proto, inspect.Options{EnableDocker: dv.docker})
+		if err != nil {
+			continue
+		}
+		reps = append(reps, rep)
+		if len(rep.Listeners) == 0 && !rep.Docker.Mapped {
+			delete(dv.known, p)
+			gone = append(gone, p)
+		}
+	}
+	return reps, appeared, gone, nil
+}
+
+// ports returns the tracked ports, sorted.
+func (dv *discoverer) ports() []int {
+	return slices.Sorted(maps.Keys(dv.known))
+}
+
+// retain stops tracking the ports filter no longer selects.
+func (dv *discoverer) retain(filter ports.Filter) {
+	dv.filter = filter
+	maps.DeleteFunc(dv.known, func(p int, _ bool) bool { return !filter.Match(p) })
+}
@@ -6,17 +6,20 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/pratik-anurag/portik/internal/config"
 )
 
 type commonFlags struct {
-	Proto   string
-	Docker  bool
-	JSON    bool
-	Yes     bool
-	Summary bool
-	Verbose bool
-	NoHints bool
-	Color   string
+	Proto    string
+	Docker   bool
+	JSON     bool
+	Yes      bool
+	Summary  bool
+	Verbose  bool
+	NoHints  bool
+	Color    string
+	NoDaemon bool
 }
 
 func parseCommon(fs *flag.FlagSet) *commonFlags {
@@ -29,6 +32,7 @@ func parseCommon(fs *flag.FlagSet) *commonFlags {
 	fs.BoolVar(&c.Verbose, "verbose", false, "verbose output (where supported)")
 	fs.BoolVar(&c.NoHints, "no-hints", false, "suppress diagnostic hints (where supported)")
 	fs.StringVar(&c.Color, "color", "auto", "color: auto|always|never")
+	fs.BoolVar(&c.NoDaemon, "no-daemon", false, "inspect live instead of using a running daemon's cache (where supported)")
 	return c
 }
 
@@ -64,13 +68,45 @@ func parseSince(s string) (time.Duration, error) {
 	if s == "" {
 		return 0, errors.New("empty since")
 	}
-	// Support "7d"
-	if len(s) > 1 && s[len(s)-1] == 'd' {
-		var n int
-		if _, err := fmt.Sscanf(s[:len(s)-1], "%d", &n); err != nil {
-			return 0, err
+	// Supports "7d" and "2w" on top of Go durations
+	return config.ParseDuration(s)
+}
+
+// parseAt parses a point in time: "-2h"/"-3d" (relative to now), RFC3339,
+// "2006-01-02 15:04[:05]", "2006-01-02", or "15:04[:05]" (today), in local time.
+func parseAt(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-") {
+		d, err := config.ParseDuration(s[1:])
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			y, m, d := now.Date()
+			return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, now.Location()), nil
 		}
-		return time.Duration(n) * 24 * time.Hour, nil
 	}
-	return time.ParseDuration(s)
+	return time.Time{}, fmt.Errorf("invalid time %q (use -2h, 09:12, \"2026-10-01 09:12\" or RFC3339)", s)
+}
+
+// flagGiven reports whether the flag name was set on the command line.
+func flagGiven(fs *flag.FlagSet, name string) bool {
+	given := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+	return given
 }
@@ -1,17 +1,20 @@
 package cli
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/pratik-anurag/portik/internal/ports"
 	"github.com/pratik-anurag/portik/internal/reserve"
 )
 
+// portik free [--ports 30000-40000] [--lease [--label L] [--for TTL] [--pid N]]
 func runFree(args []string) int {
 	fs := flag.NewFlagSet("free", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
@@ -21,12 +24,19 @@ func runFree(args []string) int {
 	var rangeSpec string
 	var attempts int
 	var jsonOut bool
+	var lease bool
+	var label, forStr string
+	var pid int
 
 	fs.StringVar(&proto, "proto", "tcp", "protocol: tcp|udp")
 	fs.StringVar(&bind, "bind", "127.0.0.1", "bind address to test (default 127.0.0.1)")
 	fs.StringVar(&rangeSpec, "ports", "", "ports spec (range recommended): e.g. 30000-40000")
 	fs.IntVar(&attempts, "attempts", 64, "random attempts before linear scan (range mode)")
 	fs.BoolVar(&jsonOut, "json", false, "output JSON")
+	fs.BoolVar(&lease, "lease", false, "lease the port through the broker so no other caller gets it until released")
+	fs.StringVar(&label, "label", "", "name for the --lease")
+	fs.StringVar(&forStr, "for", "0", "--lease TTL (0 = until released or --pid exits)")
+	fs.IntVar(&pid, "pid", 0, "process the --lease belongs to (default: the caller, portik's parent)")
 
 	if err := fs.Parse(args); err != nil {
 		return 2
@@ -36,11 +46,14 @@ func runFree(args []string) int {
 		return 2
 	}
 
-	opt := reserve.FreeOptions{Proto: proto, Bind: bind, Attempts: attempts}
+	opt := reserve.FreeOptions{Proto: proto, Bind: bind, Attempts: attempts, Leased: reserve.Leased(proto)}
 
 	var port int
 	var err error
 
+	if lease {
+		return freeLease(opt, rangeSpec, label, forStr, pid, jsonOut)
+	}
 	if rangeSpec == "" {
 		port, err = reserve.FindFreeEphemeral(opt)
 	} else {
@@ -77,3 +90,40 @@ func runFree(args []string) int {
 	fmt.Println(port)
 	return 0
 }
+
+// freeLease allocates through the broker (see reserve.Allocate): the port stays
+// unbound for the caller but is leased until released, --for expires or the
+// owning process exits.
+func freeLease(opt reserve.FreeOptions, rangeSpec, label, forStr string, pid int, jsonOut bool) int {
+	ttl, err := parseSince(forStr)
+	if err != nil || ttl < 0 {
+		fmt.Fprintln(os.Stderr, "free: invalid --for")
+		return 2
+	}
+	if _, err := strconv.Atoi(label); err == nil {
+		fmt.Fprintln(os.Stderr, "free: --label must not be a number (portik release takes a port or a label)")
+		return 2
+	}
+	if rangeSpec != "" {
+		plist, err := ports.ParseSpec(rangeSpec)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "free:", err)
+			return 2
+		}
+		opt.RangeStart, opt.RangeEnd = plist[0], plist[len(plist)-1]
+	}
+	// portik itself exits right away; the lease belongs to whoever ran it
+	l, err := reserve.Allocate(reserve.AllocOptions{FreeOptions: opt, Label: label, TTL: ttl, PID: cmp.Or(pid, os.Getppid())})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "free:", err)
+		return 1
+	}
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(l)
+		return 0
+	}
+	fmt.Println(l.Port)
+	return 0
+}
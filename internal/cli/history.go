@@ -5,46 +5,106 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"regexp"
 	"time"
 
+	"golang.org/x/term"
+
 	"github.com/pratik-anurag/portik/internal/history"
+	"github.com/pratik-anurag/portik/internal/ports"
 )
 
 func runHistory(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "prune":
+			return runHistoryPrune(args[1:])
+		case "export":
+			return runHistoryExport(args[1:])
+		case "import":
+			return runHistoryImport(args[1:])
+		}
+	}
+
 	fs := flag.NewFlagSet("history", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 
 	var sinceStr string
 	var jsonOut bool
 	var detect bool
+	var timeline bool
+	var portsSpec string
+	var width int
+	var noDaemon bool
 	fs.StringVar(&sinceStr, "since", "7d", "how far back: 24h|7d|30d")
 	fs.BoolVar(&jsonOut, "json", false, "output JSON")
 	fs.BoolVar(&detect, "detect-patterns", false, "detect simple time patterns")
+	fs.BoolVar(&timeline, "timeline", false, "draw an ASCII timeline (one lane per owner)")
+	fs.StringVar(&portsSpec, "ports", "", "ports for --timeline (e.g. 3000-3010,5432)")
+	fs.IntVar(&width, "width", 0, "timeline width in columns (default: terminal width)")
+	fs.BoolVar(&noDaemon, "no-daemon", false, "read history files directly instead of asking a running daemon")
+	var q historyQueryFlags
+	fs.StringVar(&q.owner, "owner", "", "without <port>: only this process or container name")
+	fs.StringVar(&q.user, "user", "", "without <port>: only this user")
+	fs.StringVar(&q.cmd, "cmd", "", "without <port>: only command lines matching this regexp")
+	fs.StringVar(&q.service, "service", "", "without <port>: only this compose service or container")
+	fs.StringVar(&q.proto, "proto", "", "without <port>: only tcp|udp")
+	fs.StringVar(&q.until, "until", "", "without <port>: end of window, as a duration ago (e.g. 24h)")
+	fs.StringVar(&q.format, "format", "text", "without <port>: output format text|json|csv")
+	fs.BoolVar(&q.events, "events", false, "without <port>: list matching events instead of a per-port summary")
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
-	if fs.NArg() < 1 {
-		fmt.Fprintln(os.Stderr, "history: missing <port>")
+	if portsSpec != "" && !timeline {
+		fmt.Fprintln(os.Stderr, "history: --ports requires --timeline")
 		return 2
 	}
-	port, err := parsePort(fs.Arg(0))
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "history:", err)
-		return 2
+	var plist []int
+	if portsSpec != "" {
+		p, err := ports.ParseSpec(portsSpec)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "history:", err)
+			return 2
+		}
+		plist = p
+	}
+	if fs.NArg() >= 1 {
+		port, err := parsePort(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "history:", err)
+			return 2
+		}
+		plist = append([]int{port}, plist...)
 	}
 	dur, err := parseSince(sinceStr)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "history: invalid --since")
 		return 2
 	}
-	cutoff := time.Now().Add(-dur)
+	now := time.Now()
+	cutoff := now.Add(-dur)
 
-	s, err := history.Load()
+	if len(plist) == 0 {
+		if timeline {
+			fmt.Fprintln(os.Stderr, "history: --timeline needs <port> or --ports")
+			return 2
+		}
+		if jsonOut {
+			q.format = "json"
+		}
+		return historyQuery(q, cutoff)
+	}
+	port := plist[0]
+
+	if timeline {
+		return historyTimeline(plist, cutoff, now, width, jsonOut)
+	}
+
+	view, err := historyView(port, cutoff, detect, noDaemon)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "error:", err)
 		return 1
 	}
-	view := s.ViewPortSince(port, cutoff, detect)
 
 	if jsonOut {
 		enc := json.NewEncoder(os.Stdout)
@@ -55,3 +115,122 @@ func runHistory(args []string) int {
 	fmt.Print(history.RenderView(view))
 	return 0
 }
+
+// historyView asks a running daemon for the view, falling back to reading the history
+// files directly.
+func historyView(port int, cutoff time.Time, detect, noDaemon bool) (history.View, error) {
+	if cl := daemonClient(&commonFlags{NoDaemon: noDaemon}); cl != nil {
+		if v, err := cl.History(port, cutoff, detect); err == nil {
+			return v, nil
+		}
+	}
+	s, err := history.LoadPorts(port)
+	if err != nil {
+		return history.View{}, err
+	}
+	return s.ViewPortSince(port, cutoff, detect), nil
+}
+
+func historyTimeline(plist []int, from, to time.Time, width int, jsonOut bool) int {
+	s, err := history.LoadPorts(plist...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	var keys []string
+	for _, p := range plist {
+		keys = append(keys, fmt.Sprintf("%d/tcp", p), fmt.Sprintf("%d/udp", p))
+	}
+	tl := s.BuildTimeline(keys, from, to)
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(tl)
+		return 0
+	}
+	if width <= 0 {
+		width = 100
+		if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+			width = w
+		}
+	}
+	fmt.Print(history.RenderTimeline(tl, width))
+	return 0
+}
+
+type historyQueryFlags struct {
+	owner, user, cmd, service, proto, until, format string
+	events                                          bool
+}
+
+// historyQuery answers machine-wide questions ("which ports did node hold this week?").
+func historyQuery(f historyQueryFlags, since time.Time) int {
+	q := history.Query{Owner: f.owner, User: f.user, Service: f.service, Proto: f.proto, Since: since}
+	if f.proto != "" && f.proto != "tcp" && f.proto != "udp" {
+		fmt.Fprintln(os.Stderr, "history: invalid --proto (tcp|udp)")
+		return 2
+	}
+	if f.cmd != "" {
+		re, err := regexp.Compile(f.cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "history: invalid --cmd:", err)
+			return 2
+		}
+		q.Cmd = re
+	}
+	if f.until != "" {
+		d, err := parseSince(f.until)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "history: invalid --until")
+			return 2
+		}
+		q.Until = time.Now().Add(-d)
+		if !q.Until.After(since) {
+			fmt.Fprintln(os.Stderr, "history: --until must be more recent than --since (both are durations ago)")
+			return 2
+		}
+	}
+	switch f.format {
+	case "text", "json", "csv":
+	default:
+		fmt.Fprintln(os.Stderr, "history: invalid --format (text|json|csv)")
+		return 2
+	}
+
+	s, err := history.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	res := s.Query(q)
+
+	switch {
+	case f.format == "json":
+		if !f.events {
+			res.Events = nil
+		} else {
+			res.Rows = nil
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(res)
+	case f.format == "csv" && f.events:
+		err = history.Export(os.Stdout, "csv", res.Events)
+	case f.format == "csv":
+		err = history.WriteQueryCSV(os.Stdout, res)
+	case f.events:
+		if len(res.Events) == 0 {
+			fmt.Println("No matching history in the selected window.")
+			break
+		}
+		fmt.Print(history.RenderEvents(res.Events, true))
+	default:
+		fmt.Print(history.RenderQuery(res))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
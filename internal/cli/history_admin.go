@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/history"
+	"github.com/pratik-anurag/portik/internal/ports"
+)
+
+// portik history prune [--older-than 90d] [--ports spec] [--dry-run]
+func runHistoryPrune(args []string) int {
+	fs := flag.NewFlagSet("history prune", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	var olderThan string
+	var portsSpec string
+	var dryRun bool
+	var jsonOut bool
+	fs.StringVar(&olderThan, "older-than", "", "drop events older than this (e.g. 90d); default: only apply configured retention")
+	fs.StringVar(&portsSpec, "ports", "", "only prune these ports (e.g. 5432,3000-3010)")
+	fs.BoolVar(&dryRun, "dry-run", false, "report what would be removed without writing")
+	fs.BoolVar(&jsonOut, "json", false, "output JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var cutoff time.Time
+	if olderThan != "" {
+		d, err := parseSince(olderThan)
+		if err != nil || d <= 0 {
+			fmt.Fprintln(os.Stderr, "history prune: invalid --older-than")
+			return 2
+		}
+		cutoff = time.Now().Add(-d)
+	}
+	var plist []int
+	if portsSpec != "" {
+		p, err := ports.ParseSpec(portsSpec)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "history prune:", err)
+			return 2
+		}
+		plist = p
+	}
+
+	res, err := history.Prune(cutoff, dryRun, plist...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(res)
+		return 0
+	}
+	verb := "Removed"
+	if dryRun {
+		verb = "Would remove"
+	}
+	fmt.Printf("%s %d events from %d ports (%d kept)\n", verb, res.Removed, res.Ports, res.Kept)
+	return 0
+}
+
+// portik history export [--format csv|jsonl] [--since 30d] [--ports spec] [-o file]
+func runHistoryExport(args []string) int {
+	fs := flag.NewFlagSet("history export", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	var format string
+	var sinceStr string
+	var portsSpec string
+	var outPath string
+	fs.StringVar(&format, "format", "jsonl", "output format: csv|jsonl")
+	fs.StringVar(&sinceStr, "since", "", "only events newer than this (e.g. 7d); default: everything")
+	fs.StringVar(&portsSpec, "ports", "", "only these ports (e.g. 5432,3000-3010)")
+	fs.StringVar(&outPath, "o", "", "write to file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if format != "csv" && format != "jsonl" {
+		fmt.Fprintln(os.Stderr, "history export: invalid --format (csv|jsonl)")
+		return 2
+	}
+
+	var since time.Time
+	if sinceStr != "" {
+		d, err := parseSince(sinceStr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "history export: invalid --since")
+			return 2
+		}
+		since = time.Now().Add(-d)
+	}
+	var plist []int
+	if portsSpec != "" {
+		p, err := ports.ParseSpec(portsSpec)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "history export:", err)
+			return 2
+		}
+		plist = p
+	}
+
+	evs, err := history.EventsSince(since, plist...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+
+	w := os.Stdout
+	if outPath != "" {
+		f, err := os.Create(outPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := history.Export(w, format, evs); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	if outPath != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d events to %s\n", len(evs), outPath)
+	}
+	return 0
+}
+
+// portik history import <file|dir>... [--host name]
+func runHistoryImport(args []string) int {
+	fs := flag.NewFlagSet("history import", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	var host string
+	var jsonOut bool
+	fs.StringVar(&host, "host", "", "hostname to tag events that carry none (default: file name)")
+	fs.BoolVar(&jsonOut, "json", false, "output JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "history import: missing <file> (jsonl export, history dir, or legacy history.json)")
+		return 2
+	}
+
+	var total history.ImportResult
+	for _, path := range fs.Args() {
+		evs, err := history.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "history import: %s: %v\n", path, err)
+			return 1
+		}
+		tag := host
+		if tag == "" {
+			tag = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		res, err := history.Import(evs, tag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "history import: %s: %v\n", path, err)
+			return 1
+		}
+		total.Read += res.Read
+		total.Added += res.Added
+		total.Duplicate += res.Duplicate
+		total.Ports += res.Ports
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(total)
+		return 0
+	}
+	fmt.Printf("Imported %d of %d events into %d ports (%d duplicates skipped)\n", total.Added, total.Read, total.Ports, total.Duplicate)
+	return 0
+}
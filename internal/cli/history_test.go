@@ -0,0 +1,16 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoryQueryRejectsInvertedWindow(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	since := time.Now().Add(-time.Hour)
+	for _, until := range []string{"24h", "2h"} {
+		if code := historyQuery(historyQueryFlags{until: until, format: "text"}, since); code != 2 {
+			t.Errorf("--since 1h --until %s: exit %d, want 2", until, code)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"flag"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/hooks"
+)
+
+// addHookFlags registers the change-hook flags shared by `daemon` and `watch`.
+func addHookFlags(fs *flag.FlagSet) *hooks.Config {
+	h := hooks.DefaultConfig()
+	fs.StringVar(&h.OnChange, "on-change", "", "run this shell command on ownership changes (JSON payload on stdin, PORTIK_* env)")
+	fs.StringVar(&h.Webhook, "webhook", "", "POST a JSON payload to this URL on ownership changes")
+	fs.StringVar(&h.Template, "hook-template", "", "payload template (text/template over the event; inline or @file)")
+	fs.StringVar(&h.Severity, "hook-severity", h.Severity, "also fire when a diagnostic at or above this severity appears: info|warn|error|off")
+	fs.StringVar(&h.Rate, "hook-rate", h.Rate, "deliver at most N hook events per s|m|h (excess is dropped)")
+	fs.IntVar(h.Retries, "hook-retries", *h.Retries, "retries for a failed delivery")
+	fs.DurationVar(&h.Backoff, "hook-backoff", time.Second, "delay before the first retry (doubles on each retry)")
+	fs.DurationVar(&h.Timeout, "hook-timeout", 10*time.Second, "timeout per delivery attempt")
+	return &h
+}
@@ -4,10 +4,16 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"slices"
+	"syscall"
 	"time"
 
+	"github.com/pratik-anurag/portik/internal/audit"
 	"github.com/pratik-anurag/portik/internal/history"
 	"github.com/pratik-anurag/portik/internal/inspect"
+	"github.com/pratik-anurag/portik/internal/model"
+	"github.com/pratik-anurag/portik/internal/ports"
+	"github.com/pratik-anurag/portik/internal/proctree"
 	"github.com/pratik-anurag/portik/internal/render"
 	"github.com/pratik-anurag/portik/internal/sys"
 )
@@ -18,19 +24,42 @@ func runKill(args []string) int {
 	c := parseCommon(fs)
 
 	var timeoutStr string
-	var force bool
+	var force, tree, pgid, dryRun bool
+	var watch time.Duration
+	var sigName string
+	var sel killSelector
 	fs.StringVar(&timeoutStr, "timeout", "5s", "grace period before SIGKILL")
 	fs.BoolVar(&force, "force", false, "allow killing processes not owned by your user (danger)")
+	fs.BoolVar(&tree, "tree", false, "kill the listener and all of its descendants")
+	fs.BoolVar(&pgid, "pgid", false, "kill the listener's whole process group")
+	fs.StringVar(&sigName, "signal", "", "send this signal once (HUP, USR1, INT, ...) instead of SIGTERM then SIGKILL, and report the outcome")
+	fs.DurationVar(&watch, "watch", 3*time.Second, "watch the port this long for a respawned owner (0 disables)")
+	fs.StringVar(&sel.name, "name", "", "batch: kill listeners whose process name matches (glob, e.g. node or 'python*')")
+	fs.StringVar(&sel.user, "user", "", "batch: kill listeners owned by this user (me: yourself)")
+	fs.StringVar(&sel.container, "container", "", "batch: stop the container (name or ID prefix) publishing the ports (implies --docker)")
+	fs.BoolVar(&dryRun, "dry-run", false, "batch: print the plan without killing anything")
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
-	if fs.NArg() < 1 {
-		fmt.Fprintln(os.Stderr, "kill: missing <port>")
+	if tree && pgid {
+		fmt.Fprintln(os.Stderr, "kill: choose one of --tree or --pgid")
 		return 2
 	}
-	port, err := parsePort(fs.Arg(0))
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "kill:", err)
+	var spec []int
+	if fs.NArg() > 0 {
+		var err error
+		if spec, err = ports.ParseSpec(fs.Arg(0)); err != nil {
+			fmt.Fprintln(os.Stderr, "kill:", err)
+			return 2
+		}
+	}
+	batch := sel.set() || len(spec) > 1 || dryRun
+	if !batch && len(spec) == 0 {
+		fmt.Fprintln(os.Stderr, "kill: missing <port> (or a ports spec with --name, --user, --container)")
+		return 2
+	}
+	if batch && (tree || pgid) {
+		fmt.Fprintln(os.Stderr, "kill: --tree and --pgid act on a single port")
 		return 2
 	}
 	timeout, err := time.ParseDuration(timeoutStr)
@@ -38,6 +67,23 @@ func runKill(args []string) int {
 		fmt.Fprintln(os.Stderr, "kill: invalid --timeout")
 		return 2
 	}
+	sig := syscall.SIGTERM
+	if sigName != "" {
+		if sig, err = sys.ParseSignal(sigName); err != nil {
+			fmt.Fprintln(os.Stderr, "kill:", err)
+			return 2
+		}
+	}
+	// the default SIGTERM escalates to SIGKILL; --signal, even --signal TERM, is sent once
+	oneShot := sigName != ""
+	if batch {
+		return runKillBatch(c, spec, sel, killOptions{sig: sig, oneShot: oneShot, timeout: timeout, force: force, dryRun: dryRun})
+	}
+	port := spec[0]
+	verb := "Kill"
+	if oneShot {
+		verb = "Send " + sys.SignalName(sig) + " to"
+	}
 
 	rep, err := inspect.InspectPort(port, c.Proto, inspect.Options{EnableDocker: c.Docker, IncludeConnections: false})
 	if err != nil {
@@ -52,16 +98,50 @@ func runKill(args []string) int {
 		return 1
 	}
 
-	if !force {
-		if err := sys.EnsureSameUser(target.PID); err != nil {
-			fmt.Fprintln(os.Stderr, "Refusing to kill process not owned by your user. Use --force to override.")
-			fmt.Fprintln(os.Stderr, "Details:", err)
+	victims := []proctree.Proc{{PID: target.PID, Name: target.ProcName}}
+	var group int32
+	if tree || pgid {
+		all, err := proctree.All()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "kill: cannot list processes:", err)
 			return 1
 		}
+		if tree {
+			victims = proctree.Subtree(all, listenerPIDs(rep)...)
+		} else {
+			if group, err = sys.ProcessGroup(target.PID); err != nil {
+				fmt.Fprintln(os.Stderr, "kill:", err)
+				return 1
+			}
+			victims = proctree.Group(all, group)
+		}
+	}
+
+	if !force {
+		for _, v := range victims {
+			if err := sys.EnsureSameUser(v.PID); err != nil {
+				fmt.Fprintln(os.Stderr, "Refusing to kill process not owned by your user. Use --force to override.")
+				fmt.Fprintf(os.Stderr, "Details: pid %d: %v\n", v.PID, err)
+				return 1
+			}
+		}
 	}
 
 	if !c.Yes {
-		fmt.Printf("Kill pid %d (%s) listening on %d/%s? [y/N]: ", target.PID, target.ProcName, port, c.Proto)
+		switch {
+		case tree:
+			fmt.Printf("%s process tree of pid %d (%s) listening on %d/%s (%d processes)?\n", verb, target.PID, target.ProcName, port, c.Proto, len(victims))
+		case pgid:
+			fmt.Printf("%s process group %d of pid %d (%s) listening on %d/%s (%d processes)?\n", verb, group, target.PID, target.ProcName, port, c.Proto, len(victims))
+		}
+		if tree || pgid {
+			for _, v := range victims {
+				fmt.Printf("  pid %-7d %s\n", v.PID, v.Name)
+			}
+			fmt.Print("[y/N]: ")
+		} else {
+			fmt.Printf("%s pid %d (%s) listening on %d/%s? [y/N]: ", verb, target.PID, target.ProcName, port, c.Proto)
+		}
 		var resp string
 		_, _ = fmt.Fscanln(os.Stdin, &resp)
 		if resp != "y" && resp != "Y" {
@@ -70,7 +150,118 @@ func runKill(args []string) int {
 		}
 	}
 
-	res := sys.TerminateProcess(target.PID, timeout)
+	pids := make([]int32, len(victims))
+	for i, v := range victims {
+		pids[i] = v.PID
+	}
+	ae := audit.For(audit.Kill, "cli", rep, target)
+	ae.Force = force
+	ae.Method = sys.SignalName(sig)
+	if oneShot {
+		ae.Action = audit.Signal
+	} else {
+		ae.Method += ", SIGKILL after " + timeout.String()
+	}
+	switch {
+	case tree:
+		ae.Method += " to process tree"
+		ae.PIDs = pids
+	case pgid:
+		ae.Method += fmt.Sprintf(" to process group %d", group)
+		ae.PIDs = pids
+	}
+	var res sys.ActionResult
+	switch {
+	case oneShot && pgid:
+		res = sys.SignalGroup(group, sig)
+	case oneShot:
+		res = sys.SignalProcesses(pids, sig)
+	case tree:
+		res = sys.TerminateTree(pids, timeout)
+	case pgid:
+		res = sys.TerminateGroup(group, timeout)
+	default:
+		res = sys.TerminateProcess(target.PID, timeout)
+	}
 	fmt.Print(render.ActionResult(res))
-	return res.ExitCode
+	audited(ae, res)
+	if res.ExitCode != 0 || watch <= 0 {
+		return res.ExitCode
+	}
+	if oneShot {
+		signalOutcome(port, c.Proto, rep, pids, watch)
+		return 0
+	}
+
+	killed := map[int32]bool{}
+	for _, v := range victims {
+		killed[v.PID] = true
+	}
+	l, ok := watchRespawn(port, c.Proto, killed, watch)
+	if !ok {
+		return 0
+	}
+	by := proctree.Supervisor(l.PID)
+	fmt.Printf("Port %d/%s was taken again by pid %d (%s) within %s.\n", port, c.Proto, l.PID, l.ProcName, watch)
+	fmt.Printf("Respawned by: %s", by.Kind)
+	if by.Details != "" {
+		fmt.Printf(" (%s)", by.Details)
+	}
+	fmt.Println()
+	if hint := respawnHint(by, tree || pgid); hint != "" && !c.NoHints {
+		fmt.Println("Hint:", hint)
+	}
+	return 1
+}
+
+// listenerPIDs returns the distinct PIDs holding the port; prefork servers
+// (gunicorn, nginx) share the socket between a master and its workers.
+func listenerPIDs(rep model.Report) []int32 {
+	var pids []int32
+	for _, l := range rep.Listeners {
+		for _, pid := range append([]int32{l.PID}, l.PIDs...) {
+			if pid > 0 && !slices.Contains(pids, pid) {
+				pids = append(pids, pid)
+			}
+		}
+	}
+	return pids
+}
+
+// watchRespawn polls the port until a listener outside killed shows up or
+// the window closes.
+func watchRespawn(port int, proto string, killed map[int32]bool, window time.Duration) (model.Listener, bool) {
+	deadline := time.Now().Add(window)
+	for time.Now().Before(deadline) {
+		time.Sleep(250 * time.Millisecond)
+		rep, err := inspect.InspectPort(port, proto, inspect.Options{})
+		if err != nil {
+			continue
+		}
+		if l, ok := rep.PrimaryListener(); ok && l.PID > 0 && !killed[l.PID] {
+			return l, true
+		}
+	}
+	return model.Listener{}, false
+}
+
+func respawnHint(by proctree.StartedBy, whole bool) string {
+	switch by.Kind {
+	case "systemd":
+		return "stop the unit instead: systemctl stop <unit> (or systemctl --user stop <unit>)"
+	case "pm2":
+		return "stop the app instead: pm2 stop <name>"
+	case "supervisord":
+		return "stop the program instead: supervisorctl stop <name>"
+	case "launchd":
+		return "unload the agent instead: launchctl bootout gui/$UID/<label>"
+	case "container":
+		return "stop the container instead: docker stop " + by.Details
+	case "nodemon", "shell":
+		if !whole {
+			return "stop the parent instead (" + by.Details + "), or retry with --pgid if it shares the listener's process group"
+		}
+		return "stop the parent instead (" + by.Details + ")"
+	}
+	return ""
 }
@@ -0,0 +1,243 @@
+package cli
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/audit"
+	"github.com/pratik-anurag/portik/internal/inspect"
+	"github.com/pratik-anurag/portik/internal/model"
+	"github.com/pratik-anurag/portik/internal/render"
+	"github.com/pratik-anurag/portik/internal/sys"
+)
+
+// killSelector narrows a batch kill; empty fields match everything.
+type killSelector struct {
+	name      string // glob on the process name
+	user      string // "me" for the current user
+	container string // container name or ID prefix
+}
+
+func (s killSelector) set() bool { return s.name != "" || s.user != "" || s.container != "" }
+
+type killOptions struct {
+	sig     syscall.Signal
+	oneShot bool
+	timeout time.Duration
+	force   bool
+	dryRun  bool
+}
+
+// killTarget is one row of a batch plan: a process, or a container when selected by
+// --container, with every matched port it holds.
+type killTarget struct {
+	pid       int32
+	name      string
+	user      string
+	cmdline   string
+	container string
+	runtime   string
+	ports     []int
+	skip      string // why the target is left alone
+	res       sys.ActionResult
+}
+
+func (t killTarget) action(o killOptions) string {
+	switch {
+	case t.skip != "":
+		return "skip: " + t.skip
+	case t.container != "":
+		return t.runtime + " stop"
+	case o.oneShot:
+		return sys.SignalName(o.sig)
+	}
+	return "SIGTERM, SIGKILL after " + o.timeout.String()
+}
+
+// portik kill [<ports spec>] [--name GLOB] [--user U] [--container C] [--dry-run]
+func runKillBatch(c *commonFlags, spec []int, sel killSelector, o killOptions) int {
+	if sel.name != "" {
+		if _, err := filepath.Match(sel.name, ""); err != nil {
+			fmt.Fprintln(os.Stderr, "kill: invalid --name pattern:", err)
+			return 2
+		}
+	}
+	if sel.user == "me" {
+		if u, err := user.Current(); err == nil {
+			sel.user = u.Username
+		}
+	}
+	c.Docker = c.Docker || sel.container != ""
+	reps, err := inspect.Discover(c.Proto, inspect.Options{EnableDocker: c.Docker})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	targets := planKill(reps, spec, sel)
+	if len(targets) == 0 {
+		fmt.Println("No listening process matches.")
+		return 1
+	}
+
+	todo := 0
+	for i := range targets {
+		t := &targets[i]
+		switch {
+		case t.container != "":
+		case t.pid <= 1 || int(t.pid) == os.Getpid():
+			t.skip = "protected"
+		case !o.force:
+			if err := sys.EnsureSameUser(t.pid); err != nil {
+				t.skip = "not your process (--force)"
+			}
+		}
+		if t.skip == "" {
+			todo++
+		}
+	}
+	rows := make([]render.KillPlanRow, len(targets))
+	for i, t := range targets {
+		rows[i] = t.row(o)
+	}
+	fmt.Print(render.KillPlan(rows))
+	if o.dryRun || todo == 0 {
+		return 0
+	}
+	if !c.Yes {
+		if !confirm(fmt.Sprintf("Kill %d of %d targets on %s? [y/N]: ", todo, len(targets), c.Proto)) {
+			return 0
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := range targets {
+		t := &targets[i]
+		if t.skip != "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			switch {
+			case t.container != "":
+				t.res = sys.StopContainer(t.runtime, t.container, o.timeout)
+			case o.oneShot:
+				t.res = sys.SignalProcesses([]int32{t.pid}, o.sig)
+			default:
+				t.res = sys.TerminateProcess(t.pid, o.timeout)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, t := range targets {
+		if t.skip == "" {
+			audited(t.audit(c.Proto, o), t.res)
+		}
+	}
+
+	code := 0
+	for _, t := range targets {
+		if t.skip != "" {
+			continue
+		}
+		fmt.Print(render.KillResult(t.row(o), t.res))
+		if t.res.ExitCode != 0 {
+			code = 1
+		}
+	}
+	return code
+}
+
+// planKill turns discovered reports into one target per process (or container),
+// keeping only ports in spec (nil: all) that match the selector.
+func planKill(reps []model.Report, spec []int, sel killSelector) []killTarget {
+	var targets []killTarget
+	index := map[string]int{}
+	add := func(key string, t killTarget, port int) {
+		if i, ok := index[key]; ok {
+			if !slices.Contains(targets[i].ports, port) {
+				targets[i].ports = append(targets[i].ports, port)
+			}
+			return
+		}
+		index[key] = len(targets)
+		t.ports = []int{port}
+		targets = append(targets, t)
+	}
+
+	for _, rep := range reps {
+		if spec != nil && !slices.Contains(spec, rep.Port) {
+			continue
+		}
+		if sel.container != "" {
+			d := rep.Docker
+			if d.Mapped && (d.ContainerName == sel.container || strings.HasPrefix(d.ContainerID, sel.container)) {
+				add("c:"+d.ContainerID, killTarget{container: d.ContainerID, name: d.ContainerName, runtime: cmp.Or(d.Runtime, "docker")}, rep.Port)
+			}
+			continue
+		}
+		for _, l := range rep.Listeners {
+			if l.PID <= 0 {
+				continue
+			}
+			if sel.name != "" {
+				if ok, _ := filepath.Match(sel.name, l.ProcName); !ok {
+					continue
+				}
+			}
+			if sel.user != "" && !strings.EqualFold(l.User, sel.user) {
+				continue
+			}
+			add(fmt.Sprintf("p:%d", l.PID), killTarget{pid: l.PID, name: l.ProcName, user: l.User, cmdline: l.Cmdline}, rep.Port)
+		}
+	}
+	return targets
+}
+
+func (t killTarget) row(o killOptions) render.KillPlanRow {
+	label := fmt.Sprintf("%d %s", t.pid, t.name)
+	if t.container != "" {
+		label = fmt.Sprintf("%s (%s)", t.name, t.container[:min(12, len(t.container))])
+	}
+	return render.KillPlanRow{Target: label, User: t.user, Ports: joinPorts(t.ports), Action: t.action(o)}
+}
+
+func (t killTarget) audit(proto string, o killOptions) audit.Entry {
+	e := audit.Entry{Source: "cli", Action: audit.Kill, Port: t.ports[0], Proto: proto, PID: t.pid,
+		Owner: t.name, OwnerUser: t.user, Cmdline: t.cmdline, Force: o.force,
+		Method: "batch " + t.action(o) + " on " + joinPorts(t.ports)}
+	switch {
+	case t.container != "":
+		e.Action, e.Owner, e.Container = audit.Stop, "", t.name
+	case o.oneShot:
+		e.Action = audit.Signal
+	}
+	return e
+}
+
+// joinPorts renders ports compactly: 3000-3002,8080.
+func joinPorts(ps []int) string {
+	ps = slices.Sorted(slices.Values(ps))
+	var parts []string
+	for i := 0; i < len(ps); {
+		j := i
+		for j+1 < len(ps) && ps[j+1] == ps[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", ps[i], ps[j]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d", ps[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
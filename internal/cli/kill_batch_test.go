@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/pratik-anurag/portik/internal/model"
+)
+
+func TestPlanKill(t *testing.T) {
+	listen := func(port int, ls ...model.Listener) model.Report {
+		return model.Report{Port: port, Proto: "tcp", Listeners: ls}
+	}
+	node := model.Listener{PID: 100, ProcName: "node", User: "alice"}
+	reps := []model.Report{
+		listen(3000, node),
+		listen(3001, node), // one process on several ports
+		listen(3002, model.Listener{PID: 200, ProcName: "nodemon", User: "bob"}),
+		listen(5432, model.Listener{PID: 300, ProcName: "postgres", User: "postgres"}),
+		listen(8080, model.Listener{PID: 0, ProcName: "?"}), // owner unknown
+		{Port: 6379, Proto: "tcp", Docker: model.DockerMap{Mapped: true, ContainerID: "9f3c2a1b7d4e5f60", ContainerName: "redis", Runtime: "podman"}},
+		{Port: 6380, Proto: "tcp", Docker: model.DockerMap{Mapped: true, ContainerID: "9f3c2a1b7d4e5f60", ContainerName: "redis"}},
+		{Port: 9000, Proto: "tcp", Docker: model.DockerMap{Mapped: true, ContainerID: "0123456789ab", ContainerName: "minio"}},
+	}
+
+	type want struct {
+		key   string // pid or container id
+		ports []int
+	}
+	for _, tc := range []struct {
+		name string
+		spec []int
+		sel  killSelector
+		want []want
+	}{
+		{"all ports", nil, killSelector{}, []want{{"100", []int{3000, 3001}}, {"200", []int{3002}}, {"300", []int{5432}}}},
+		{"spec", []int{3001, 5432, 7000}, killSelector{}, []want{{"100", []int{3001}}, {"300", []int{5432}}}},
+		{"name glob", nil, killSelector{name: "node*"}, []want{{"100", []int{3000, 3001}}, {"200", []int{3002}}}},
+		{"name exact", nil, killSelector{name: "node"}, []want{{"100", []int{3000, 3001}}}},
+		{"user", nil, killSelector{user: "BOB"}, []want{{"200", []int{3002}}}},
+		{"name and user", []int{3000, 3002}, killSelector{name: "node*", user: "alice"}, []want{{"100", []int{3000}}}},
+		{"container name", nil, killSelector{container: "redis"}, []want{{"9f3c2a1b7d4e5f60", []int{6379, 6380}}}},
+		{"container id prefix", nil, killSelector{container: "0123"}, []want{{"0123456789ab", []int{9000}}}},
+		{"container name is not a prefix match", nil, killSelector{container: "red"}, nil},
+		{"container outside spec", []int{3000}, killSelector{container: "redis"}, nil},
+	} {
+		got := planKill(reps, tc.spec, tc.sel)
+		var keys []want
+		for _, tg := range got {
+			k := tg.container
+			if k == "" {
+				k = strconv.Itoa(int(tg.pid))
+			}
+			keys = append(keys, want{k, tg.ports})
+		}
+		if !slices.EqualFunc(keys, tc.want, func(a, b want) bool { return a.key == b.key && slices.Equal(a.ports, b.ports) }) {
+			t.Errorf("%s: got %v, want %v", tc.name, keys, tc.want)
+		}
+	}
+
+	// the container target keeps the runtime that mapped it, docker by default
+	if got := planKill(reps, nil, killSelector{container: "redis"}); len(got) != 1 || got[0].runtime != "podman" || got[0].name != "redis" {
+		t.Fatalf("container target: %+v", got)
+	}
+	if got := planKill(reps, nil, killSelector{container: "minio"}); len(got) != 1 || got[0].runtime != "docker" {
+		t.Fatalf("default runtime: %+v", got)
+	}
+}
+
+func TestJoinPorts(t *testing.T) {
+	for _, tc := range []struct {
+		in   []int
+		want string
+	}{
+		{nil, ""},
+		{[]int{8080}, "8080"},
+		{[]int{3002, 3000, 3001, 8080}, "3000-3002,8080"},
+		{[]int{1, 3, 5}, "1,3,5"},
+		{[]int{5432, 5433, 6379, 6380, 6381}, "5432-5433,6379-6381"},
+	} {
+		if got := joinPorts(tc.in); got != tc.want {
+			t.Errorf("joinPorts(%v) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/render"
+	"github.com/pratik-anurag/portik/internal/reserve"
+)
+
+// portik leases [--proto tcp|udp] [--json]
+func runLeases(args []string) int {
+	fs := flag.NewFlagSet("leases", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	var proto string
+	var jsonOut bool
+	fs.StringVar(&proto, "proto", "", "only tcp or udp leases")
+	fs.BoolVar(&jsonOut, "json", false, "output JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	all, err := reserve.Leases()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	var ls []reserve.Lease
+	for _, l := range all {
+		if proto == "" || l.Proto == proto {
+			ls = append(ls, l)
+		}
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(map[string]any{"leases": ls})
+		return 0
+	}
+	if len(ls) == 0 {
+		fmt.Println("No active leases (portik reserve --detach creates one).")
+		return 0
+	}
+	fmt.Print(render.Leases(ls, time.Now()))
+	return 0
+}
+
+// portik release <port|label> [--proto tcp|udp]
+func runRelease(args []string) int {
+	fs := flag.NewFlagSet("release", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	var proto string
+	fs.StringVar(&proto, "proto", "", "only release the tcp or udp lease")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "release: missing <port|label>")
+		return 2
+	}
+	key := fs.Arg(0)
+
+	ls, err := reserve.FindLeases(key, proto)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	if len(ls) == 0 {
+		fmt.Fprintf(os.Stderr, "release: no active lease matches %q (see portik leases)\n", key)
+		return 1
+	}
+	code := 0
+	for _, l := range ls {
+		name := fmt.Sprintf("%d/%s", l.Port, l.Proto)
+		if l.Label != "" {
+			name += fmt.Sprintf(" (%s)", l.Label)
+		}
+		if err := reserve.Release(l); err != nil {
+			fmt.Fprintf(os.Stderr, "release %s: %v\n", name, err)
+			code = 1
+			continue
+		}
+		fmt.Printf("Released %s\n", name)
+	}
+	return code
+}
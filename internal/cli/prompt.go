@@ -0,0 +1,18 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+)
+
+// confirm prints prompt and reports whether the user answered y/Y.
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	var resp string
+	_, _ = fmt.Fscanln(os.Stdin, &resp)
+	if resp != "y" && resp != "Y" {
+		fmt.Println("Aborted.")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/audit"
+	"github.com/pratik-anurag/portik/internal/history"
+	"github.com/pratik-anurag/portik/internal/inspect"
+	"github.com/pratik-anurag/portik/internal/model"
+	"github.com/pratik-anurag/portik/internal/proctree"
+	"github.com/pratik-anurag/portik/internal/render"
+	"github.com/pratik-anurag/portik/internal/sys"
+)
+
+// portik reload <port> [--signal HUP] [--wait 3s] [--force] [--yes]
+func runReload(args []string) int {
+	fs := flag.NewFlagSet("reload", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	c := parseCommon(fs)
+
+	var sigName string
+	var wait time.Duration
+	var force bool
+	fs.StringVar(&sigName, "signal", "", "signal to send instead of the server's conventional reload signal")
+	fs.DurationVar(&wait, "wait", 3*time.Second, "how long to watch the port for replaced workers")
+	fs.BoolVar(&force, "force", false, "allow signalling processes not owned by your user (danger)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "reload: missing <port>")
+		return 2
+	}
+	port, err := parsePort(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "reload:", err)
+		return 2
+	}
+
+	rep, err := inspect.InspectPort(port, c.Proto, inspect.Options{EnableDocker: c.Docker, IncludeConnections: false})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	_ = history.Record(rep)
+
+	roots := masters(rep)
+	if len(roots) == 0 {
+		fmt.Fprintln(os.Stderr, "No listening process found for this port.")
+		return 1
+	}
+	m := roots[0]
+	if chain, _ := proctree.Build(m.PID, 1); len(chain) > 0 {
+		m = chain[0] // with the full command line
+	}
+
+	what := "--signal"
+	if sigName == "" {
+		name, server, effect, ok := sys.ReloadSignal(m.Name, m.Cmdline)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "reload: no known reload signal for %q; pass one with --signal (e.g. --signal HUP)\n", m.Name)
+			return 1
+		}
+		sigName, what = name, server+": "+effect
+	}
+	sig, err := sys.ParseSignal(sigName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "reload:", err)
+		return 2
+	}
+
+	pids := make([]int32, len(roots))
+	for i, p := range roots {
+		pids[i] = p.PID
+	}
+	if !force {
+		for _, pid := range pids {
+			if err := sys.EnsureSameUser(pid); err != nil {
+				fmt.Fprintln(os.Stderr, "Refusing to signal process not owned by your user. Use --force to override.")
+				fmt.Fprintf(os.Stderr, "Details: pid %d: %v\n", pid, err)
+				return 1
+			}
+		}
+	}
+	if !c.Yes {
+		if !confirm(fmt.Sprintf("Send %s to pid %d (%s) listening on %d/%s (%s)? [y/N]: ",
+			sys.SignalName(sig), m.PID, m.Name, port, c.Proto, what)) {
+			return 0
+		}
+	}
+
+	res := sys.SignalProcesses(pids, sig)
+	fmt.Print(render.ActionResult(res))
+	l, _ := rep.PrimaryListener()
+	ae := audit.For(audit.Reload, "cli", rep, l)
+	ae.PID, ae.Owner, ae.Cmdline = m.PID, m.Name, m.Cmdline
+	ae.Method, ae.Force = sys.SignalName(sig)+" ("+what+")", force
+	if len(pids) > 1 {
+		ae.PIDs = pids
+	}
+	audited(ae, res)
+	if res.ExitCode != 0 {
+		return res.ExitCode
+	}
+	if !signalOutcome(port, c.Proto, rep, pids, wait) {
+		return 1
+	}
+	return 0
+}
+
+// masters returns the processes holding the port whose parent does not hold it
+// too: the nginx/gunicorn master rather than its workers.
+func masters(rep model.Report) []proctree.Proc {
+	holders := listenerPIDs(rep)
+	all, _ := proctree.All()
+	byPID := map[int32]proctree.Proc{}
+	for _, p := range all {
+		byPID[p.PID] = p
+	}
+	var out []proctree.Proc
+	for _, pid := range holders {
+		p, ok := byPID[pid]
+		if !ok {
+			p = proctree.Proc{PID: pid}
+		}
+		if !slices.Contains(holders, p.PPID) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// signalOutcome watches the port after a non-terminating signal and reports whether
+// the signalled PIDs still hold it and which worker PIDs appeared or went away. It
+// reports false when nothing listens on the port any more. If the port could not
+// be inspected at all it says so and reports true: the signal itself was sent.
+func signalOutcome(port int, proto string, before model.Report, signalled []int32, wait time.Duration) bool {
+	prev := listenerPIDs(before)
+	var afterPIDs []int32
+	sampled := false
+	var lastErr error
+	deadline := time.Now().Add(wait)
+	for {
+		time.Sleep(250 * time.Millisecond)
+		rep, err := inspect.InspectPort(port, proto, inspect.Options{})
+		if err == nil {
+			pids := listenerPIDs(rep)
+			// stop once the set changed and held still for one sample
+			changed := !sameSet(pids, prev)
+			stable := sampled && sameSet(pids, afterPIDs)
+			afterPIDs, sampled = pids, true
+			if changed && stable {
+				break
+			}
+		} else {
+			lastErr = err
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+	}
+
+	if !sampled {
+		fmt.Printf("Signal sent, but could not inspect %d/%s afterwards: %v\n", port, proto, lastErr)
+		return true
+	}
+	if len(afterPIDs) == 0 {
+		fmt.Printf("%d/%s is no longer listening.\n", port, proto)
+		return false
+	}
+	var kept []string
+	for _, pid := range signalled {
+		if slices.Contains(afterPIDs, pid) {
+			kept = append(kept, fmt.Sprintf("%d", pid))
+		}
+	}
+	names := map[int32]string{}
+	if all, err := proctree.All(); err == nil {
+		for _, p := range all {
+			names[p.PID] = p.Name
+		}
+	}
+	var added, gone []string
+	for _, pid := range afterPIDs {
+		if !slices.Contains(prev, pid) {
+			added = append(added, fmt.Sprintf("%d (%s)", pid, names[pid]))
+		}
+	}
+	for _, pid := range prev {
+		if !slices.Contains(afterPIDs, pid) {
+			gone = append(gone, fmt.Sprintf("%d", pid))
+		}
+	}
+
+	if len(kept) > 0 {
+		fmt.Printf("Same pid %s still holds %d/%s.\n", strings.Join(kept, ", "), port, proto)
+	} else {
+		held := make([]string, len(afterPIDs))
+		for i, pid := range afterPIDs {
+			held[i] = fmt.Sprintf("%d", pid)
+		}
+		fmt.Printf("%d/%s is now held by pid %s instead.\n", port, proto, strings.Join(held, ", "))
+	}
+	switch {
+	case len(added) > 0:
+		fmt.Printf("New workers: %s\n", strings.Join(added, ", "))
+		if len(gone) > 0 {
+			fmt.Printf("Replaced:    %s\n", strings.Join(gone, ", "))
+		}
+	case len(gone) > 0:
+		fmt.Printf("Exited:      %s\n", strings.Join(gone, ", "))
+	default:
+		fmt.Printf("No worker changes seen within %s.\n", wait)
+	}
+	return true
+}
+
+func sameSet(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, x := range a {
+		if !slices.Contains(b, x) {
+			return false
+		}
+	}
+	return true
+}
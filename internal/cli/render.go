@@ -35,7 +35,7 @@ func resolveColor(mode string) bool {
 }
 
 func recentOwners(port int, proto string, n int) []render.OwnerEvent {
-	s, err := history.Load()
+	s, err := history.LoadPorts(port)
 	if err != nil || s == nil {
 		return nil
 	}
@@ -1,30 +1,43 @@
 package cli
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"os/exec"
 	"os/signal"
+	"slices"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/pratik-anurag/portik/internal/pidctl"
 	"github.com/pratik-anurag/portik/internal/reserve"
 )
 
+// portik reserve [port] [--for 30s] | --detach [--for 1h] [--label NAME]
 func runReserve(args []string) int {
+	if len(args) > 0 && args[0] == "hold" {
+		return runReserveHold(args[1:])
+	}
 	fs := flag.NewFlagSet("reserve", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 
 	var proto string
 	var bind string
 	var forStr string
+	var detach bool
+	var label string
 	var jsonOut bool
 
 	fs.StringVar(&proto, "proto", "tcp", "protocol: tcp|udp")
 	fs.StringVar(&bind, "bind", "127.0.0.1", "bind address (default 127.0.0.1)")
-	fs.StringVar(&forStr, "for", "30s", "how long to hold the reservation (e.g. 10s, 2m)")
+	fs.StringVar(&forStr, "for", "30s", "how long to hold the reservation (e.g. 10s, 2m; with --detach default 1h, 0 = until released)")
+	fs.BoolVar(&detach, "detach", false, "hold the port from a background process and return (see portik leases / release)")
+	fs.StringVar(&label, "label", "", "name for a --detach lease, usable with portik release")
 	fs.BoolVar(&jsonOut, "json", false, "output JSON")
 
 	if err := fs.Parse(args); err != nil {
@@ -34,11 +47,22 @@ func runReserve(args []string) int {
 		fmt.Fprintln(os.Stderr, "reserve: invalid --proto (tcp|udp)")
 		return 2
 	}
-	hold, err := time.ParseDuration(forStr)
-	if err != nil || hold <= 0 {
+	if detach && !flagGiven(fs, "for") {
+		forStr = "1h"
+	}
+	hold, err := parseSince(forStr)
+	if err != nil || hold < 0 || hold == 0 && !detach {
 		fmt.Fprintln(os.Stderr, "reserve: invalid --for")
 		return 2
 	}
+	if label != "" && !detach {
+		fmt.Fprintln(os.Stderr, "reserve: --label needs --detach")
+		return 2
+	}
+	if _, err := strconv.Atoi(label); err == nil {
+		fmt.Fprintln(os.Stderr, "reserve: --label must not be a number (portik release takes a port or a label)")
+		return 2
+	}
 
 	port := 0
 	if fs.NArg() >= 1 {
@@ -49,6 +73,13 @@ func runReserve(args []string) int {
 		}
 		port = p
 	}
+	if l, ok := reserve.Leased(proto)[port]; ok && port > 0 {
+		fmt.Fprintf(os.Stderr, "reserve: %d/%s is already %s (holder pid %d); portik release %d\n", port, proto, l.Describe(), l.PID, port)
+		return 1
+	}
+	if detach {
+		return reserveDetached(proto, bind, port, hold, label, jsonOut)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -71,8 +102,6 @@ func runReserve(args []string) int {
 	}
 	defer h.Close()
 
-	res.Until = time.Now().Add(hold)
-
 	if jsonOut {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
@@ -85,3 +114,100 @@ func runReserve(args []string) int {
 	<-ctxHold.Done()
 	return 0
 }
+
+// reserveDetached binds the port here, so errors and the picked port are reported
+// directly, then hands the socket to a background holder and records the lease.
+func reserveDetached(proto, bind string, port int, ttl time.Duration, label string, jsonOut bool) int {
+	res, h, err := reserve.Reserve(context.Background(), proto, bind, port)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "reserve:", err)
+		return 1
+	}
+	defer h.Close()
+	f, err := h.File()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "reserve --detach:", err)
+		return 1
+	}
+	defer f.Close()
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+
+	l := reserve.Lease{Port: res.Port, Proto: proto, Bind: res.Bind, Label: label, Created: time.Now()}
+	holdArgs := append(slices.Clone(reserve.HolderArgs), "--proto", proto, "--port", strconv.Itoa(res.Port))
+	if ttl > 0 {
+		l.Until = l.Created.Add(ttl)
+		holdArgs = append(holdArgs, "--until", l.Until.Format(time.RFC3339Nano))
+	}
+	cmd := exec.Command(exe, holdArgs...)
+	cmd.ExtraFiles = []*os.File{f} // fd 3
+	pidctl.Detach(cmd)
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, "reserve --detach:", err)
+		return 1
+	}
+	l.PID = cmd.Process.Pid
+	if err := reserve.WriteLease(&l); err != nil {
+		_ = cmd.Process.Kill()
+		fmt.Fprintln(os.Stderr, "reserve --detach:", err)
+		return 1
+	}
+	_ = cmd.Process.Release()
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(l)
+		return 0
+	}
+	as := ""
+	if label != "" {
+		as = fmt.Sprintf(" as %q", label)
+	}
+	fmt.Printf("Leased %d/%s on %s%s %s (holder pid %d)\n", l.Port, l.Proto, l.Bind, as, l.Expiry(), l.PID)
+	fmt.Printf("Release it with: portik release %s\n", cmp.Or(label, strconv.Itoa(l.Port)))
+	return 0
+}
+
+// runReserveHold is the lease holder started by reserve --detach: it keeps the
+// socket it inherited on fd 3 open until the lease expires or is released.
+func runReserveHold(args []string) int {
+	fs := flag.NewFlagSet("reserve hold", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	var proto, untilStr string
+	var port int
+	fs.StringVar(&proto, "proto", "tcp", "protocol of the held socket")
+	fs.IntVar(&port, "port", 0, "held port")
+	fs.StringVar(&untilStr, "until", "", "expiry (RFC3339); empty = until released")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	sock := os.NewFile(3, "lease")
+	if sock == nil || port <= 0 {
+		fmt.Fprintln(os.Stderr, "reserve hold: started without a socket; use portik reserve --detach")
+		return 2
+	}
+	defer sock.Close()
+	defer reserve.RemoveLease(proto, port, os.Getpid())
+
+	var expire <-chan time.Time
+	if untilStr != "" {
+		until, err := time.Parse(time.RFC3339Nano, untilStr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "reserve hold: invalid --until")
+			return 2
+		}
+		expire = time.After(time.Until(until))
+	}
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
+	signal.Ignore(syscall.SIGHUP)
+	select {
+	case <-sigc:
+	case <-expire:
+	}
+	return 0
+}
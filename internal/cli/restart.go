@@ -4,10 +4,14 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/pratik-anurag/portik/internal/audit"
+	"github.com/pratik-anurag/portik/internal/docker"
 	"github.com/pratik-anurag/portik/internal/history"
 	"github.com/pratik-anurag/portik/internal/inspect"
+	"github.com/pratik-anurag/portik/internal/model"
 	"github.com/pratik-anurag/portik/internal/render"
 	"github.com/pratik-anurag/portik/internal/sys"
 )
@@ -18,11 +22,21 @@ func runRestart(args []string) int {
 	c := parseCommon(fs)
 
 	var timeoutStr string
+	var waitStr string
 	var force bool
 	var container bool
+	var viaCompose bool
+	var recreate bool
+	var logPath string
+	var rerun bool
 	fs.StringVar(&timeoutStr, "timeout", "10s", "grace period before force kill")
+	fs.StringVar(&waitStr, "wait", "30s", "wait this long for the port to listen again after restarting (0 disables)")
 	fs.BoolVar(&force, "force", false, "allow restarting processes not owned by your user (danger)")
-	fs.BoolVar(&container, "container", false, "restart mapped docker container instead (requires --docker)")
+	fs.BoolVar(&container, "container", false, "restart the mapped docker/podman container instead (implies --docker)")
+	fs.BoolVar(&viaCompose, "compose", false, "restart the mapped compose service via `compose restart` (implies --docker)")
+	fs.BoolVar(&recreate, "recreate", false, "recreate the mapped compose service via `compose up -d --force-recreate` (implies --compose)")
+	fs.BoolVar(&rerun, "cmdline", false, "stop and re-run the command line even if systemd/launchd/pm2/supervisord manages the process")
+	fs.StringVar(&logPath, "log", "", "append the restarted process's output to this file (default ~/.portik/logs/restart-<port>.log)")
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
@@ -40,6 +54,14 @@ func runRestart(args []string) int {
 		fmt.Fprintln(os.Stderr, "restart: invalid --timeout")
 		return 2
 	}
+	wait, err := time.ParseDuration(waitStr)
+	if err != nil || wait < 0 {
+		fmt.Fprintln(os.Stderr, "restart: invalid --wait")
+		return 2
+	}
+	viaCompose = viaCompose || recreate
+	container = container || viaCompose
+	c.Docker = c.Docker || container
 
 	rep, err := inspect.InspectPort(port, c.Proto, inspect.Options{EnableDocker: c.Docker, IncludeConnections: false})
 	if err != nil {
@@ -48,55 +70,186 @@ func runRestart(args []string) int {
 	}
 	_ = history.Record(rep)
 
-	if c.Docker && rep.Docker.Mapped && container {
-		if !c.Yes {
-			fmt.Printf("Restart Docker container %s (%s) mapping %d/%s? [y/N]: ",
-				rep.Docker.ContainerID, rep.Docker.ContainerName, port, c.Proto)
-			var resp string
-			_, _ = fmt.Fscanln(os.Stdin, &resp)
-			if resp != "y" && resp != "Y" {
-				fmt.Println("Aborted.")
-				return 0
-			}
+	if container && rep.Docker.Mapped {
+		code, acted := restartContainer(rep, c, viaCompose, recreate, timeout)
+		if code != 0 || !acted {
+			return code
 		}
-		res := sys.RestartDockerContainer(rep.Docker.ContainerID, timeout)
-		fmt.Print(render.ActionResult(res))
-		return res.ExitCode
+		return waitReady(port, c.Proto, wait)
+	}
+	if viaCompose {
+		fmt.Fprintf(os.Stderr, "restart: %d/%s is not mapped from a container; --compose needs a compose-managed container.\n", port, c.Proto)
+		return 1
 	}
 
 	target, ok := rep.PrimaryListener()
 	if !ok || target.PID <= 0 {
 		fmt.Fprintln(os.Stderr, "No listening process found for this port.")
 		if c.Docker && rep.Docker.Mapped {
-			fmt.Fprintln(os.Stderr, "Note: port maps to Docker container; rerun with --container to restart it.")
+			fmt.Fprintln(os.Stderr, "Note: port maps to a container; rerun with --container (or --compose) to restart it.")
 		}
 		return 1
 	}
+	if !force {
+		if err := sys.EnsureSameUser(target.PID); err != nil {
+			fmt.Fprintln(os.Stderr, "Refusing to restart process not owned by your user. Use --force to override.")
+			fmt.Fprintln(os.Stderr, "Details:", err)
+			return 1
+		}
+	}
+
+	if !rerun {
+		svc, managed, err := sys.ManagedBy(target.PID)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "restart:", err)
+			fmt.Fprintln(os.Stderr, "Use --cmdline to stop and re-run the command line anyway.")
+			return 1
+		}
+		if managed {
+			if !c.Yes {
+				if !confirm(fmt.Sprintf("Restart pid %d (%s) through %s, which manages it? This will run:\n  %s\nProceed? [y/N]: ",
+					target.PID, target.ProcName, svc.Manager, strings.Join(sys.ServiceArgs(svc), " "))) {
+					return 0
+				}
+			}
+			res := sys.RestartService(svc)
+			fmt.Print(render.ActionResult(res))
+			ae := audit.For(audit.Restart, "cli", rep, target)
+			ae.Method, ae.Force = strings.Join(sys.ServiceArgs(svc), " "), force
+			audited(ae, res)
+			if res.ExitCode != 0 {
+				return res.ExitCode
+			}
+			return waitReady(port, c.Proto, wait)
+		}
+	}
+
 	if target.Cmdline == "" {
 		fmt.Fprintln(os.Stderr, "Cannot restart: command line not available. Try running with sudo.")
 		return 1
 	}
 
-	if !force {
-		if err := sys.EnsureSameUser(target.PID); err != nil {
-			fmt.Fprintln(os.Stderr, "Refusing to restart process not owned by your user. Use --force to override.")
-			fmt.Fprintln(os.Stderr, "Details:", err)
+	// capture now: once the process is gone its cwd and environment are too
+	pc := sys.CaptureProcess(target.PID, target.Cmdline)
+	if logPath == "" {
+		if logPath, err = sys.RestartLogPath(port); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
 			return 1
 		}
 	}
 
 	if !c.Yes {
-		fmt.Printf("Restart pid %d (%s)? This will stop and re-run:\n  %s\nProceed? [y/N]: ",
-			target.PID, target.ProcName, target.Cmdline)
-		var resp string
-		_, _ = fmt.Fscanln(os.Stdin, &resp)
-		if resp != "y" && resp != "Y" {
-			fmt.Println("Aborted.")
+		why := "No service manager owns it, so this"
+		if rerun {
+			why = "This (--cmdline)"
+		}
+		if !confirm(fmt.Sprintf("Restart pid %d (%s)? %s will stop and re-run:\n  %s\n%sProceed? [y/N]: ",
+			target.PID, target.ProcName, why, pc.Describe(), restartContext(pc, logPath))) {
 			return 0
 		}
 	}
 
-	res := sys.SmartRestart(target.PID, target.Cmdline, timeout)
+	res := sys.SmartRestart(pc, timeout, logPath)
 	fmt.Print(render.ActionResult(res))
-	return res.ExitCode
+	ae := audit.For(audit.Restart, "cli", rep, target)
+	ae.Method, ae.Force = "re-run: "+pc.Describe(), force
+	audited(ae, res)
+	if res.ExitCode != 0 {
+		return res.ExitCode
+	}
+	code := waitReady(port, c.Proto, wait)
+	if code != 0 {
+		fmt.Fprintf(os.Stderr, "Output of the restarted process: %s\n", logPath)
+	}
+	return code
+}
+
+// restartContext lists where and how the relaunch runs, one indented line each.
+func restartContext(pc sys.ProcessContext, logPath string) string {
+	var b strings.Builder
+	if pc.Cwd != "" {
+		fmt.Fprintf(&b, "  in %s\n", pc.Cwd)
+	}
+	if len(pc.Env) > 0 {
+		fmt.Fprintf(&b, "  with its original environment (%d variables)\n", len(pc.Env))
+	} else {
+		b.WriteString("  with portik's environment (original not readable)\n")
+	}
+	if pc.UID >= 0 && os.Geteuid() == 0 && pc.UID != 0 {
+		fmt.Fprintf(&b, "  as uid %d\n", pc.UID)
+	}
+	fmt.Fprintf(&b, "  output to %s\n", logPath)
+	return b.String()
+}
+
+// restartContainer restarts the container mapped to the port, either directly or through
+// compose when the container belongs to a compose service. acted is false if the user aborted.
+func restartContainer(rep model.Report, c *commonFlags, viaCompose, recreate bool, timeout time.Duration) (code int, acted bool) {
+	rt := rep.Docker.Runtime
+	var ci docker.ComposeInfo
+	if rep.Docker.ComposeProject != "" || viaCompose {
+		ci = docker.Compose(rt, rep.Docker.ContainerID)
+	}
+
+	if viaCompose {
+		if ci.Project == "" || ci.Service == "" {
+			fmt.Fprintf(os.Stderr, "restart: container %s (%s) has no compose project/service labels; use --container instead.\n",
+				rep.Docker.ContainerID, rep.Docker.ContainerName)
+			return 1, false
+		}
+		opt := sys.ComposeRestart{
+			Runtime:     rt,
+			Project:     ci.Project,
+			Service:     ci.Service,
+			WorkingDir:  ci.WorkingDir,
+			ConfigFiles: ci.ConfigFiles,
+			Recreate:    recreate,
+		}
+		if !c.Yes {
+			if !confirm(fmt.Sprintf("Restart compose service %s (project %s) mapping %d/%s via:\n  %s\nProceed? [y/N]: ",
+				ci.Service, ci.Project, rep.Port, rep.Proto, strings.Join(sys.ComposeArgs(opt, timeout), " "))) {
+				return 0, false
+			}
+		}
+		res := sys.RestartComposeService(opt, timeout)
+		fmt.Print(render.ActionResult(res))
+		audited(containerAudit(rep, strings.Join(sys.ComposeArgs(opt, timeout), " ")), res)
+		return res.ExitCode, true
+	}
+
+	if ci.Project != "" && ci.Service != "" {
+		fmt.Printf("Note: container belongs to compose service %s (project %s); use --compose or --recreate to restart it through compose.\n",
+			ci.Service, ci.Project)
+	}
+	if !c.Yes {
+		if !confirm(fmt.Sprintf("Restart %s container %s (%s) mapping %d/%s? [y/N]: ",
+			rt, rep.Docker.ContainerID, rep.Docker.ContainerName, rep.Port, rep.Proto)) {
+			return 0, false
+		}
+	}
+	res := sys.RestartContainer(rt, rep.Docker.ContainerID, timeout)
+	fmt.Print(render.ActionResult(res))
+	audited(containerAudit(rep, rt+" restart "+rep.Docker.ContainerID), res)
+	return res.ExitCode, true
+}
+
+func containerAudit(rep model.Report, method string) audit.Entry {
+	l, _ := rep.PrimaryListener()
+	e := audit.For(audit.Restart, "cli", rep, l)
+	e.Method = method
+	return e
+}
+
+// waitReady waits for the restarted owner to listen on the port again.
+func waitReady(port int, proto string, wait time.Duration) int {
+	if wait <= 0 {
+		return 0
+	}
+	fmt.Printf("Waiting up to %s for %d/%s to listen…\n", wait, port, proto)
+	if waitForPort(port, proto, false, isReady, wait, 500*time.Millisecond) {
+		fmt.Printf("%d/%s is LISTENING\n", port, proto)
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "restart: %d/%s is not listening after %s\n", port, proto, wait)
+	return 1
 }
@@ -24,6 +24,10 @@ func Run(args []string) int {
 		return runKill(args[1:])
 	case "restart":
 		return runRestart(args[1:])
+	case "reload":
+		return runReload(args[1:])
+	case "audit":
+		return runAudit(args[1:])
 	case "watch":
 		return runWatch(args[1:])
 	case "history":
@@ -40,6 +44,10 @@ func Run(args []string) int {
 		return runFree(args[1:])
 	case "reserve":
 		return runReserve(args[1:])
+	case "leases":
+		return runLeases(args[1:])
+	case "release":
+		return runRelease(args[1:])
 	case "use":
 		return runUse(args[1:])
 	case "conn":
@@ -50,6 +58,8 @@ func Run(args []string) int {
 		return runWait(args[1:])
 	case "trace":
 		return runTrace(args[1:])
+	case "compose":
+		return runCompose(args[1:])
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", args[0])
 		printHelp()
@@ -66,22 +76,31 @@ Usage:
 Commands:
   who <port>        Show who is listening on a port
   explain <port>    Explain likely reasons a port is stuck / bind fails
-  kill <port>       Terminate the process owning a port (safe by default)
+  kill <port>       Terminate the process owning a port (safe by default; --tree, --pgid, --signal)
+  kill <spec>       Batch kill by ports spec / --name / --user / --container (--dry-run)
   restart <port>    Smart restart (kill + restart last command)
-  watch <port>      Watch a port and record changes
+  reload <port>     Send the server's reload signal (nginx HUP, ...) and report worker changes
+  audit             Query the log of kill/restart/reload actions (--user, --port, --action)
+  watch <port>|--all  Watch a port (or every listening port) and record changes
   history <port>    Show port ownership history (+ pattern detection)
-  daemon            Monitor multiple ports and record history (foreground)
+  history           Machine-wide history (--owner, --user, --cmd, --service filters)
+  history prune|export|import  Manage recorded history
+  daemon            Monitor multiple (or --all listening) ports and record history (foreground)
+  daemon start|stop|status|reload|install  Run the daemon in the background / as a service
 	blame <port>      Process tree + who started this
 	tui               Interactive TUI (build tag: tui)
 
 	scan              Scan a set/range of ports and show a table
-  free              Find a free port (optionally within a range)
-  reserve           Reserve a port by binding it for a duration
-  use               Run a command with a free PORT selected automatically
+  free              Find a free port (optionally within a range; --lease hands it out through the broker)
+  reserve           Reserve a port by binding it for a duration (--detach: background lease)
+  leases            List active leases from reserve --detach
+  release <port|label>  Drop a lease
+  use               Run a command with a free PORT selected automatically (--pass-fd: hand it the bound socket)
   conn              Show active connections to/from a port (top clients)
   top               Top ports by connection count
   wait              Wait until a port is listening or becomes free
   trace             Trace ownership and routing hints for a port
+  compose [file]    Check a compose project's published ports for conflicts
 
   version           Show version
 
@@ -13,12 +13,13 @@ import (
 	"github.com/pratik-anurag/portik/internal/model"
 	"github.com/pratik-anurag/portik/internal/ports"
 	"github.com/pratik-anurag/portik/internal/render"
+	"github.com/pratik-anurag/portik/internal/reserve"
 )
 
 type scanRow struct {
 	Port      int    `json:"port"`
 	Proto     string `json:"proto"`
-	Status    string `json:"status"` // free|in-use|unknown|error
+	Status    string `json:"status"` // free|in-use|leased|unknown|error
 	Owner     string `json:"owner,omitempty"`
 	PID       int32  `json:"pid,omitempty"`
 	Addr      string `json:"addr,omitempty"`
@@ -26,6 +27,8 @@ type scanRow struct {
 	Hint      string `json:"hint,omitempty"`
 	Error     string `json:"error,omitempty"`
 	Signature string `json:"signature,omitempty"`
+
+	Lease *reserve.Lease `json:"lease,omitempty"`
 }
 
 func runScan(args []string) int {
@@ -64,7 +67,8 @@ func runScan(args []string) int {
 		concurrency = 32
 	}
 
-	rows := scanPorts(portsList, c.Proto, c.Docker, concurrency)
+	rows := scanPorts(portsList, c.Proto, c.Docker, concurrency, cachedReports(portsList, c))
+	markLeased(rows, reserve.Leased(c.Proto))
 
 	if c.JSON {
 		enc := json.NewEncoder(os.Stdout)
@@ -81,7 +85,9 @@ func runScan(args []string) int {
 	return 0
 }
 
-func scanPorts(portsList []int, proto string, docker bool, conc int) []scanRow {
+// scanPorts inspects portsList concurrently; ports found in cached (the daemon's
+// cache) are not inspected again.
+func scanPorts(portsList []int, proto string, docker bool, conc int, cached map[int]model.Report) []scanRow {
 	type job struct {
 		port int
 	}
@@ -110,6 +116,12 @@ func scanPorts(portsList []int, proto string, docker bool, conc int) []scanRow {
 	}
 
 	for _, p := range portsList {
+		if rep, ok := cached[p]; ok {
+			mu.Lock()
+			out = append(out, reportToScanRow(rep, nil))
+			mu.Unlock()
+			continue
+		}
 		jobs <- job{port: p}
 	}
 	close(jobs)
@@ -158,6 +170,34 @@ func reportToScanRow(rep model.Report, err error) scanRow {
 	return row
 }
 
+// markLeased shows ports held by `portik reserve --detach` as leased rather than
+// free or in use by an anonymous portik process.
+func markLeased(rows []scanRow, leased map[int]reserve.Lease) {
+	for i := range rows {
+		r := &rows[i]
+		l, ok := leased[r.Port]
+		if !ok {
+			continue
+		}
+		r.Lease = &l
+		if r.Status == "in-use" && r.PID != int32(l.PID) {
+			// someone else bound it on another address
+			r.Hint = l.Describe()
+			continue
+		}
+		r.Status = "leased"
+		r.PID = int32(l.PID)
+		r.Owner = strings.TrimSpace("lease " + l.Label)
+		if l.User != "" {
+			r.Owner += " (" + l.User + ")"
+		}
+		r.Hint = l.Expiry()
+		if l.Client && l.Until.IsZero() {
+			r.Hint = "until the pid exits"
+		}
+	}
+}
+
 func scanHint(diags []model.Diagnostic) string {
 	// keep scan output short: pick first warn/error, else first info
 	for _, d := range diags {
@@ -21,6 +21,7 @@ func runTUI(args []string) int {
 	var docker bool
 	var actions bool
 	var force bool
+	var noDaemon bool
 
 	fs.StringVar(&portsStr, "ports", "", "comma-separated ports to monitor (e.g., 5432,6379,8080)")
 	fs.StringVar(&intervalStr, "interval", "2s", "poll interval")
@@ -28,6 +29,7 @@ func runTUI(args []string) int {
 	fs.BoolVar(&docker, "docker", false, "enable docker mapping")
 	fs.BoolVar(&actions, "actions", false, "enable kill/restart actions (with confirm)")
 	fs.BoolVar(&force, "force", false, "allow actions on non-owned processes (danger; requires --actions)")
+	fs.BoolVar(&noDaemon, "no-daemon", false, "inspect live instead of using a running daemon's cache")
 
 	if err := fs.Parse(args); err != nil {
 		return 2
@@ -62,6 +64,7 @@ func runTUI(args []string) int {
 		Docker:   docker,
 		Actions:  actions,
 		Force:    force,
+		NoDaemon: noDaemon,
 	}
 	if err := tui.Run(opts); err != nil {
 		fmt.Fprintln(os.Stderr, "tui:", err)
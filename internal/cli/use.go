@@ -4,16 +4,24 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"runtime"
 	"time"
 
-	"github.com/pratik-anurag/portik/internal/reserve"
 	"github.com/pratik-anurag/portik/internal/use"
 )
 
 // portik use --ports 3000-3999 -- <cmd> [args...]
 // Exposes PORT=<picked> to the child process.
 // Optionally replaces "{PORT}" in argv (no shell needed) or runs via `sh -lc` (shell expansion).
+// With --pass-fd the port stays bound and the child inherits the listener (socket activation).
 func runUse(args []string) int {
+	if len(args) > 0 && args[0] == use.ActivateArgs[1] {
+		return runUseActivate(args[1:])
+	}
+	return runUseCmd(args)
+}
+
+func runUseCmd(args []string) int {
 	fs := flag.NewFlagSet("use", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 
@@ -25,6 +33,8 @@ func runUse(args []string) int {
 	var shell bool
 	var printOnly bool
 	var timeoutStr string
+	var passFD bool
+	var fdName, fdEnv string
 
 	fs.StringVar(&portsSpec, "ports", "", "ports spec (recommended range): e.g. 3000-3999. If omitted, uses ephemeral port")
 	fs.StringVar(&proto, "proto", "tcp", "protocol: tcp|udp (default tcp)")
@@ -34,6 +44,9 @@ func runUse(args []string) int {
 	fs.BoolVar(&shell, "shell", false, "run command through `sh -lc` (enables $PORT expansion)")
 	fs.BoolVar(&printOnly, "print", false, "print chosen port and exit (do not run command)")
 	fs.StringVar(&timeoutStr, "timeout", "3s", "max time allowed to find a free port")
+	fs.BoolVar(&passFD, "pass-fd", false, "keep the port bound and pass the listener to the command as fd 3 (LISTEN_FDS/LISTEN_PID/LISTEN_FDNAMES)")
+	fs.StringVar(&fdName, "fd-name", "portik", "LISTEN_FDNAMES value for --pass-fd")
+	fs.StringVar(&fdEnv, "fd-env", "", "also set this variable to the listener's fd number (implies --pass-fd)")
 
 	if err := fs.Parse(args); err != nil {
 		return 2
@@ -57,17 +70,48 @@ func runUse(args []string) int {
 		return 2
 	}
 
-	// Pick a free port (range if provided, else ephemeral).
-	port, err := use.PickFreePort(use.PickOptions{
+	passFD = passFD || fdEnv != ""
+	if passFD && runtime.GOOS == "windows" {
+		fmt.Fprintln(os.Stderr, "use: --pass-fd is not supported on Windows")
+		return 2
+	}
+	if passFD && shell {
+		// LISTEN_PID would name the shell, which may fork the server instead of
+		// exec'ing it; sd_listen_fds() then ignores the listener
+		fmt.Fprintln(os.Stderr, "use: --pass-fd cannot be combined with --shell; use --template for {PORT}")
+		return 2
+	}
+
+	pick := use.PickOptions{
 		Proto:     proto,
 		Bind:      bind,
 		PortsSpec: portsSpec,
 		Attempts:  attempts,
 		Timeout:   timeout,
-	})
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "use:", err)
-		return 1
+	}
+	// Pick a free port (range if provided, else ephemeral); with --pass-fd it
+	// stays bound so nothing can take it before the child starts.
+	var port int
+	var listener *os.File
+	if passFD && !printOnly {
+		res, h, err := use.BindFreePort(pick)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "use:", err)
+			return 1
+		}
+		defer h.Close()
+		if listener, err = h.File(); err != nil {
+			fmt.Fprintln(os.Stderr, "use:", err)
+			return 1
+		}
+		defer listener.Close()
+		port = res.Port
+	} else {
+		port, err = use.PickFreePort(pick)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "use:", err)
+			return 1
+		}
 	}
 
 	if printOnly {
@@ -77,18 +121,20 @@ func runUse(args []string) int {
 
 	// Run the child process with PORT set.
 	code, err := use.RunWithPort(use.RunOptions{
-		Port:        port,
-		Proto:       proto,
-		Bind:        bind,
-		Args:        cmdArgs,
-		Template:    template,
-		Shell:       shell,
-		EnvVarName:  "PORT",
-		ExtraEnv:    nil,
-		ReserveHint: reserve.FreeOptions{Proto: proto, Bind: bind, Attempts: attempts},
-		Stdout:      os.Stdout,
-		Stderr:      os.Stderr,
-		Stdin:       os.Stdin,
+		Port:       port,
+		Proto:      proto,
+		Bind:       bind,
+		Args:       cmdArgs,
+		Template:   template,
+		Shell:      shell,
+		EnvVarName: "PORT",
+		ExtraEnv:   nil,
+		Listener:   listener,
+		FDName:     fdName,
+		FDEnv:      fdEnv,
+		Stdout:     os.Stdout,
+		Stderr:     os.Stderr,
+		Stdin:      os.Stdin,
 	})
 	if err != nil {
 		// Preserve exit code if available
@@ -100,3 +146,14 @@ func runUse(args []string) int {
 	}
 	return code
 }
+
+// runUseActivate runs in the child of use --pass-fd: it sets LISTEN_PID to its
+// own pid and execs the command, which inherits the listener on fd 3.
+func runUseActivate(args []string) int {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	err := use.Activate(args)
+	fmt.Fprintln(os.Stderr, "use:", err)
+	return 127
+}
@@ -70,40 +70,44 @@ func runWait(args []string) int {
 		return 2
 	}
 
+	cond := isListening
+	if wantFree {
+		cond = isFree
+	}
+	if waitForPort(port, proto, docker, cond, timeout, interval) {
+		if !quiet {
+			if wantListening {
+				fmt.Printf("%d/%s is LISTENING\n", port, proto)
+			} else {
+				fmt.Printf("%d/%s is FREE\n", port, proto)
+			}
+		}
+		return 0
+	}
+	if !quiet {
+		mode := "LISTENING"
+		if wantFree {
+			mode = "FREE"
+		}
+		fmt.Fprintf(os.Stderr, "wait: timeout waiting for %d/%s to be %s\n", port, proto, mode)
+	}
+	return 1
+}
+
+// waitForPort polls the port until cond holds and reports whether that happened
+// before the timeout.
+func waitForPort(port int, proto string, docker bool, cond func(model.Report) bool, timeout, interval time.Duration) bool {
 	deadline := time.Now().Add(timeout)
 	for {
 		rep, err := inspect.InspectPort(port, proto, inspect.Options{
 			EnableDocker:       docker,
 			IncludeConnections: false,
 		})
-		if err == nil {
-			ok := false
-			if wantListening {
-				ok = isListening(rep)
-			} else {
-				ok = isFree(rep)
-			}
-			if ok {
-				if !quiet {
-					if wantListening {
-						fmt.Printf("%d/%s is LISTENING\n", port, proto)
-					} else {
-						fmt.Printf("%d/%s is FREE\n", port, proto)
-					}
-				}
-				return 0
-			}
+		if err == nil && cond(rep) {
+			return true
 		}
-
 		if time.Now().After(deadline) {
-			if !quiet {
-				mode := "LISTENING"
-				if wantFree {
-					mode = "FREE"
-				}
-				fmt.Fprintf(os.Stderr, "wait: timeout waiting for %d/%s to be %s\n", port, proto, mode)
-			}
-			return 1
+			return false
 		}
 		time.Sleep(interval)
 	}
@@ -117,6 +121,17 @@ func isListening(rep model.Report) bool {
 	return strings.ToUpper(strings.TrimSpace(l.State)) == "LISTEN"
 }
 
+// isReady is a looser isListening used after restarts: a listener counts even when its
+// PID is not visible (e.g. root-owned docker-proxy), and any bound UDP socket counts.
+func isReady(rep model.Report) bool {
+	for _, l := range rep.Listeners {
+		if rep.Proto == "udp" || strings.ToUpper(strings.TrimSpace(l.State)) == "LISTEN" {
+			return true
+		}
+	}
+	return false
+}
+
 func isFree(rep model.Report) bool {
 	// Free means no LISTEN listeners. Keep it conservative.
 	if l, ok := rep.PrimaryListener(); ok {
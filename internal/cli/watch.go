@@ -8,7 +8,10 @@ import (
 	"time"
 
 	"github.com/pratik-anurag/portik/internal/history"
+	"github.com/pratik-anurag/portik/internal/hooks"
 	"github.com/pratik-anurag/portik/internal/inspect"
+	"github.com/pratik-anurag/portik/internal/model"
+	"github.com/pratik-anurag/portik/internal/ports"
 	"github.com/pratik-anurag/portik/internal/render"
 )
 
@@ -17,19 +20,39 @@ func runWatch(args []string) int {
 	fs.SetOutput(os.Stderr)
 	c := parseCommon(fs)
 
-	var intervalStr string
+	var intervalStr, includeStr, excludeStr string
+	var all bool
 	fs.StringVar(&intervalStr, "interval", "10s", "poll interval")
+	fs.BoolVar(&all, "all", false, "discover and watch every listening port (one socket enumeration per interval)")
+	fs.StringVar(&includeStr, "include", "", "with --all, only watch these ports (e.g., 3000-9999)")
+	fs.StringVar(&excludeStr, "exclude", "", "with --all, never watch these ports (e.g., 22,631)")
+	hf := addHookFlags(fs)
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
-	if fs.NArg() < 1 {
-		fmt.Fprintln(os.Stderr, "watch: missing <port>")
+	var port int
+	var filter ports.Filter
+	var err error
+	switch {
+	case all && fs.NArg() > 0:
+		fmt.Fprintln(os.Stderr, "watch: use either <port> or --all")
 		return 2
-	}
-	port, err := parsePort(fs.Arg(0))
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "watch:", err)
+	case all:
+		if filter, err = ports.ParseFilter(includeStr, excludeStr); err != nil {
+			fmt.Fprintln(os.Stderr, "watch: invalid --include/--exclude:", err)
+			return 2
+		}
+	case includeStr != "" || excludeStr != "":
+		fmt.Fprintln(os.Stderr, "watch: --include and --exclude need --all")
 		return 2
+	case fs.NArg() < 1:
+		fmt.Fprintln(os.Stderr, "watch: missing <port> (or --all)")
+		return 2
+	default:
+		if port, err = parsePort(fs.Arg(0)); err != nil {
+			fmt.Fprintln(os.Stderr, "watch:", err)
+			return 2
+		}
 	}
 	interval, err := time.ParseDuration(intervalStr)
 	if err != nil || interval < time.Second {
@@ -37,27 +60,91 @@ func runWatch(args []string) int {
 		return 2
 	}
 
-	var lastSig string
+	hookOpt, err := hf.Options()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "watch: hooks:", err)
+		return 2
+	}
+	var dispatch *hooks.Dispatcher
+	detector := &hooks.Detector{MinSeverity: hf.Severity}
+	if hookOpt.Enabled() {
+		dispatch = hooks.NewDispatcher(hookOpt)
+	}
+
 	t := time.NewTicker(interval)
 	defer t.Stop()
 
+	if all {
+		watchAll(c, filter, detector, dispatch, t.C)
+		return 0
+	}
+
+	var lastSig string
 	for {
 		rep, err := inspect.InspectPort(port, c.Proto, inspect.Options{EnableDocker: c.Docker, IncludeConnections: false})
 		if err == nil {
 			_ = history.Record(rep)
+			if dispatch != nil {
+				for _, ev := range detector.Events(rep) {
+					dispatch.Send(ev)
+				}
+			}
 			sig := rep.Signature()
 			if sig != lastSig {
 				lastSig = sig
-				if c.JSON {
-					enc := json.NewEncoder(os.Stdout)
-					enc.SetIndent("", "  ")
-					_ = enc.Encode(rep)
-				} else {
-					fmt.Print(render.Who(rep, renderOptions(c)))
-					fmt.Println("---")
-				}
+				printWatch(rep, c)
 			}
 		}
 		<-t.C
 	}
 }
+
+// watchAll tracks every listening port the filter selects, printing and recording
+// each one when it appears, changes owner or is released.
+func watchAll(c *commonFlags, filter ports.Filter, detector *hooks.Detector, dispatch *hooks.Dispatcher, tick <-chan time.Time) {
+	dv := newDiscoverer(c.Proto, c.Docker, false, filter)
+	lastSig := map[int]string{}
+	first := true
+	for {
+		reps, appeared, gone, err := dv.scan()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "watch:", err)
+		}
+		if !first {
+			for _, p := range appeared {
+				// the port was free until now, so its first owner is a change
+				detector.Events(model.Report{Port: p, Proto: c.Proto})
+			}
+		}
+		for _, rep := range reps {
+			_ = history.Record(rep)
+			if dispatch != nil {
+				for _, ev := range detector.Events(rep) {
+					dispatch.Send(ev)
+				}
+			}
+			if sig := rep.Signature(); sig != lastSig[rep.Port] {
+				lastSig[rep.Port] = sig
+				printWatch(rep, c)
+			}
+		}
+		for _, p := range gone {
+			delete(lastSig, p)
+		}
+		if err == nil {
+			first = false
+		}
+		<-tick
+	}
+}
+
+func printWatch(rep model.Report, c *commonFlags) {
+	if c.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(rep)
+		return
+	}
+	fmt.Print(render.Who(rep, renderOptions(c)))
+	fmt.Println("---")
+}
@@ -1,14 +1,19 @@
 package cli
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/pratik-anurag/portik/internal/daemon"
 	"github.com/pratik-anurag/portik/internal/history"
 	"github.com/pratik-anurag/portik/internal/inspect"
+	"github.com/pratik-anurag/portik/internal/model"
 	"github.com/pratik-anurag/portik/internal/render"
 )
 
@@ -18,11 +23,32 @@ func runWho(args []string) int {
 	c := parseCommon(fs)
 	var follow bool
 	var intervalStr string
+	var atStr string
 	fs.BoolVar(&follow, "follow", false, "stream changes (delta-only)")
 	fs.StringVar(&intervalStr, "interval", "2s", "poll interval for --follow")
+	fs.StringVar(&atStr, "at", "", "show who owned the port at a past time, from history (e.g. -2h, \"2026-10-01 09:12\")")
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
+	if atStr != "" {
+		if follow {
+			fmt.Fprintln(os.Stderr, "who: --at cannot be combined with --follow")
+			return 2
+		}
+		at, err := parseAt(atStr, time.Now())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "who: --at:", err)
+			return 2
+		}
+		port := 0
+		if fs.NArg() >= 1 {
+			if port, err = parsePort(fs.Arg(0)); err != nil {
+				fmt.Fprintln(os.Stderr, "who:", err)
+				return 2
+			}
+		}
+		return whoAt(port, at, c)
+	}
 	if fs.NArg() < 1 {
 		fmt.Fprintln(os.Stderr, "who: missing <port>")
 		return 2
@@ -42,12 +68,11 @@ func runWho(args []string) int {
 		return followWho(port, c, interval)
 	}
 
-	rep, err := inspect.InspectPort(port, c.Proto, inspect.Options{EnableDocker: c.Docker, IncludeConnections: false})
+	rep, cached, err := lookupPort(port, c)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "error:", err)
 		return 1
 	}
-	_ = history.Record(rep)
 
 	if c.JSON {
 		enc := json.NewEncoder(os.Stdout)
@@ -59,33 +84,53 @@ func runWho(args []string) int {
 	opt := renderOptions(c)
 	opt.RecentOwners = recentOwners(port, c.Proto, 3)
 	fmt.Print(render.Who(rep, opt))
+	if cached != nil && !c.Summary {
+		fmt.Print(cachedNote(cached))
+	}
 	return 0
 }
 
+// followWho prints the port's owner whenever it changes. With a daemon monitoring the
+// port it follows the daemon's change feed; otherwise (or once the daemon goes away)
+// it polls.
 func followWho(port int, c *commonFlags, interval time.Duration) int {
 	var lastSig string
+	show := func(rep model.Report) {
+		sig := rep.Signature()
+		if sig == lastSig {
+			return
+		}
+		lastSig = sig
+		if c.JSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(rep)
+			return
+		}
+		opt := renderOptions(c)
+		opt.RecentOwners = recentOwners(port, c.Proto, 3)
+		fmt.Print(changeBanner(opt.Color, port, c.Proto))
+		fmt.Print(render.Who(rep, opt))
+		fmt.Println("---")
+	}
+
+	if cl := daemonClient(c); cl != nil {
+		if cr, ok := cl.Report(port, c.Proto, c.Docker, cacheMaxAge); ok {
+			show(cr.Report)
+			_ = cl.Feed(context.Background(), []int{port}, func(ch daemon.Change) error {
+				show(ch.Report)
+				return nil
+			})
+		}
+	}
+
 	t := time.NewTicker(interval)
 	defer t.Stop()
-
 	for {
 		rep, err := inspect.InspectPort(port, c.Proto, inspect.Options{EnableDocker: c.Docker, IncludeConnections: false})
 		if err == nil {
 			_ = history.Record(rep)
-			sig := rep.Signature()
-			if sig != lastSig {
-				lastSig = sig
-				if c.JSON {
-					enc := json.NewEncoder(os.Stdout)
-					enc.SetIndent("", "  ")
-					_ = enc.Encode(rep)
-				} else {
-					opt := renderOptions(c)
-					opt.RecentOwners = recentOwners(port, c.Proto, 3)
-					fmt.Print(changeBanner(opt.Color, port, c.Proto))
-					fmt.Print(render.Who(rep, opt))
-					fmt.Println("---")
-				}
-			}
+			show(rep)
 		}
 		<-t.C
 	}
@@ -98,3 +143,80 @@ func changeBanner(color bool, port int, proto string) string {
 	}
 	return "\x1b[35m" + msg + "\x1b[0m"
 }
+
+type whoAtResult struct {
+	history.Snapshot
+	StaleSec int64        `json:"stale_sec"`
+	Report   model.Report `json:"report"`
+}
+
+// whoAt rebuilds ownership at a past time from history. Port 0 means every recorded port
+// (only those that were occupied are shown).
+func whoAt(port int, at time.Time, c *commonFlags) int {
+	var s *history.Store
+	var err error
+	if port > 0 {
+		s, err = history.LoadPorts(port)
+	} else {
+		s, err = history.Load()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	keys := []string{fmt.Sprintf("%d/%s", port, c.Proto)}
+	if port == 0 {
+		keys = keys[:0]
+		for k := range s.Ports {
+			if strings.HasSuffix(k, "/"+c.Proto) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool { return keyPort(keys[i]) < keyPort(keys[j]) })
+	}
+
+	var results []whoAtResult
+	for _, k := range keys {
+		snap := s.SnapshotAt(k, at)
+		if port == 0 && (snap.Sample == nil || snap.Sample.IsReleased()) {
+			continue
+		}
+		rep := model.Report{Port: keyPort(k), Proto: c.Proto, Generated: at}
+		if snap.Sample != nil {
+			rep = history.ReportFromEvent(*snap.Sample)
+			rep.Generated = at
+		}
+		results = append(results, whoAtResult{Snapshot: snap, StaleSec: int64(snap.StaleFor() / time.Second), Report: rep})
+	}
+
+	if c.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if port > 0 {
+			_ = enc.Encode(results[0])
+		} else {
+			_ = enc.Encode(results)
+		}
+		return 0
+	}
+	if len(results) == 0 {
+		fmt.Printf("No recorded %s port was occupied at %s.\n", c.Proto, at.Local().Format("2006-01-02 15:04:05"))
+		return 0
+	}
+	for i, r := range results {
+		if i > 0 {
+			fmt.Println("---")
+		}
+		opt := renderOptions(c)
+		snap := r.Snapshot
+		opt.AsOf = &snap
+		fmt.Print(render.Who(r.Report, opt))
+	}
+	return 0
+}
+
+func keyPort(key string) int {
+	var p int
+	fmt.Sscanf(key, "%d/", &p)
+	return p
+}
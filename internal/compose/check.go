@@ -0,0 +1,190 @@
+package compose
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pratik-anurag/portik/internal/inspect"
+	"github.com/pratik-anurag/portik/internal/model"
+)
+
+type PortStatus struct {
+	PublishedPort
+	Status         string `json:"status"` // free|project|conflict|error
+	Owner          string `json:"owner,omitempty"`
+	PID            int32  `json:"pid,omitempty"`
+	Container      string `json:"container,omitempty"`
+	ComposeProject string `json:"compose_project,omitempty"`
+	ComposeService string `json:"compose_service,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+type Conflict struct {
+	Kind    string `json:"kind"` // in-use|other-project|duplicate
+	Port    int    `json:"port"`
+	Proto   string `json:"proto"`
+	Service string `json:"service"`
+	Summary string `json:"summary"`
+	Action  string `json:"action,omitempty"`
+}
+
+type CheckOptions struct {
+	Docker      bool // map listeners to containers (needed to recognise the project's own containers)
+	Concurrency int
+}
+
+// Check inspects every published host port of the project and classifies its current owner.
+func Check(p Project, opt CheckOptions) []PortStatus {
+	conc := opt.Concurrency
+	if conc <= 0 {
+		conc = 4
+	}
+	out := make([]PortStatus, len(p.Ports))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < conc; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				pp := p.Ports[i]
+				rep, err := inspect.InspectPort(pp.HostPort, pp.Proto, inspect.Options{EnableDocker: opt.Docker})
+				out[i] = Classify(p.Name, pp, rep, err)
+			}
+		}()
+	}
+	for i := range p.Ports {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return out
+}
+
+// Classify decides whether a published port is free, held by the project itself, or held
+// by something else.
+func Classify(project string, pp PublishedPort, rep model.Report, err error) PortStatus {
+	st := PortStatus{PublishedPort: pp, Status: "free"}
+	if err != nil {
+		st.Status = "error"
+		st.Error = err.Error()
+		return st
+	}
+	if rep.Docker.Mapped {
+		st.Container = rep.Docker.ContainerName
+		st.ComposeProject = rep.Docker.ComposeProject
+		st.ComposeService = rep.Docker.ComposeService
+	}
+	l, ok := rep.PrimaryListener()
+	if !ok && !rep.Docker.Mapped {
+		return st
+	}
+	if ok {
+		st.PID = l.PID
+		st.Owner = ownerLabel(l)
+	}
+	if rep.Docker.Mapped && rep.Docker.ComposeProject != "" && rep.Docker.ComposeProject == project {
+		st.Status = "project"
+		return st
+	}
+	st.Status = "conflict"
+	return st
+}
+
+// Conflicts lists the problems `docker compose up` would run into: ports held by other
+// processes or projects, and host ports published twice within the file.
+func Conflicts(p Project, rows []PortStatus) []Conflict {
+	var out []Conflict
+
+	byKey := map[string][]PublishedPort{}
+	for _, pp := range p.Ports {
+		k := fmt.Sprintf("%d/%s", pp.HostPort, pp.Proto)
+		byKey[k] = append(byKey[k], pp)
+	}
+	keys := make([]string, 0, len(byKey))
+	for k := range byKey {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		pps := byKey[k]
+		if len(pps) < 2 || !overlappingHostIPs(pps) {
+			continue
+		}
+		var svcs []string
+		for _, pp := range pps {
+			svcs = append(svcs, pp.Service)
+		}
+		out = append(out, Conflict{
+			Kind:    "duplicate",
+			Port:    pps[0].HostPort,
+			Proto:   pps[0].Proto,
+			Service: strings.Join(svcs, ","),
+			Summary: fmt.Sprintf("%s is published by more than one service (%s)", k, strings.Join(svcs, ", ")),
+			Action:  "Publish each service on its own host port.",
+		})
+	}
+
+	for _, r := range rows {
+		if r.Status != "conflict" {
+			continue
+		}
+		c := Conflict{Kind: "in-use", Port: r.HostPort, Proto: r.Proto, Service: r.Service}
+		switch {
+		case r.ComposeProject != "":
+			c.Kind = "other-project"
+			c.Summary = fmt.Sprintf("%d/%s (%s) is held by compose project %q (service=%s, container=%s)",
+				r.HostPort, r.Proto, r.Service, r.ComposeProject, dash(r.ComposeService), dash(r.Container))
+			c.Action = fmt.Sprintf("Stop it: docker compose -p %s stop %s  |  or publish %s on another host port",
+				r.ComposeProject, r.ComposeService, r.Service)
+		case r.Container != "":
+			c.Summary = fmt.Sprintf("%d/%s (%s) is held by container %s", r.HostPort, r.Proto, r.Service, r.Container)
+			c.Action = fmt.Sprintf("Stop it: docker stop %s  |  or publish %s on another host port", r.Container, r.Service)
+		default:
+			c.Summary = fmt.Sprintf("%d/%s (%s) is held by %s", r.HostPort, r.Proto, r.Service, dash(r.Owner))
+			if r.PID > 0 {
+				c.Summary += fmt.Sprintf(" pid %d", r.PID)
+			}
+			c.Action = fmt.Sprintf("Stop it: portik kill %d  |  or publish %s on another host port", r.HostPort, r.Service)
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func overlappingHostIPs(pps []PublishedPort) bool {
+	seen := map[string]bool{}
+	for _, pp := range pps {
+		ip := pp.HostIP
+		if ip == "" || ip == "0.0.0.0" || ip == "::" {
+			return true
+		}
+		if seen[ip] {
+			return true
+		}
+		seen[ip] = true
+	}
+	return false
+}
+
+func ownerLabel(l model.Listener) string {
+	if l.ProcName != "" {
+		if l.User != "" {
+			return fmt.Sprintf("%s (%s)", l.ProcName, l.User)
+		}
+		return l.ProcName
+	}
+	if l.PID > 0 {
+		return fmt.Sprintf("pid:%d", l.PID)
+	}
+	return "unknown"
+}
+
+func dash(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "-"
+	}
+	return s
+}
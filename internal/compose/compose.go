@@ -0,0 +1,242 @@
+package compose
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultFiles are the compose file names looked up (in order) when no file is given.
+var DefaultFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yml", "docker-compose.yaml"}
+
+type Project struct {
+	Name     string          `json:"name"`
+	File     string          `json:"file"`
+	Source   string          `json:"source"` // docker|parser
+	Services []string        `json:"services"`
+	Ports    []PublishedPort `json:"ports"`
+}
+
+type PublishedPort struct {
+	Service       string `json:"service"`
+	HostIP        string `json:"host_ip,omitempty"`
+	HostPort      int    `json:"host_port"`
+	ContainerPort int    `json:"container_port"`
+	Proto         string `json:"proto"`
+}
+
+type LoadOptions struct {
+	File        string // compose file; if empty, DefaultFiles are searched in Dir
+	Dir         string // working dir for the lookup (default ".")
+	ProjectName string // override project name
+	EnvFile     string // default: .env next to the compose file
+	NoDocker    bool   // skip `docker compose config` and use the built-in parser
+}
+
+// FindFile returns the first default compose file present in dir.
+func FindFile(dir string) (string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	for _, name := range DefaultFiles {
+		p := filepath.Join(dir, name)
+		if st, err := os.Stat(p); err == nil && !st.IsDir() {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("no compose file found in %s (tried %s)", dir, strings.Join(DefaultFiles, ", "))
+}
+
+// Load resolves a compose project and its published ports.
+// It prefers `docker compose config` (exact interpolation and merge semantics) and
+// falls back to a small built-in parser when docker is unavailable.
+func Load(opt LoadOptions) (Project, error) {
+	file := opt.File
+	if file == "" {
+		f, err := FindFile(opt.Dir)
+		if err != nil {
+			return Project{}, err
+		}
+		file = f
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return Project{}, err
+	}
+	envFile := opt.EnvFile
+	if envFile == "" {
+		envFile = filepath.Join(filepath.Dir(abs), ".env")
+	}
+
+	if !opt.NoDocker {
+		if p, err := loadWithDocker(abs, envFile, opt.ProjectName); err == nil {
+			return p, nil
+		}
+	}
+
+	b, err := os.ReadFile(abs)
+	if err != nil {
+		return Project{}, err
+	}
+	env, err := LoadDotEnv(envFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Project{}, err
+	}
+	p, err := Parse(b, lookupEnv(env))
+	if err != nil {
+		return Project{}, fmt.Errorf("%s: %w", file, err)
+	}
+	p.File = abs
+	p.Source = "parser"
+	if opt.ProjectName != "" {
+		p.Name = opt.ProjectName
+	}
+	if p.Name == "" {
+		p.Name = NormalizeProjectName(filepath.Base(filepath.Dir(abs)))
+	}
+	return p, nil
+}
+
+// lookupEnv resolves variables from the process environment first, then the .env file
+// (matching compose precedence).
+func lookupEnv(dotenv map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		if v, ok := os.LookupEnv(k); ok {
+			return v, true
+		}
+		v, ok := dotenv[k]
+		return v, ok
+	}
+}
+
+type dockerConfig struct {
+	Name     string `json:"name"`
+	Services map[string]struct {
+		Ports []struct {
+			Target    int    `json:"target"`
+			Published any    `json:"published"`
+			Protocol  string `json:"protocol"`
+			HostIP    string `json:"host_ip"`
+		} `json:"ports"`
+	} `json:"services"`
+}
+
+func loadWithDocker(file, envFile, projectName string) (Project, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return Project{}, err
+	}
+	args := []string{"compose", "-f", file}
+	if projectName != "" {
+		args = append(args, "-p", projectName)
+	}
+	if _, err := os.Stat(envFile); err == nil {
+		args = append(args, "--env-file", envFile)
+	}
+	args = append(args, "config", "--format", "json")
+
+	cmd := exec.Command("docker", args...)
+	cmd.Dir = filepath.Dir(file)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return Project{}, fmt.Errorf("docker compose config: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	var cfg dockerConfig
+	if err := json.Unmarshal(out, &cfg); err != nil {
+		return Project{}, err
+	}
+
+	p := Project{Name: cfg.Name, File: file, Source: "docker"}
+	for name, svc := range cfg.Services {
+		p.Services = append(p.Services, name)
+		for _, sp := range svc.Ports {
+			proto := strings.ToLower(sp.Protocol)
+			if proto == "" {
+				proto = "tcp"
+			}
+			lo, hi, err := parseRange(fmt.Sprint(publishedValue(sp.Published)))
+			if err != nil || lo == 0 {
+				continue // not published on the host (ephemeral)
+			}
+			for i := 0; i <= hi-lo; i++ {
+				p.Ports = append(p.Ports, PublishedPort{
+					Service:       name,
+					HostIP:        sp.HostIP,
+					HostPort:      lo + i,
+					ContainerPort: sp.Target + i,
+					Proto:         proto,
+				})
+			}
+		}
+	}
+	p.finish()
+	return p, nil
+}
+
+func publishedValue(v any) any {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return int(x)
+	default:
+		return x
+	}
+}
+
+func (p *Project) finish() {
+	sort.Strings(p.Services)
+	sort.SliceStable(p.Ports, func(i, j int) bool {
+		if p.Ports[i].HostPort == p.Ports[j].HostPort {
+			return p.Ports[i].Service < p.Ports[j].Service
+		}
+		return p.Ports[i].HostPort < p.Ports[j].HostPort
+	})
+}
+
+// NormalizeProjectName mirrors compose's default project name rules:
+// lowercase, only [a-z0-9_-], must start with a letter or digit.
+func NormalizeProjectName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimLeft(b.String(), "_-")
+}
+
+func parseRange(s string) (int, int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, 0, nil
+	}
+	if i := strings.Index(s, "-"); i > 0 {
+		lo, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return 0, 0, err
+		}
+		hi, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return 0, 0, err
+		}
+		if hi < lo {
+			return 0, 0, fmt.Errorf("invalid port range %q", s)
+		}
+		return lo, hi, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, 0, err
+	}
+	return n, n, nil
+}
@@ -0,0 +1,409 @@
+package compose
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Parse extracts the project name, services and published ports from a compose file.
+// It understands the subset of YAML that compose files use for ports (block and flow
+// sequences, short and long port syntax); use `docker compose config` for anything fancier.
+func Parse(b []byte, lookup func(string) (string, bool)) (Project, error) {
+	var p Project
+	lines := splitYAMLLines(b)
+
+	servicesIndent := -1 // indent of the "services:" key
+	svcIndent := -1      // indent of service names
+	svc := ""
+	svcKeyIndent := -1 // indent of keys inside the current service
+	portsIndent := -1  // indent of "ports:" inside the current service
+	var long map[string]string
+	longIndent := -1
+
+	flushLong := func(lineNo int) error {
+		if long == nil {
+			return nil
+		}
+		pp, err := longPort(svc, long)
+		long = nil
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		p.Ports = append(p.Ports, pp...)
+		return nil
+	}
+
+	for _, ln := range lines {
+		text, err := Interpolate(ln.text, lookup)
+		if err != nil {
+			return Project{}, fmt.Errorf("line %d: %w", ln.no, err)
+		}
+		indent := ln.indent
+
+		if long != nil && (indent <= longIndent || !strings.Contains(text, ":") || strings.HasPrefix(text, "- ")) {
+			if err := flushLong(ln.no); err != nil {
+				return Project{}, err
+			}
+		}
+
+		if indent == 0 {
+			servicesIndent, svcIndent, svc, svcKeyIndent, portsIndent = -1, -1, "", -1, -1
+			k, v := splitKey(text)
+			switch k {
+			case "name":
+				p.Name = unquote(v)
+			case "services":
+				servicesIndent = 0
+			}
+			continue
+		}
+		if servicesIndent < 0 {
+			continue
+		}
+
+		if svcIndent < 0 || indent == svcIndent {
+			k, _ := splitKey(text)
+			if k == "" {
+				continue
+			}
+			svcIndent = indent
+			svc = unquote(k)
+			svcKeyIndent, portsIndent = -1, -1
+			p.Services = append(p.Services, svc)
+			continue
+		}
+		if indent < svcIndent || svc == "" {
+			continue
+		}
+
+		seqItem := strings.HasPrefix(text, "- ") || text == "-"
+		if svcKeyIndent < 0 || indent < svcKeyIndent || (indent == svcKeyIndent && !(seqItem && portsIndent == indent)) {
+			svcKeyIndent = indent
+			portsIndent = -1
+			k, v := splitKey(text)
+			if k != "ports" {
+				continue
+			}
+			portsIndent = indent
+			if strings.HasPrefix(v, "[") {
+				for _, item := range splitFlow(v) {
+					pp, err := shortPort(svc, item)
+					if err != nil {
+						return Project{}, fmt.Errorf("line %d: %w", ln.no, err)
+					}
+					p.Ports = append(p.Ports, pp...)
+				}
+				portsIndent = -1
+			}
+			continue
+		}
+		if portsIndent < 0 {
+			continue
+		}
+
+		// inside ports:
+		if seqItem {
+			item := strings.TrimSpace(strings.TrimPrefix(text, "-"))
+			if k, v := splitKey(item); k != "" && !looksLikePort(item) {
+				long = map[string]string{k: unquote(v)}
+				longIndent = indent
+				continue
+			}
+			pp, err := shortPort(svc, item)
+			if err != nil {
+				return Project{}, fmt.Errorf("line %d: %w", ln.no, err)
+			}
+			p.Ports = append(p.Ports, pp...)
+			continue
+		}
+		if long != nil {
+			k, v := splitKey(text)
+			long[k] = unquote(v)
+		}
+	}
+	if err := flushLong(len(lines)); err != nil {
+		return Project{}, err
+	}
+	if len(p.Services) == 0 {
+		return Project{}, fmt.Errorf("no services found")
+	}
+	p.finish()
+	return p, nil
+}
+
+// shortPort parses "[HOST_IP:][HOST:]CONTAINER[/PROTO]". Entries without a host port are
+// not published on a fixed host port and are skipped.
+func shortPort(svc, s string) ([]PublishedPort, error) {
+	s = unquote(strings.TrimSpace(s))
+	if s == "" {
+		return nil, nil
+	}
+	proto := "tcp"
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		proto = strings.ToLower(s[i+1:])
+		s = s[:i]
+	}
+
+	hostIP := ""
+	if strings.HasPrefix(s, "[") {
+		i := strings.Index(s, "]:")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid port %q", s)
+		}
+		hostIP = s[1:i]
+		s = s[i+2:]
+	}
+	parts := strings.Split(s, ":")
+	var host, ctr string
+	switch len(parts) {
+	case 1:
+		return nil, nil
+	case 2:
+		host, ctr = parts[0], parts[1]
+	case 3:
+		hostIP, host, ctr = parts[0], parts[1], parts[2]
+	default:
+		return nil, fmt.Errorf("invalid port %q", s)
+	}
+	return expand(svc, hostIP, host, ctr, proto)
+}
+
+func longPort(svc string, m map[string]string) ([]PublishedPort, error) {
+	proto := strings.ToLower(m["protocol"])
+	if proto == "" {
+		proto = "tcp"
+	}
+	return expand(svc, m["host_ip"], m["published"], m["target"], proto)
+}
+
+func expand(svc, hostIP, host, ctr, proto string) ([]PublishedPort, error) {
+	hlo, hhi, err := parseRange(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid published port %q", host)
+	}
+	if hlo == 0 {
+		return nil, nil
+	}
+	clo, chi, err := parseRange(ctr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid container port %q", ctr)
+	}
+	if chi-clo != hhi-hlo && clo != chi {
+		return nil, fmt.Errorf("port ranges %s and %s differ in size", host, ctr)
+	}
+	var out []PublishedPort
+	for i := 0; i <= hhi-hlo; i++ {
+		cp := clo
+		if chi != clo {
+			cp = clo + i
+		}
+		out = append(out, PublishedPort{Service: svc, HostIP: hostIP, HostPort: hlo + i, ContainerPort: cp, Proto: proto})
+	}
+	return out, nil
+}
+
+// Interpolate expands $VAR, ${VAR}, ${VAR:-default}, ${VAR-default}, ${VAR:?err}, ${VAR?err}
+// and $$ the same way compose does.
+func Interpolate(s string, lookup func(string) (string, bool)) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '$' || i+1 >= len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		next := s[i+1]
+		switch {
+		case next == '$':
+			b.WriteByte('$')
+			i++
+		case next == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable in %q", s)
+			}
+			v, err := expandBraced(s[i+2:i+end], lookup)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			i += end
+		case isNameByte(next, true):
+			j := i + 1
+			for j < len(s) && isNameByte(s[j], j == i+1) {
+				j++
+			}
+			v, _ := lookup(s[i+1 : j])
+			b.WriteString(v)
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+func expandBraced(expr string, lookup func(string) (string, bool)) (string, error) {
+	for _, op := range []string{":-", ":?", "-", "?"} {
+		i := strings.Index(expr, op)
+		if i <= 0 {
+			continue
+		}
+		name, arg := expr[:i], expr[i+len(op):]
+		v, ok := lookup(name)
+		empty := !ok || (strings.HasPrefix(op, ":") && v == "")
+		switch op {
+		case ":-", "-":
+			if empty {
+				return arg, nil
+			}
+			return v, nil
+		default:
+			if empty {
+				if arg == "" {
+					arg = "required variable is missing a value"
+				}
+				return "", fmt.Errorf("%s: %s", name, arg)
+			}
+			return v, nil
+		}
+	}
+	v, _ := lookup(expr)
+	return v, nil
+}
+
+func isNameByte(c byte, first bool) bool {
+	if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+		return true
+	}
+	return !first && c >= '0' && c <= '9'
+}
+
+// LoadDotEnv reads KEY=VALUE pairs from a .env file.
+func LoadDotEnv(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]string{}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		} else if i := strings.Index(v, " #"); i >= 0 {
+			v = strings.TrimSpace(v[:i])
+		}
+		out[strings.TrimSpace(k)] = v
+	}
+	return out, sc.Err()
+}
+
+type yamlLine struct {
+	no     int
+	indent int
+	text   string
+}
+
+func splitYAMLLines(b []byte) []yamlLine {
+	var out []yamlLine
+	for i, raw := range strings.Split(string(b), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		line := stripComment(raw)
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		out = append(out, yamlLine{no: i + 1, indent: len(line) - len(strings.TrimLeft(line, " \t")), text: trimmed})
+	}
+	return out
+}
+
+func stripComment(s string) string {
+	inS, inD := false, false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			if !inD {
+				inS = !inS
+			}
+		case '"':
+			if !inS {
+				inD = !inD
+			}
+		case '#':
+			if !inS && !inD && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t') {
+				return s[:i]
+			}
+		}
+	}
+	return s
+}
+
+// splitKey splits "key: value". Returns an empty key if the line is not a mapping entry.
+func splitKey(s string) (string, string) {
+	if strings.HasPrefix(s, "- ") || strings.HasPrefix(s, "\"") && !strings.Contains(s, "\":") {
+		return "", ""
+	}
+	i := strings.Index(s, ": ")
+	if i < 0 {
+		if strings.HasSuffix(s, ":") {
+			return strings.TrimSpace(s[:len(s)-1]), ""
+		}
+		return "", ""
+	}
+	return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+2:])
+}
+
+// looksLikePort reports whether a sequence item is a short-syntax port rather than a
+// long-syntax mapping ("8080:80" vs "target: 80").
+func looksLikePort(s string) bool {
+	s = unquote(s)
+	if s == "" {
+		return false
+	}
+	c := s[0]
+	return (c >= '0' && c <= '9') || c == '['
+}
+
+func splitFlow(s string) []string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "[")
+	s = strings.TrimSuffix(s, "]")
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		if s[0] == '"' {
+			if u, err := strconv.Unquote(s); err == nil {
+				return u
+			}
+		}
+		return s[1 : len(s)-1]
+	}
+	return s
+}
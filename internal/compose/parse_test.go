@@ -0,0 +1,77 @@
+package compose
+
+import "testing"
+
+func TestParsePorts(t *testing.T) {
+	src := []byte(`name: demo
+services:
+  web:
+    image: nginx
+    ports:
+      - "${WEB_PORT:-8080}:80"   # comment
+      - 127.0.0.1:9000-9001:9000-9001/udp
+      - "3000"
+  db:
+    ports:
+    - target: 5432
+      published: 15432
+    environment:
+      - FOO=bar
+`)
+	env := map[string]string{}
+	p, err := Parse(src, func(k string) (string, bool) { v, ok := env[k]; return v, ok })
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if p.Name != "demo" || len(p.Services) != 2 {
+		t.Fatalf("unexpected project: %#v", p)
+	}
+	want := []PublishedPort{
+		{Service: "web", HostPort: 8080, ContainerPort: 80, Proto: "tcp"},
+		{Service: "web", HostIP: "127.0.0.1", HostPort: 9000, ContainerPort: 9000, Proto: "udp"},
+		{Service: "web", HostIP: "127.0.0.1", HostPort: 9001, ContainerPort: 9001, Proto: "udp"},
+		{Service: "db", HostPort: 15432, ContainerPort: 5432, Proto: "tcp"},
+	}
+	if len(p.Ports) != len(want) {
+		t.Fatalf("expected %d ports, got %#v", len(want), p.Ports)
+	}
+	got := map[PublishedPort]bool{}
+	for _, pp := range p.Ports {
+		got[pp] = true
+	}
+	for _, w := range want {
+		if !got[w] {
+			t.Fatalf("missing %#v in %#v", w, p.Ports)
+		}
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	lookup := func(k string) (string, bool) {
+		if k == "SET" {
+			return "1", true
+		}
+		if k == "EMPTY" {
+			return "", true
+		}
+		return "", false
+	}
+	cases := map[string]string{
+		"$SET":        "1",
+		"${SET}":      "1",
+		"${UNSET:-7}": "7",
+		"${EMPTY:-7}": "7",
+		"${EMPTY-7}":  "",
+		"$$SET":       "$SET",
+		"a-${SET}-b":  "a-1-b",
+	}
+	for in, want := range cases {
+		got, err := Interpolate(in, lookup)
+		if err != nil || got != want {
+			t.Fatalf("Interpolate(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := Interpolate("${UNSET:?boom}", lookup); err == nil {
+		t.Fatalf("expected error for required variable")
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the user configuration read from ~/.portik/config.yaml.
+//
+//	history:
+//	  max_entries_per_port: 500
+//	  max_age: 90d
+//	audit:
+//	  path: /srv/ops/portik-audit.jsonl
+//	  syslog: true
+type Config struct {
+	History History `yaml:"history" json:"history"`
+	Audit   Audit   `yaml:"audit" json:"audit"`
+}
+
+// Audit locates the action audit log. By default it is /var/log/portik/audit.jsonl
+// when that is writable and ~/.portik/audit.jsonl otherwise; a shared path lets
+// every user of a host write to the same log. Syslog also sends each entry to the
+// local syslog (and so journald), where users cannot rewrite it.
+type Audit struct {
+	Path   string `yaml:"path" json:"path,omitempty"`
+	Syslog bool   `yaml:"syslog" json:"syslog,omitempty"`
+}
+
+type History struct {
+	MaxEntriesPerPort int    `yaml:"max_entries_per_port" json:"max_entries_per_port"`
+	MaxAge            string `yaml:"max_age" json:"max_age,omitempty"`
+}
+
+const DefaultMaxEntriesPerPort = 200
+
+func Default() Config {
+	return Config{History: History{MaxEntriesPerPort: DefaultMaxEntriesPerPort}}
+}
+
+// Path returns the config file location ($PORTIK_CONFIG or ~/.portik/config.yaml).
+func Path() (string, error) {
+	if p := strings.TrimSpace(os.Getenv("PORTIK_CONFIG")); p != "" {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".portik", "config.yaml"), nil
+}
+
+// Load reads the config file; a missing file yields the defaults.
+func Load() (Config, error) {
+	cfg := Default()
+	p, err := Path()
+	if err != nil {
+		return cfg, err
+	}
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return Default(), fmt.Errorf("%s: %w", p, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Default(), fmt.Errorf("%s: %w", p, err)
+	}
+	return cfg, nil
+}
+
+func (c Config) Validate() error {
+	if c.History.MaxEntriesPerPort < 0 {
+		return errors.New("history.max_entries_per_port must be >= 0 (0 = unlimited)")
+	}
+	if c.History.MaxAge != "" {
+		if _, err := ParseDuration(c.History.MaxAge); err != nil {
+			return fmt.Errorf("history.max_age: %w", err)
+		}
+	}
+	return nil
+}
+
+// MaxAgeDuration returns the parsed history.max_age (0 = keep forever).
+func (h History) MaxAgeDuration() time.Duration {
+	d, _ := ParseDuration(h.MaxAge)
+	return d
+}
+
+// ParseDuration is time.ParseDuration plus day ("7d") and week ("2w") suffixes.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	unit := time.Duration(0)
+	switch s[len(s)-1] {
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	}
+	if unit > 0 && len(s) > 1 {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * unit, nil
+	}
+	return time.ParseDuration(s)
+}
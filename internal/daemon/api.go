@@ -0,0 +1,357 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/history"
+	"github.com/pratik-anurag/portik/internal/model"
+)
+
+func SockPath() (string, error) { return path("daemon.sock") }
+
+// maxChanges bounds the recent-changes buffer served by /v1/changes.
+const maxChanges = 256
+
+// Status describes the running daemon.
+type Status struct {
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	Targets []Target  `json:"targets"`
+}
+
+// Target returns what the daemon monitors for port/proto.
+func (st Status) Target(port int, proto string) (Target, bool) {
+	for _, t := range st.Targets {
+		if t.Port == port && t.Proto == proto {
+			return t, true
+		}
+	}
+	return Target{}, false
+}
+
+// CachedReport is the latest report for a monitored port.
+type CachedReport struct {
+	Report  model.Report `json:"report"`
+	Updated time.Time    `json:"updated"`
+}
+
+// Age is how long ago the daemon inspected the port.
+func (cr CachedReport) Age() time.Duration { return time.Since(cr.Updated) }
+
+// Change is an ownership change seen by the daemon (the first poll of a port counts too).
+type Change struct {
+	Seq    int64        `json:"seq"`
+	At     time.Time    `json:"at"`
+	Port   int          `json:"port"`
+	Proto  string       `json:"proto"`
+	From   string       `json:"from,omitempty"` // previous owner label
+	To     string       `json:"to"`
+	Report model.Report `json:"report"`
+}
+
+// Server caches what the daemon polls and serves it as JSON over a Unix socket:
+//
+//	GET /v1/status
+//	GET /v1/reports?port=N&port=M&proto=tcp&max_age=2s
+//	GET /v1/changes?port=N&since=RFC3339
+//	GET /v1/history?port=N&since=RFC3339&detect=1
+//	GET /v1/feed?port=N   (NDJSON stream of changes)
+type Server struct {
+	// Refresh, when set, re-inspects a monitored port whose cached report is older
+	// than the max_age a client asked for. It must call Update.
+	Refresh func(t Target)
+
+	mu      sync.Mutex
+	status  Status
+	reports map[string]CachedReport // by port/proto
+	sigs    map[string]string
+	owners  map[string]string
+	changes []Change
+	seq     int64
+	subs    map[chan Change]struct{}
+}
+
+func NewServer() *Server {
+	return &Server{
+		status:  Status{PID: os.Getpid(), Started: time.Now()},
+		reports: map[string]CachedReport{},
+		sigs:    map[string]string{},
+		owners:  map[string]string{},
+		subs:    map[chan Change]struct{}{},
+	}
+}
+
+// Configure sets what the daemon monitors, dropping cached state for targets it no
+// longer watches (or now watches differently).
+func (s *Server) Configure(targets []Target) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keep := map[string]Target{}
+	for _, t := range targets {
+		keep[t.Key()] = t
+	}
+	for _, old := range s.status.Targets {
+		if t, ok := keep[old.Key()]; !ok || t.Docker != old.Docker {
+			delete(s.reports, old.Key())
+			delete(s.sigs, old.Key())
+			delete(s.owners, old.Key())
+		}
+	}
+	s.status.Targets = slices.Clone(targets)
+}
+
+// Update caches rep and reports whether its owner signature changed; changes are
+// kept for /v1/changes and pushed to feed subscribers.
+func (s *Server) Update(rep model.Report) (Change, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := Target{Port: rep.Port, Proto: rep.Proto}.Key()
+	s.reports[k] = CachedReport{Report: rep, Updated: time.Now()}
+	sig := rep.Signature()
+	prev, seen := s.sigs[k]
+	if seen && prev == sig {
+		return Change{}, false
+	}
+	s.sigs[k] = sig
+
+	s.seq++
+	ch := Change{Seq: s.seq, At: rep.Generated, Port: rep.Port, Proto: rep.Proto, From: s.owners[k], To: history.OwnerLabel(history.EventFromReport(rep)), Report: rep}
+	s.owners[k] = ch.To
+	s.changes = append(s.changes, ch)
+	if len(s.changes) > maxChanges {
+		s.changes = slices.Delete(s.changes, 0, len(s.changes)-maxChanges)
+	}
+	for sub := range s.subs {
+		select {
+		case sub <- ch:
+		default: // slow reader; it will see the change in /v1/changes
+		}
+	}
+	return ch, true
+}
+
+// Listen opens the API socket at path, replacing a stale socket but refusing to
+// steal one another daemon is serving.
+func Listen(path string) (net.Listener, error) {
+	if _, err := os.Stat(path); err == nil {
+		if c, err := net.DialTimeout("unix", path, 200*time.Millisecond); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("another daemon is serving %s", path)
+		}
+		_ = os.Remove(path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	_ = os.Chmod(path, 0o600)
+	return l, nil
+}
+
+// Serve answers API requests on l until it is closed.
+func (s *Server) Serve(l net.Listener) error {
+	srv := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	err := srv.Serve(l)
+	if errors.Is(err, net.ErrClosed) || errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", s.handleStatus)
+	mux.HandleFunc("GET /v1/reports", s.handleReports)
+	mux.HandleFunc("GET /v1/changes", s.handleChanges)
+	mux.HandleFunc("GET /v1/history", s.handleHistory)
+	mux.HandleFunc("GET /v1/feed", s.handleFeed)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// queryPorts parses the repeated ?port= parameter.
+func queryPorts(r *http.Request) ([]int, error) {
+	var out []int
+	for _, v := range r.URL.Query()["port"] {
+		p, err := strconv.Atoi(v)
+		if err != nil || p <= 0 || p > 65535 {
+			return nil, fmt.Errorf("invalid port %q", v)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func querySince(r *http.Request) (time.Time, error) {
+	v := r.URL.Query().Get("since")
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid since %q (RFC3339)", v)
+	}
+	return t, nil
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	st := s.status
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, st)
+}
+
+func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
+	ports, err := queryPorts(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	proto := r.URL.Query().Get("proto")
+	var maxAge time.Duration
+	if v := r.URL.Query().Get("max_age"); v != "" {
+		if maxAge, err = time.ParseDuration(v); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid max_age %q", v))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	var want, stale []Target
+	for _, t := range s.status.Targets {
+		if len(ports) > 0 && !slices.Contains(ports, t.Port) || proto != "" && t.Proto != proto {
+			continue
+		}
+		want = append(want, t)
+		cr, ok := s.reports[t.Key()]
+		if !ok || maxAge > 0 && time.Since(cr.Updated) > maxAge {
+			stale = append(stale, t)
+		}
+	}
+	s.mu.Unlock()
+
+	if s.Refresh != nil {
+		for _, t := range stale {
+			s.Refresh(t)
+		}
+	}
+
+	s.mu.Lock()
+	out := []CachedReport{}
+	for _, t := range want {
+		if cr, ok := s.reports[t.Key()]; ok {
+			out = append(out, cr)
+		}
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleChanges(w http.ResponseWriter, r *http.Request) {
+	ports, err := queryPorts(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	since, err := querySince(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.mu.Lock()
+	out := []Change{}
+	for _, c := range s.changes {
+		if c.At.Before(since) || len(ports) > 0 && !slices.Contains(ports, c.Port) {
+			continue
+		}
+		out = append(out, c)
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	ports, err := queryPorts(r)
+	if err != nil || len(ports) != 1 {
+		writeError(w, http.StatusBadRequest, errors.New("exactly one port is required"))
+		return
+	}
+	since, err := querySince(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if since.IsZero() {
+		since = time.Now().Add(-7 * 24 * time.Hour)
+	}
+	detect, _ := strconv.ParseBool(r.URL.Query().Get("detect"))
+	st, err := history.LoadPorts(ports[0])
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st.ViewPortSince(ports[0], since, detect))
+}
+
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	ports, err := queryPorts(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	fl, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+	sub := make(chan Change, 32)
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subs, sub)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	fl.Flush()
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case c := <-sub:
+			if len(ports) > 0 && !slices.Contains(ports, c.Port) {
+				continue
+			}
+			if err := enc.Encode(c); err != nil {
+				return
+			}
+			fl.Flush()
+		}
+	}
+}
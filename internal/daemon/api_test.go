@@ -0,0 +1,81 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/model"
+)
+
+func TestAPI(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	srv := NewServer()
+	srv.Configure([]Target{{Port: 5432, Proto: "tcp", IntervalSec: 30}, {Port: 6379, Proto: "tcp", IntervalSec: 30}, {Port: 53, Proto: "udp", IntervalSec: 30}})
+
+	pg := model.Report{Port: 5432, Proto: "tcp", Generated: time.Now(), Listeners: []model.Listener{{LocalPort: 5432, PID: 42, ProcName: "postgres", State: "LISTEN"}}}
+	if _, ok := srv.Update(pg); !ok {
+		t.Fatal("the first sighting counts as a change")
+	}
+	if _, ok := srv.Update(pg); ok {
+		t.Fatal("an unchanged owner is not a change")
+	}
+
+	sock := filepath.Join(t.TempDir(), "d.sock")
+	l, err := Listen(sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go srv.Serve(l)
+	if _, err := Listen(sock); err == nil {
+		t.Fatal("a second daemon must not take over a live socket")
+	}
+
+	cl, err := Dial(sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cl.Serves(5432, "tcp", false) || cl.Serves(5432, "tcp", true) || cl.Serves(8080, "tcp", false) || !cl.Serves(53, "udp", false) || cl.Serves(53, "tcp", false) {
+		t.Fatalf("Serves: %+v", cl.Status)
+	}
+	cr, ok := cl.Report(5432, "tcp", false, 0)
+	if !ok || cr.Report.Listeners[0].PID != 42 {
+		t.Fatalf("Report = %+v, %v", cr, ok)
+	}
+
+	refreshed := make(chan int, 1)
+	srv.Refresh = func(t Target) {
+		refreshed <- t.Port
+		srv.Update(model.Report{Port: t.Port, Proto: t.Proto, Generated: time.Now()})
+	}
+	m, err := cl.Reports([]int{6379}, "tcp", time.Second)
+	if err != nil || len(m) != 1 || <-refreshed != 6379 {
+		t.Fatalf("missing report must be refreshed: %+v, %v", m, err)
+	}
+
+	got := make(chan Change, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go cl.Feed(ctx, []int{5432}, func(c Change) error {
+		got <- c
+		return errors.New("done")
+	})
+	time.Sleep(100 * time.Millisecond) // let the feed subscribe
+	srv.Update(model.Report{Port: 5432, Proto: "tcp", Generated: time.Now()})
+	select {
+	case c := <-got:
+		if c.From != "postgres" || c.To != "free" {
+			t.Fatalf("feed change = %s -> %s", c.From, c.To)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change on the feed")
+	}
+
+	chs, err := cl.Changes([]int{5432}, time.Time{})
+	if err != nil || len(chs) != 2 {
+		t.Fatalf("changes = %+v, %v", chs, err)
+	}
+}
@@ -0,0 +1,194 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/history"
+)
+
+// Client talks to a running daemon's API socket.
+type Client struct {
+	Status Status
+
+	hc     *http.Client
+	stream *http.Client
+}
+
+// Connect returns a client for the daemon serving ~/.portik/daemon.sock, or an error
+// when none is running. Setting PORTIK_NO_DAEMON=1 disables it.
+func Connect() (*Client, error) {
+	if os.Getenv("PORTIK_NO_DAEMON") != "" {
+		return nil, errors.New("daemon disabled by PORTIK_NO_DAEMON")
+	}
+	p, err := SockPath()
+	if err != nil {
+		return nil, err
+	}
+	return Dial(p)
+}
+
+// Dial connects to the API socket at path and fetches the daemon's status.
+func Dial(path string) (*Client, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	tr := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}
+	c := &Client{
+		hc:     &http.Client{Transport: tr, Timeout: 10 * time.Second},
+		stream: &http.Client{Transport: tr},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := c.get(ctx, c.hc, "/v1/status", nil, &c.Status); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Serves reports whether the daemon's cache can stand in for a live inspection of
+// port with these options.
+func (c *Client) Serves(port int, proto string, docker bool) bool {
+	t, ok := c.Status.Target(port, proto)
+	return ok && (t.Docker || !docker)
+}
+
+func portsQuery(ports []int) url.Values {
+	q := url.Values{}
+	for _, p := range ports {
+		q.Add("port", strconv.Itoa(p))
+	}
+	return q
+}
+
+func (c *Client) get(ctx context.Context, hc *http.Client, path string, q url.Values, out any) error {
+	resp, err := c.do(ctx, hc, path, q)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *Client) do(ctx context.Context, hc *http.Client, path string, q url.Values) (*http.Response, error) {
+	u := "http://portik" + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		var e struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return nil, fmt.Errorf("daemon: %s: %s", resp.Status, e.Error)
+	}
+	return resp, nil
+}
+
+// Reports returns the cached proto reports for ports (all monitored ports when empty).
+// Reports older than maxAge are refreshed by the daemon first (0 = any age).
+func (c *Client) Reports(ports []int, proto string, maxAge time.Duration) (map[int]CachedReport, error) {
+	q := portsQuery(ports)
+	q.Set("proto", proto)
+	if maxAge > 0 {
+		q.Set("max_age", maxAge.String())
+	}
+	var list []CachedReport
+	if err := c.get(context.Background(), c.hc, "/v1/reports", q, &list); err != nil {
+		return nil, err
+	}
+	out := make(map[int]CachedReport, len(list))
+	for _, cr := range list {
+		out[cr.Report.Port] = cr
+	}
+	return out, nil
+}
+
+// Report returns the cached report for port, if the daemon serves it and has one
+// at most maxAge old (0 = any age), refreshing it first when it is older.
+func (c *Client) Report(port int, proto string, docker bool, maxAge time.Duration) (CachedReport, bool) {
+	if !c.Serves(port, proto, docker) {
+		return CachedReport{}, false
+	}
+	m, err := c.Reports([]int{port}, proto, maxAge)
+	if err != nil {
+		return CachedReport{}, false
+	}
+	cr, ok := m[port]
+	if ok && maxAge > 0 && cr.Age() > maxAge {
+		return CachedReport{}, false // the refresh failed
+	}
+	return cr, ok
+}
+
+// Changes returns the recent ownership changes since t for ports (all when empty).
+func (c *Client) Changes(ports []int, since time.Time) ([]Change, error) {
+	q := portsQuery(ports)
+	if !since.IsZero() {
+		q.Set("since", since.Format(time.RFC3339Nano))
+	}
+	var out []Change
+	err := c.get(context.Background(), c.hc, "/v1/changes", q, &out)
+	return out, err
+}
+
+// History returns the daemon's history view for port.
+func (c *Client) History(port int, since time.Time, detect bool) (history.View, error) {
+	q := portsQuery([]int{port})
+	q.Set("since", since.Format(time.RFC3339Nano))
+	q.Set("detect", strconv.FormatBool(detect))
+	var v history.View
+	err := c.get(context.Background(), c.hc, "/v1/history", q, &v)
+	return v, err
+}
+
+// Feed streams ownership changes for ports (all when empty) to fn until ctx is done,
+// the daemon goes away, or fn returns an error.
+func (c *Client) Feed(ctx context.Context, ports []int, fn func(Change) error) error {
+	resp, err := c.do(ctx, c.stream, "/v1/feed", portsQuery(ports))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		var ch Change
+		if err := json.Unmarshal(sc.Bytes(), &ch); err != nil {
+			return err
+		}
+		if err := fn(ch); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return errors.New("daemon: feed closed")
+}
@@ -0,0 +1,313 @@
+package daemon
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pratik-anurag/portik/internal/hooks"
+	"github.com/pratik-anurag/portik/internal/model"
+	"github.com/pratik-anurag/portik/internal/ports"
+)
+
+func ConfigPath() (string, error) { return path("daemon.yaml") }
+
+// File is the declarative daemon configuration (~/.portik/daemon.yaml). Top-level
+// settings are defaults for every entry.
+//
+//	interval: 30s
+//	hooks:
+//	  webhook: https://chat.example/hook
+//	ports:
+//	  - ports: 5432
+//	    expect: {name: postgres, user: postgres}
+//	  - ports: 3000-3010
+//	    interval: 5s
+//	    docker: true
+//	    thresholds: {connections: 200, time_wait: 50}
+//	    hooks: {on_change: "notify-send portik \"$PORTIK_PORT changed\""}
+type File struct {
+	Interval string       `yaml:"interval"`
+	Proto    string       `yaml:"proto"`
+	Docker   bool         `yaml:"docker"`
+	Alerts   bool         `yaml:"alerts"` // anomaly detection (flapping, crash loops, ...)
+	Hooks    hooks.Config `yaml:"hooks"`
+	Ports    []Entry      `yaml:"ports"`
+}
+
+// Entry is one item of `ports:`; unset fields inherit the top-level defaults.
+type Entry struct {
+	Ports      string       `yaml:"ports"` // 5432 or "3000-3010,8080"
+	Proto      string       `yaml:"proto"`
+	Interval   string       `yaml:"interval"`
+	Docker     *bool        `yaml:"docker"`
+	Alerts     *bool        `yaml:"alerts"`
+	Expect     Expect       `yaml:"expect"`
+	Thresholds Thresholds   `yaml:"thresholds"`
+	Hooks      hooks.Config `yaml:"hooks"`
+}
+
+// Expect describes the owner a port should have; empty fields are not checked.
+type Expect struct {
+	Name      string `yaml:"name" json:"name,omitempty"`           // process name
+	User      string `yaml:"user" json:"user,omitempty"`           // process user
+	Container string `yaml:"container" json:"container,omitempty"` // container or compose service
+}
+
+func (e Expect) set() bool { return e != Expect{} }
+
+func (e Expect) String() string {
+	var parts []string
+	for _, kv := range [][2]string{{"name", e.Name}, {"user", e.User}, {"container", e.Container}} {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+"="+kv[1])
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Thresholds raise a diagnostic when a port has more sockets than allowed (0 = off).
+type Thresholds struct {
+	Connections int `yaml:"connections" json:"connections,omitempty"` // any state but LISTEN
+	TimeWait    int `yaml:"time_wait" json:"time_wait,omitempty"`
+	CloseWait   int `yaml:"close_wait" json:"close_wait,omitempty"`
+}
+
+func (t Thresholds) String() string {
+	var parts []string
+	for _, kv := range []struct {
+		name string
+		n    int
+	}{{"connections", t.Connections}, {"time_wait", t.TimeWait}, {"close_wait", t.CloseWait}} {
+		if kv.n > 0 {
+			parts = append(parts, fmt.Sprintf("%s>%d", kv.name, kv.n))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Target is one monitored port/proto as reported by the API.
+type Target struct {
+	Port        int     `json:"port"`
+	Proto       string  `json:"proto"`
+	Docker      bool    `json:"docker,omitempty"`
+	IntervalSec float64 `json:"interval_sec"`
+}
+
+func (t Target) Interval() time.Duration { return time.Duration(t.IntervalSec * float64(time.Second)) }
+
+func (t Target) Key() string { return fmt.Sprintf("%d/%s", t.Port, t.Proto) }
+
+// Policy is everything that applies to one monitored port.
+type Policy struct {
+	Target
+	Entry      int    // index in File.Ports
+	Spec       string // the entry's ports spec, for summaries
+	Alerts     bool
+	Expect     Expect
+	Thresholds Thresholds
+	Hooks      hooks.Config
+	HookOpt    hooks.Options
+}
+
+// LoadFile reads and validates a daemon config file, expanding it into policies.
+func LoadFile(path string) ([]Policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	pols, err := f.Policies()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return pols, nil
+}
+
+// Policies validates f and returns one policy per port/proto, sorted by port.
+func (f File) Policies() ([]Policy, error) {
+	if len(f.Ports) == 0 {
+		return nil, errors.New("no ports configured (add entries under `ports:`)")
+	}
+	seen := map[string]int{}
+	var out []Policy
+	for i, e := range f.Ports {
+		where := fmt.Sprintf("ports[%d]", i)
+		if e.Ports == "" {
+			return nil, fmt.Errorf("%s: missing `ports`", where)
+		}
+		list, err := ports.ParseSpec(e.Ports)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", where, err)
+		}
+		proto := cmp.Or(e.Proto, f.Proto, "tcp")
+		if proto != "tcp" && proto != "udp" {
+			return nil, fmt.Errorf("%s: invalid proto %q (tcp|udp)", where, proto)
+		}
+		interval, err := time.ParseDuration(cmp.Or(e.Interval, f.Interval, "30s"))
+		if err != nil || interval < time.Second {
+			return nil, fmt.Errorf("%s: invalid interval %q (>= 1s)", where, cmp.Or(e.Interval, f.Interval))
+		}
+		t := e.Thresholds
+		if t.Connections < 0 || t.TimeWait < 0 || t.CloseWait < 0 {
+			return nil, fmt.Errorf("%s: thresholds must be >= 0", where)
+		}
+		hc := f.Hooks.Merge(e.Hooks)
+		hookOpt, err := hc.Options()
+		if err != nil {
+			return nil, fmt.Errorf("%s: hooks: %w", where, err)
+		}
+		docker, alerts := f.Docker, f.Alerts
+		if e.Docker != nil {
+			docker = *e.Docker
+		}
+		if e.Alerts != nil {
+			alerts = *e.Alerts
+		}
+
+		for _, p := range list {
+			k := fmt.Sprintf("%d/%s", p, proto)
+			if prev, dup := seen[k]; dup {
+				return nil, fmt.Errorf("%s: %s is already configured in ports[%d]", where, k, prev)
+			}
+			seen[k] = i
+			out = append(out, Policy{
+				Target:     Target{Port: p, Proto: proto, Docker: docker, IntervalSec: interval.Seconds()},
+				Entry:      i,
+				Spec:       e.Ports,
+				Alerts:     alerts,
+				Expect:     e.Expect,
+				Thresholds: t,
+				Hooks:      hc,
+				HookOpt:    hookOpt,
+			})
+		}
+	}
+	slices.SortStableFunc(out, func(a, b Policy) int {
+		if a.Port != b.Port {
+			return a.Port - b.Port
+		}
+		return strings.Compare(a.Proto, b.Proto)
+	})
+	return out, nil
+}
+
+// Summary describes pols, one line per config entry.
+func Summary(pols []Policy) []string {
+	var lines []string
+	done := map[int]bool{}
+	for _, p := range pols {
+		if done[p.Entry] {
+			continue
+		}
+		done[p.Entry] = true
+		var b strings.Builder
+		fmt.Fprintf(&b, "%-16s every %s", p.Spec+"/"+p.Proto, p.Interval())
+		if p.Docker {
+			b.WriteString(", docker")
+		}
+		if p.Alerts {
+			b.WriteString(", alerts")
+		}
+		if p.Expect.set() {
+			fmt.Fprintf(&b, ", expect %s", p.Expect)
+		}
+		if s := p.Thresholds.String(); s != "" {
+			fmt.Fprintf(&b, ", thresholds %s", s)
+		}
+		var hk []string
+		if p.Hooks.OnChange != "" {
+			hk = append(hk, "on-change")
+		}
+		if p.Hooks.Webhook != "" {
+			hk = append(hk, "webhook")
+		}
+		if len(hk) > 0 {
+			fmt.Fprintf(&b, ", hooks %s", strings.Join(hk, "+"))
+		}
+		lines = append(lines, b.String())
+	}
+	return lines
+}
+
+// Diagnostic kinds raised by policies.
+const (
+	DiagUnexpectedOwner = "unexpected-owner"
+	DiagConnections     = "connections-threshold"
+	DiagTimeWait        = "time-wait-threshold"
+	DiagCloseWait       = "close-wait-threshold"
+)
+
+// IsPolicyDiagnostic reports whether kind was raised by Check.
+func IsPolicyDiagnostic(kind string) bool {
+	switch kind {
+	case DiagUnexpectedOwner, DiagConnections, DiagTimeWait, DiagCloseWait:
+		return true
+	}
+	return false
+}
+
+// Check returns the diagnostics p raises for rep: an owner other than the expected
+// one, and socket counts above the thresholds.
+func (p Policy) Check(rep model.Report) []model.Diagnostic {
+	var out []model.Diagnostic
+	if l, ok := rep.PrimaryListener(); ok && p.Expect.set() {
+		var bad []string
+		if p.Expect.Name != "" && !strings.EqualFold(l.ProcName, p.Expect.Name) {
+			bad = append(bad, fmt.Sprintf("process %q", l.ProcName))
+		}
+		if p.Expect.User != "" && !strings.EqualFold(l.User, p.Expect.User) {
+			bad = append(bad, fmt.Sprintf("user %q", l.User))
+		}
+		if c := p.Expect.Container; c != "" && !strings.EqualFold(rep.Docker.ContainerName, c) && !strings.EqualFold(rep.Docker.ComposeService, c) {
+			bad = append(bad, fmt.Sprintf("container %q", rep.Docker.ContainerName))
+		}
+		if len(bad) > 0 {
+			out = append(out, model.Diagnostic{
+				Kind:     DiagUnexpectedOwner,
+				Severity: "warn",
+				Summary:  fmt.Sprintf("Unexpected owner (expected %s)", p.Expect),
+				Details:  fmt.Sprintf("pid %d: %s", l.PID, strings.Join(bad, ", ")),
+				Action:   fmt.Sprintf("Check with: portik who %d --proto %s", rep.Port, rep.Proto),
+			})
+		}
+	}
+
+	byState := map[string]int{}
+	total := 0
+	for _, c := range rep.Connections {
+		st := strings.ToUpper(c.State)
+		if st == "LISTEN" {
+			continue
+		}
+		byState[st]++
+		total++
+	}
+	over := func(kind, what string, n, max int) {
+		if max > 0 && n > max {
+			out = append(out, model.Diagnostic{
+				Kind:     kind,
+				Severity: "warn",
+				Summary:  fmt.Sprintf("%d %s on port %d (threshold %d)", n, what, rep.Port, max),
+				Action:   fmt.Sprintf("Inspect with: portik conn %d", rep.Port),
+			})
+		}
+	}
+	over(DiagConnections, "connections", total, p.Thresholds.Connections)
+	over(DiagTimeWait, "TIME_WAIT sockets", byState["TIME_WAIT"], p.Thresholds.TimeWait)
+	over(DiagCloseWait, "CLOSE_WAIT sockets", byState["CLOSE_WAIT"], p.Thresholds.CloseWait)
+	return out
+}
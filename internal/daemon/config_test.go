@@ -0,0 +1,80 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/model"
+)
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(s string) string {
+		p := filepath.Join(dir, "daemon.yaml")
+		if err := os.WriteFile(p, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	pols, err := LoadFile(write(`
+interval: 10s
+hooks: {webhook: "https://hooks.example/x", severity: error}
+ports:
+  - ports: 5432
+    expect: {name: postgres}
+  - ports: 3000-3002
+    proto: udp
+    interval: 2s
+    docker: true
+    hooks: {severity: warn}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pols) != 4 || pols[0].Port != 3000 || pols[3].Port != 5432 {
+		t.Fatalf("want 3000-3002 then 5432, got %+v", pols)
+	}
+	if p := pols[0]; p.Proto != "udp" || !p.Docker || p.Interval() != 2*time.Second || p.Hooks.Severity != "warn" || p.Hooks.Webhook == "" {
+		t.Fatalf("entry overrides and inherited hooks: %+v", p)
+	}
+	if p := pols[3]; p.Proto != "tcp" || p.Docker || p.Interval() != 10*time.Second || p.Hooks.Severity != "error" || !p.HookOpt.Enabled() {
+		t.Fatalf("top-level defaults: %+v", p)
+	}
+	if s := Summary(pols); len(s) != 2 || !strings.Contains(s[1], "expect name=postgres") {
+		t.Fatalf("one summary line per entry: %q", s)
+	}
+
+	for yml, want := range map[string]string{
+		"ports: []":                                       "no ports configured",
+		"ports: [{ports: 80}, {ports: 70-80}]":            "ports[1]: 80/tcp is already configured in ports[0]",
+		"ports: [{ports: 80, interval: 10ms}]":            "invalid interval",
+		"ports: [{ports: 80, hooks: {webhook: ftp://x}}]": "hooks: invalid webhook",
+		"ports: [{ports: 80, colour: red}]":               "field colour not found",
+	} {
+		if _, err := LoadFile(write(yml)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v, want %q", yml, err, want)
+		}
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	p := Policy{Expect: Expect{Name: "postgres", User: "postgres"}, Thresholds: Thresholds{TimeWait: 1}}
+	rep := model.Report{Port: 5432, Proto: "tcp",
+		Listeners:   []model.Listener{{LocalPort: 5432, PID: 7, ProcName: "nc", User: "postgres", State: "LISTEN"}},
+		Connections: []model.Conn{{State: "LISTEN"}, {State: "TIME_WAIT"}, {State: "TIME_WAIT"}},
+	}
+	got := p.Check(rep)
+	if len(got) != 2 || got[0].Kind != DiagUnexpectedOwner || !strings.Contains(got[0].Details, `process "nc"`) ||
+		strings.Contains(got[0].Details, "user") || got[1].Kind != DiagTimeWait {
+		t.Fatalf("Check = %+v", got)
+	}
+	rep.Listeners[0].ProcName = "postgres"
+	rep.Connections = rep.Connections[:2]
+	if got := p.Check(rep); len(got) != 0 {
+		t.Fatalf("expected owner within thresholds: %+v", got)
+	}
+}
@@ -0,0 +1,128 @@
+// Package daemon holds the lifecycle plumbing of `portik daemon`: the pidfile, the
+// saved options the daemon re-reads on SIGHUP, and service unit generation.
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/pidctl"
+)
+
+// Dir returns ~/.portik, where the pidfile, log and options live.
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".portik"), nil
+}
+
+func path(name string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+func PidPath() (string, error)     { return path("daemon.pid") }
+func LogPath() (string, error)     { return path("daemon.log") }
+func OptionsPath() (string, error) { return path("daemon.json") }
+
+// Options is what `daemon start` saved: the daemon flags, re-parsed on every reload.
+type Options struct {
+	Args    []string  `json:"args"`
+	Updated time.Time `json:"updated"`
+}
+
+func LoadOptions(path string) (Options, error) {
+	var o Options
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return o, err
+	}
+	if err := json.Unmarshal(b, &o); err != nil {
+		return o, fmt.Errorf("%s: %w", path, err)
+	}
+	return o, nil
+}
+
+func SaveOptions(path string, o Options) error {
+	o.Updated = time.Now()
+	b, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(b, '\n'))
+}
+
+// WritePid records the current process in path, refusing when another live daemon
+// already owns it.
+func WritePid(path string) error {
+	if pid, ok := Running(path); ok && pid != os.Getpid() {
+		return fmt.Errorf("daemon already running (pid %d)", pid)
+	}
+	return writeFileAtomic(path, []byte(strconv.Itoa(os.Getpid())+"\n"))
+}
+
+// ReadPid returns the pid stored in path.
+func ReadPid(path string) (int, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("%s: invalid pid", path)
+	}
+	return pid, nil
+}
+
+// Running reports the pid in path if that process is still alive.
+func Running(path string) (int, bool) {
+	pid, err := ReadPid(path)
+	if err != nil {
+		return 0, false
+	}
+	return pid, pidctl.Alive(pid)
+}
+
+// RemovePid deletes the pidfile if it still names this process.
+func RemovePid(path string) {
+	if pid, err := ReadPid(path); err == nil && pid == os.Getpid() {
+		_ = os.Remove(path)
+	}
+}
+
+func writeFileAtomic(path string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// WaitPidfile waits up to timeout for path to name pid (the child has started up).
+func WaitPidfile(path string, pid int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if got, err := ReadPid(path); err == nil && got == pid {
+			return nil
+		}
+		if !pidctl.Alive(pid) {
+			return errors.New("daemon exited during startup")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return errors.New("timed out waiting for the daemon to write its pidfile")
+}
@@ -0,0 +1,37 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPidfile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "daemon.pid")
+	if _, ok := Running(p); ok {
+		t.Fatal("no pidfile must not be running")
+	}
+	if err := WritePid(p); err != nil {
+		t.Fatal(err)
+	}
+	if pid, ok := Running(p); !ok || pid != os.Getpid() {
+		t.Fatalf("Running = %d, %v", pid, ok)
+	}
+	RemovePid(p)
+	if _, err := os.Stat(p); !os.IsNotExist(err) {
+		t.Fatalf("pidfile not removed: %v", err)
+	}
+}
+
+func TestUnits(t *testing.T) {
+	argv := []string{"/opt/my tools/portik", "daemon", "run", "--ports=5432,6379"}
+	u := SystemdUnit(argv, "/home/me/.portik/daemon.log")
+	if !strings.Contains(u, `ExecStart="/opt/my tools/portik" daemon run --ports=5432,6379`+"\n") {
+		t.Fatalf("systemd ExecStart not quoted:\n%s", u)
+	}
+	p := LaunchdPlist(append(argv, "--cmd=a&b"), "/tmp/log")
+	if !strings.Contains(p, "<string>--cmd=a&amp;b</string>") || !strings.Contains(p, "<string>"+launchdLabel+"</string>") {
+		t.Fatalf("plist:\n%s", p)
+	}
+}
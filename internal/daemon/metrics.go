@@ -0,0 +1,242 @@
+package daemon
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/model"
+)
+
+// Metrics holds Prometheus gauges and counters derived from the reports the daemon
+// polls, rendered in the text exposition format on /metrics.
+type Metrics struct {
+	mu      sync.Mutex
+	ports   map[portKey]*portMetrics
+	changes map[changeKey]int64
+}
+
+type portKey struct {
+	port  int
+	proto string
+}
+
+type changeKey struct {
+	portKey
+	from, to string
+}
+
+type portMetrics struct {
+	seen       bool // a report was observed (gauges are only exported then)
+	listener   bool
+	conns      map[string]int // by TCP state
+	duration   time.Duration  // last inspection
+	scrapes    int64
+	errors     int64
+	lastScrape time.Time
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{ports: map[portKey]*portMetrics{}, changes: map[changeKey]int64{}}
+}
+
+func (m *Metrics) port(port int, proto string) *portMetrics {
+	k := portKey{port, proto}
+	pm := m.ports[k]
+	if pm == nil {
+		pm = &portMetrics{conns: map[string]int{}}
+		m.ports[k] = pm
+	}
+	return pm
+}
+
+// Configure drops the series of ports the daemon no longer monitors.
+func (m *Metrics) Configure(targets []Target) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keep := func(k portKey) bool {
+		return slices.ContainsFunc(targets, func(t Target) bool { return t.Port == k.port && t.Proto == k.proto })
+	}
+	for k := range m.ports {
+		if !keep(k) {
+			delete(m.ports, k)
+		}
+	}
+	for k := range m.changes {
+		if !keep(k.portKey) {
+			delete(m.changes, k)
+		}
+	}
+}
+
+// Observe records a successful inspection that took `took`.
+func (m *Metrics) Observe(rep model.Report, took time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pm := m.port(rep.Port, rep.Proto)
+	pm.seen = true
+	pm.scrapes++
+	pm.duration = took
+	pm.lastScrape = rep.Generated
+	pm.listener = len(rep.Listeners) > 0
+	clear(pm.conns)
+	for _, c := range rep.Connections {
+		if st := strings.ToUpper(c.State); st != "LISTEN" {
+			pm.conns[st]++
+		}
+	}
+}
+
+// ObserveError records a failed inspection.
+func (m *Metrics) ObserveError(port int, proto string, took time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pm := m.port(port, proto)
+	pm.scrapes++
+	pm.errors++
+	pm.duration = took
+}
+
+// OwnerChanged counts a change of owner (labels as in history.OwnerLabel).
+func (m *Metrics) OwnerChanged(port int, proto, from, to string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.changes[changeKey{portKey{port, proto}, from, to}]++
+}
+
+func labelValue(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+func portLabels(k portKey) string {
+	return fmt.Sprintf(`port="%d",proto="%s"`, k.port, labelValue(k.proto))
+}
+
+func compareKeys(a, b portKey) int {
+	return cmp.Or(cmp.Compare(a.port, b.port), strings.Compare(a.proto, b.proto))
+}
+
+// WriteTo renders every series in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]portKey, 0, len(m.ports))
+	for k := range m.ports {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, compareKeys)
+
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	family := func(name, typ, help string) {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+	perPort := func(name, typ, help string, seenOnly bool, value func(*portMetrics) string) {
+		family(name, typ, help)
+		for _, k := range keys {
+			pm := m.ports[k]
+			if seenOnly && !pm.seen {
+				continue
+			}
+			fmt.Fprintf(cw, "%s{%s} %s\n", name, portLabels(k), value(pm))
+		}
+	}
+	boolValue := func(b bool) string {
+		if b {
+			return "1"
+		}
+		return "0"
+	}
+
+	perPort("portik_listener_up", "gauge", "Whether something is listening on the port (1) or not (0).", true,
+		func(pm *portMetrics) string { return boolValue(pm.listener) })
+
+	family("portik_connections", "gauge", "Connections involving the port, by TCP state.")
+	for _, k := range keys {
+		pm := m.ports[k]
+		states := make([]string, 0, len(pm.conns))
+		for st := range pm.conns {
+			states = append(states, st)
+		}
+		slices.Sort(states)
+		for _, st := range states {
+			fmt.Fprintf(cw, "portik_connections{%s,state=\"%s\"} %d\n", portLabels(k), labelValue(st), pm.conns[st])
+		}
+	}
+	perPort("portik_time_wait_sockets", "gauge", "Sockets on the port in TIME_WAIT.", true,
+		func(pm *portMetrics) string { return strconv.Itoa(pm.conns["TIME_WAIT"]) })
+	perPort("portik_close_wait_sockets", "gauge", "Sockets on the port in CLOSE_WAIT.", true,
+		func(pm *portMetrics) string { return strconv.Itoa(pm.conns["CLOSE_WAIT"]) })
+
+	family("portik_owner_changes_total", "counter", "Ownership changes of the port, by previous and new owner.")
+	cks := make([]changeKey, 0, len(m.changes))
+	for k := range m.changes {
+		cks = append(cks, k)
+	}
+	slices.SortFunc(cks, func(a, b changeKey) int {
+		return cmp.Or(compareKeys(a.portKey, b.portKey), strings.Compare(a.from, b.from), strings.Compare(a.to, b.to))
+	})
+	for _, k := range cks {
+		fmt.Fprintf(cw, "portik_owner_changes_total{%s,from=\"%s\",to=\"%s\"} %d\n", portLabels(k.portKey), labelValue(k.from), labelValue(k.to), m.changes[k])
+	}
+
+	perPort("portik_scrape_duration_seconds", "gauge", "How long the last inspection of the port took.", false,
+		func(pm *portMetrics) string { return strconv.FormatFloat(pm.duration.Seconds(), 'g', -1, 64) })
+	perPort("portik_scrapes_total", "counter", "Inspections of the port.", false,
+		func(pm *portMetrics) string { return strconv.FormatInt(pm.scrapes, 10) })
+	perPort("portik_scrape_errors_total", "counter", "Inspections of the port that failed.", false,
+		func(pm *portMetrics) string { return strconv.FormatInt(pm.errors, 10) })
+	perPort("portik_last_scrape_timestamp_seconds", "gauge", "When the port was last inspected successfully.", true,
+		func(pm *portMetrics) string { return strconv.FormatInt(pm.lastScrape.Unix(), 10) })
+
+	if err := cw.w.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, cw.err
+}
+
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+func (m *Metrics) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = m.WriteTo(w)
+	})
+	return mux
+}
+
+// Serve answers /metrics on l until it is closed.
+func (m *Metrics) Serve(l net.Listener) error {
+	srv := &http.Server{Handler: m.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	err := srv.Serve(l)
+	if errors.Is(err, net.ErrClosed) || errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/model"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	m.Observe(model.Report{
+		Port: 5432, Proto: "tcp", Generated: time.Unix(1700000000, 0),
+		Listeners: []model.Listener{{LocalPort: 5432, PID: 42, ProcName: "postgres", State: "LISTEN"}},
+		Connections: []model.Conn{
+			{LocalPort: 5432, State: "ESTABLISHED"},
+			{LocalPort: 5432, State: "ESTABLISHED"},
+			{LocalPort: 5432, State: "TIME_WAIT"},
+		},
+	}, 25*time.Millisecond)
+	m.Observe(model.Report{Port: 8080, Proto: "tcp", Generated: time.Now()}, time.Millisecond)
+	m.ObserveError(8080, "tcp", time.Millisecond)
+	m.OwnerChanged(5432, "tcp", "free", `postgres "db" (me)`)
+
+	srv := httptest.NewServer(m.Handler())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	body := string(b)
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("content type %q", ct)
+	}
+
+	for _, want := range []string{
+		"# TYPE portik_listener_up gauge\n",
+		`portik_listener_up{port="5432",proto="tcp"} 1`,
+		`portik_listener_up{port="8080",proto="tcp"} 0`,
+		`portik_connections{port="5432",proto="tcp",state="ESTABLISHED"} 2`,
+		`portik_time_wait_sockets{port="5432",proto="tcp"} 1`,
+		`portik_close_wait_sockets{port="5432",proto="tcp"} 0`,
+		`portik_owner_changes_total{port="5432",proto="tcp",from="free",to="postgres \"db\" (me)"} 1`,
+		`portik_scrape_duration_seconds{port="5432",proto="tcp"} 0.025`,
+		`portik_scrape_errors_total{port="8080",proto="tcp"} 1`,
+		`portik_last_scrape_timestamp_seconds{port="5432",proto="tcp"} 1700000000`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %s in:\n%s", want, body)
+		}
+	}
+
+	m.Configure([]Target{{Port: 8080, Proto: "tcp"}})
+	var sb strings.Builder
+	_, _ = m.WriteTo(&sb)
+	if strings.Contains(sb.String(), `port="5432"`) {
+		t.Fatalf("series of unmonitored ports must be dropped:\n%s", sb.String())
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"os"
+	"syscall"
+)
+
+// Reload asks the daemon to re-read its options and config (SIGHUP).
+func Reload(pid int) error { return signal(pid, syscall.SIGHUP) }
+
+func signal(pid int, sig os.Signal) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Signal(sig)
+}
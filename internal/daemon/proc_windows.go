@@ -0,0 +1,9 @@
+//go:build windows
+
+package daemon
+
+import "errors"
+
+func Reload(pid int) error {
+	return errors.New("reload is not supported on Windows; restart the daemon instead")
+}
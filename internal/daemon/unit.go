@@ -0,0 +1,105 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	UnitSystemd = "systemd"
+	UnitLaunchd = "launchd"
+
+	launchdLabel = "dev.portik.daemon"
+)
+
+// UnitPath returns where a user-level unit of the given kind is installed.
+func UnitPath(kind string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch kind {
+	case UnitSystemd:
+		if x := os.Getenv("XDG_CONFIG_HOME"); x != "" {
+			return filepath.Join(x, "systemd", "user", "portik.service"), nil
+		}
+		return filepath.Join(home, ".config", "systemd", "user", "portik.service"), nil
+	case UnitLaunchd:
+		return filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist"), nil
+	default:
+		return "", fmt.Errorf("unknown unit kind %q (systemd|launchd)", kind)
+	}
+}
+
+// SystemdUnit renders a systemd user service running argv in the foreground.
+func SystemdUnit(argv []string, logPath string) string {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=portik daemon (port ownership history)\n")
+	b.WriteString("After=network.target\n\n")
+	b.WriteString("[Service]\n")
+	b.WriteString("Type=simple\n")
+	fmt.Fprintf(&b, "ExecStart=%s\n", systemdQuote(argv))
+	b.WriteString("ExecReload=/bin/kill -HUP $MAINPID\n")
+	b.WriteString("Restart=on-failure\n")
+	b.WriteString("RestartSec=5\n")
+	if logPath != "" {
+		fmt.Fprintf(&b, "StandardOutput=append:%s\n", logPath)
+		fmt.Fprintf(&b, "StandardError=append:%s\n", logPath)
+	}
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=default.target\n")
+	return b.String()
+}
+
+func systemdQuote(argv []string) string {
+	out := make([]string, 0, len(argv))
+	for _, a := range argv {
+		if a == "" || strings.ContainsAny(a, " \t\"'\\$%") {
+			a = strings.ReplaceAll(a, `\`, `\\`)
+			a = strings.ReplaceAll(a, `"`, `\"`)
+			a = strings.ReplaceAll(a, "$", "$$")
+			a = strings.ReplaceAll(a, "%", "%%")
+			a = `"` + a + `"`
+		}
+		out = append(out, a)
+	}
+	return strings.Join(out, " ")
+}
+
+// LaunchdPlist renders a launchd agent running argv, kept alive and started at login.
+func LaunchdPlist(argv []string, logPath string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString(`<plist version="1.0">` + "\n<dict>\n")
+	fmt.Fprintf(&b, "  <key>Label</key>\n  <string>%s</string>\n", launchdLabel)
+	b.WriteString("  <key>ProgramArguments</key>\n  <array>\n")
+	for _, a := range argv {
+		fmt.Fprintf(&b, "    <string>%s</string>\n", xmlEscape(a))
+	}
+	b.WriteString("  </array>\n")
+	b.WriteString("  <key>RunAtLoad</key>\n  <true/>\n")
+	b.WriteString("  <key>KeepAlive</key>\n  <dict>\n    <key>SuccessfulExit</key>\n    <false/>\n  </dict>\n")
+	if logPath != "" {
+		fmt.Fprintf(&b, "  <key>StandardOutPath</key>\n  <string>%s</string>\n", xmlEscape(logPath))
+		fmt.Fprintf(&b, "  <key>StandardErrorPath</key>\n  <string>%s</string>\n", xmlEscape(logPath))
+	}
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String()
+}
+
+// UnitHint tells the user how to activate an installed unit.
+func UnitHint(kind, path string) string {
+	if kind == UnitLaunchd {
+		return fmt.Sprintf("launchctl bootstrap gui/%d %s", os.Getuid(), path)
+	}
+	return "systemctl --user daemon-reload && systemctl --user enable --now portik.service"
+}
+
+func xmlEscape(s string) string {
+	r := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+	return r.Replace(s)
+}
@@ -1,25 +1,44 @@
 package docker
 
 import (
-	"bytes"
 	"os/exec"
+	"strconv"
 	"strings"
 
 	"github.com/pratik-anurag/portik/internal/model"
 )
 
 func MapPort(port int, proto string) model.DockerMap {
-	m := model.DockerMap{Checked: true}
-	if _, err := exec.LookPath("docker"); err != nil {
-		return m
+	return MapPorts([]int{port}, proto)[port]
+}
+
+// MapPorts maps many host ports with one pass over the running containers of every
+// installed runtime. Every port is in the result, with Checked set; a mapped port
+// records the runtime that published it.
+func MapPorts(ports []int, proto string) map[int]model.DockerMap {
+	out := map[int]model.DockerMap{}
+	for _, p := range ports {
+		out[p] = model.DockerMap{Checked: true}
+	}
+	left := len(ports)
+	for _, rt := range Runtimes() {
+		if left == 0 {
+			break
+		}
+		left -= mapRuntime(rt, proto, out, left)
 	}
+	return out
+}
 
-	out, err := exec.Command("docker", "ps", "--format", "{{.ID}} {{.Names}}").Output()
+// mapRuntime fills the left still unmapped ports of out from rt's containers and
+// returns how many it mapped.
+func mapRuntime(rt, proto string, out map[int]model.DockerMap, left int) int {
+	ps, err := exec.Command(rt, "ps", "--format", "{{.ID}} {{.Names}}").Output()
 	if err != nil {
-		return m
+		return 0
 	}
-	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
-	for _, line := range lines {
+	mapped := 0
+	for _, line := range strings.Split(strings.TrimSpace(string(ps)), "\n") {
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
@@ -30,23 +49,37 @@ func MapPort(port int, proto string) model.DockerMap {
 		id := strings.TrimSpace(parts[0])
 		name := strings.TrimSpace(parts[1])
 
-		po, err := exec.Command("docker", "port", id).Output()
+		po, err := exec.Command(rt, "port", id).Output()
 		if err != nil {
 			continue
 		}
-		if mapped, cport := parseDockerPortOutput(po, port, proto); mapped {
-			m.Mapped = true
-			m.ContainerID = id
-			m.ContainerName = name
-			m.ContainerPort = cport
-			m.ComposeService = composeServiceLabel(id)
-			return m
+		var ci ComposeInfo
+		composed := false
+		for hostPort, cport := range parseDockerPortOutput(po, proto) {
+			m, ok := out[hostPort]
+			if !ok || m.Mapped {
+				continue
+			}
+			if !composed {
+				ci, composed = Compose(rt, id), true
+			}
+			out[hostPort] = model.DockerMap{
+				Checked: true, Mapped: true, Runtime: rt,
+				ContainerID: id, ContainerName: name, ContainerPort: cport,
+				ComposeProject: ci.Project, ComposeService: ci.Service,
+			}
+			mapped++
+		}
+		if mapped == left {
+			break
 		}
 	}
-	return m
+	return mapped
 }
 
-func parseDockerPortOutput(b []byte, hostPort int, proto string) (bool, string) {
+// parseDockerPortOutput maps host ports to container ports ("5432/tcp") for proto.
+func parseDockerPortOutput(b []byte, proto string) map[int]string {
+	out := map[int]string{}
 	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
 	for _, l := range lines {
 		l = strings.TrimSpace(l)
@@ -62,32 +95,15 @@ func parseDockerPortOutput(b []byte, hostPort int, proto string) (bool, string)
 		if !strings.HasSuffix(left, "/"+proto) {
 			continue
 		}
-		if strings.HasSuffix(right, ":"+itoa(hostPort)) {
-			return true, left
+		i := strings.LastIndex(right, ":")
+		if i < 0 {
+			continue
+		}
+		if hp, err := strconv.Atoi(right[i+1:]); err == nil {
+			if _, dup := out[hp]; !dup {
+				out[hp] = left
+			}
 		}
 	}
-	return false, ""
-}
-
-func composeServiceLabel(containerID string) string {
-	cmd := exec.Command("docker", "inspect", "-f", `{{ index .Config.Labels "com.docker.compose.service" }}`, containerID)
-	var buf bytes.Buffer
-	cmd.Stdout = &buf
-	_ = cmd.Run()
-	return strings.TrimSpace(buf.String())
-}
-
-func itoa(n int) string {
-	if n == 0 {
-		return "0"
-	}
-	var b [32]byte
-	i := len(b)
-	x := n
-	for x > 0 {
-		i--
-		b[i] = byte('0' + x%10)
-		x /= 10
-	}
-	return string(b[i:])
+	return out
 }
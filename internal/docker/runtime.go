@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Runtimes returns the container CLIs to shell out to, docker first: a host can run
+// both, and a port may be published by either. PORTIK_CONTAINER_RUNTIME=docker|podman
+// restricts it to one.
+func Runtimes() []string {
+	if rt := strings.TrimSpace(os.Getenv("PORTIK_CONTAINER_RUNTIME")); rt != "" {
+		if _, err := exec.LookPath(rt); err == nil {
+			return []string{rt}
+		}
+		return nil
+	}
+	var out []string
+	for _, rt := range []string{"docker", "podman"} {
+		if _, err := exec.LookPath(rt); err == nil {
+			out = append(out, rt)
+		}
+	}
+	return out
+}
+
+// ComposeInfo is the compose metadata stamped on a container by docker compose / podman-compose.
+type ComposeInfo struct {
+	Project     string   `json:"project,omitempty"`
+	Service     string   `json:"service,omitempty"`
+	WorkingDir  string   `json:"working_dir,omitempty"`
+	ConfigFiles []string `json:"config_files,omitempty"`
+}
+
+// Compose reads the compose labels of a container. Missing labels yield empty fields.
+func Compose(runtime, containerID string) ComposeInfo {
+	if runtime == "" {
+		runtime = "docker"
+	}
+	format := strings.Join([]string{
+		`{{ index .Config.Labels "com.docker.compose.project" }}`,
+		`{{ index .Config.Labels "com.docker.compose.service" }}`,
+		`{{ index .Config.Labels "com.docker.compose.project.working_dir" }}`,
+		`{{ index .Config.Labels "com.docker.compose.project.config_files" }}`,
+	}, "|")
+	cmd := exec.Command(runtime, "inspect", "-f", format, containerID)
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	_ = cmd.Run()
+
+	parts := strings.Split(strings.TrimSpace(buf.String()), "|")
+	if len(parts) != 4 {
+		return ComposeInfo{}
+	}
+	ci := ComposeInfo{
+		Project:    clean(parts[0]),
+		Service:    clean(parts[1]),
+		WorkingDir: clean(parts[2]),
+	}
+	for _, f := range strings.Split(clean(parts[3]), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			ci.ConfigFiles = append(ci.ConfigFiles, f)
+		}
+	}
+	return ci
+}
+
+func clean(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "<no value>" {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,174 @@
+// Package eventlog writes the daemon's structured event log: one Entry per event,
+// with stable field names, fanned out to pluggable sinks (NDJSON file, syslog,
+// journald).
+package eventlog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/history"
+	"github.com/pratik-anurag/portik/internal/model"
+)
+
+// Event types.
+const (
+	OwnerChanged = "owner_changed"
+	PortFreed    = "port_freed"
+	DiagRaised   = "diag_raised"
+	ProbeFailed  = "probe_failed"
+	Anomaly      = "anomaly" // a history pattern (flapping, crash loop, ...)
+	Daemon       = "daemon"  // lifecycle: started, reloaded, stopped
+	Action       = "action"  // a kill/restart/reload copied from the audit log
+)
+
+// Levels, lowest first.
+const (
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+var levelRank = map[string]int{LevelInfo: 1, LevelWarn: 2, LevelError: 3}
+
+// ValidLevel reports whether s is info|warn|error.
+func ValidLevel(s string) bool { return levelRank[s] > 0 }
+
+// Entry is one event. Field names are part of the log format; add, never rename.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"`
+	Event     string    `json:"event"`
+	Msg       string    `json:"msg"`
+	Host      string    `json:"host,omitempty"`
+	Port      int       `json:"port,omitempty"`
+	Proto     string    `json:"proto,omitempty"`
+	OldOwner  string    `json:"old_owner,omitempty"`
+	NewOwner  string    `json:"new_owner,omitempty"`
+	PID       int32     `json:"pid,omitempty"`
+	Process   string    `json:"process,omitempty"`
+	User      string    `json:"user,omitempty"`
+	Container string    `json:"container,omitempty"`
+	Kind      string    `json:"kind,omitempty"`     // diagnostic or anomaly kind
+	Severity  string    `json:"severity,omitempty"` // diagnostic severity
+	Details   string    `json:"details,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// ForReport fills the port and owner fields from rep.
+func ForReport(e Entry, rep model.Report) Entry {
+	e.Port, e.Proto, e.Host = rep.Port, rep.Proto, rep.Host.Hostname
+	if e.Time.IsZero() {
+		e.Time = rep.Generated
+	}
+	if l, ok := rep.PrimaryListener(); ok {
+		e.PID, e.Process, e.User = l.PID, l.ProcName, l.User
+	}
+	e.Container = rep.Docker.ContainerName
+	if e.NewOwner == "" {
+		e.NewOwner = history.OwnerLabel(history.EventFromReport(rep))
+	}
+	return e
+}
+
+// Sink receives every entry at or above the logger's level.
+type Sink interface {
+	Write(Entry) error
+	Close() error
+}
+
+// Logger fans entries out to its sinks. The zero Logger and a nil *Logger discard
+// everything.
+type Logger struct {
+	level string
+	sinks []Sink
+	onErr func(error)
+
+	mu     sync.Mutex
+	failed map[int]bool // sinks whose last write failed, so errors are reported once
+}
+
+// New returns a logger writing entries at or above level to sinks. Write errors are
+// passed to onErr (stderr when nil) once per failing streak.
+func New(level string, onErr func(error), sinks ...Sink) *Logger {
+	if onErr == nil {
+		onErr = func(err error) { fmt.Fprintln(os.Stderr, "error: event log:", err) }
+	}
+	return &Logger{level: level, sinks: sinks, onErr: onErr, failed: map[int]bool{}}
+}
+
+// Enabled reports whether the logger has any sink.
+func (l *Logger) Enabled() bool { return l != nil && len(l.sinks) > 0 }
+
+// Log writes e, filling Time, Level and Host when unset.
+func (l *Logger) Log(e Entry) {
+	if !l.Enabled() {
+		return
+	}
+	if e.Level == "" {
+		e.Level = LevelInfo
+	}
+	if levelRank[e.Level] < levelRank[l.level] {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Host == "" {
+		e.Host, _ = os.Hostname()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, s := range l.sinks {
+		err := s.Write(e)
+		if err != nil && !l.failed[i] {
+			l.onErr(err)
+		}
+		l.failed[i] = err != nil
+	}
+}
+
+// Close closes every sink.
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var errs []error
+	for _, s := range l.sinks {
+		errs = append(errs, s.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// fields lists the set per-event fields of e as name/value pairs, named as in JSON.
+// Time, level, event, msg and host are carried natively by syslog and journald.
+func fields(e Entry) [][2]string {
+	var out [][2]string
+	add := func(k, v string) {
+		if v != "" {
+			out = append(out, [2]string{k, v})
+		}
+	}
+	if e.Port > 0 {
+		add("port", fmt.Sprint(e.Port))
+	}
+	add("proto", e.Proto)
+	add("old_owner", e.OldOwner)
+	add("new_owner", e.NewOwner)
+	if e.PID > 0 {
+		add("pid", fmt.Sprint(e.PID))
+	}
+	add("process", e.Process)
+	add("user", e.User)
+	add("container", e.Container)
+	add("kind", e.Kind)
+	add("severity", e.Severity)
+	add("details", e.Details)
+	add("error", e.Error)
+	return out
+}
@@ -0,0 +1,154 @@
+package eventlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+var at = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func TestFileSinkRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	s, err := NewFileSink(path, 300, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New(LevelWarn, func(err error) { t.Fatal(err) }, s)
+	for i := range 10 {
+		l.Log(Entry{Time: at, Level: LevelWarn, Event: DiagRaised, Msg: "zombie owner", Port: 3000 + i, Proto: "tcp"})
+	}
+	l.Log(Entry{Event: OwnerChanged, Port: 1}) // info: below the level
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for _, p := range []string{path + ".2", path + ".1", path} {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) > 300 {
+			t.Errorf("%s is %d bytes, over the limit", p, len(b))
+		}
+		lines = append(lines, strings.Split(strings.TrimSpace(string(b)), "\n")...)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("only 2 rotated files must be kept: %v", err)
+	}
+	var last Entry
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil || last.Port != 3009 || last.Host == "" {
+		t.Fatalf("last entry %q: %v", lines[len(lines)-1], err)
+	}
+}
+
+func TestLoggerReportsFailuresOnce(t *testing.T) {
+	var errs int
+	l := New(LevelInfo, func(error) { errs++ }, failing{})
+	l.Log(Entry{Event: ProbeFailed})
+	l.Log(Entry{Event: ProbeFailed})
+	if errs != 1 {
+		t.Fatalf("want 1 reported error, got %d", errs)
+	}
+}
+
+type failing struct{}
+
+func (failing) Write(Entry) error { return errors.New("disk full") }
+func (failing) Close() error      { return nil }
+
+// listenGram opens a unixgram socket in a short temp dir (socket paths are limited
+// to ~100 bytes).
+func listenGram(t *testing.T) (*net.UnixConn, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix datagram sockets")
+	}
+	dir, err := os.MkdirTemp("", "elog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "s")
+	c, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return c, path
+}
+
+func TestSyslog(t *testing.T) {
+	srv, path := listenGram(t)
+	s, err := NewSyslogSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.host, s.pid = "box", 42
+	if err := s.Write(Entry{Time: at, Level: LevelWarn, Event: OwnerChanged, Msg: "5432/tcp: postgres -> free", Port: 5432, Proto: "tcp", Details: `a "b" ]`}); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4096)
+	n, err := srv.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<28>1 2024-05-01T10:00:00Z box portik 42 owner_changed [portik@32473 port="5432" proto="tcp" details="a \"b\" \]"] 5432/tcp: postgres -> free`
+	if got := string(buf[:n]); got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestJournald(t *testing.T) {
+	srv, path := listenGram(t)
+	s, err := NewJournaldSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Write(Entry{Time: at, Level: LevelError, Event: ProbeFailed, Msg: "probe failed", Port: 8080, Proto: "tcp", Error: "ss: not found\nexit 127"}); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4096)
+	n, err := srv.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]string{}
+	r := bufio.NewReader(bytes.NewReader(buf[:n]))
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if k, v, ok := strings.Cut(line, "="); ok {
+			fields[k] = v
+			continue
+		}
+		var size uint64 // binary form
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			t.Fatal(err)
+		}
+		v := make([]byte, size+1)
+		if _, err := r.Read(v); err != nil {
+			t.Fatal(err)
+		}
+		fields[line] = string(v[:size])
+	}
+	if fields["MESSAGE"] != "probe failed" || fields["PRIORITY"] != "3" || fields["PORTIK_EVENT"] != ProbeFailed ||
+		fields["PORTIK_PORT"] != "8080" || fields["PORTIK_ERROR"] != "ss: not found\nexit 127" {
+		t.Fatalf("fields: %q", fields)
+	}
+}
@@ -0,0 +1,89 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FileSink appends NDJSON to a file, rotating it to path.1 ... path.N once it would
+// grow past MaxSize. The path "-" writes to stdout without rotation.
+type FileSink struct {
+	path    string
+	maxSize int64
+	keep    int
+
+	f    io.WriteCloser
+	size int64
+}
+
+// NewFileSink opens path for appending. maxSize <= 0 disables rotation; keep is how
+// many rotated files are kept.
+func NewFileSink(path string, maxSize int64, keep int) (*FileSink, error) {
+	s := &FileSink{path: path, maxSize: maxSize, keep: max(keep, 1)}
+	if path == "-" {
+		s.f, s.maxSize = nopCloser{os.Stdout}, 0
+		return s, nil
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func (s *FileSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.f, s.size = f, fi.Size()
+	return nil
+}
+
+func (s *FileSink) Write(e Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(b)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return fmt.Errorf("rotate %s: %w", s.path, err)
+		}
+	}
+	n, err := s.f.Write(b)
+	s.size += int64(n)
+	return err
+}
+
+// rotate shifts path.(N-1) to path.N, ..., path to path.1 and reopens path.
+func (s *FileSink) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return err
+	}
+	_ = os.Remove(fmt.Sprintf("%s.%d", s.path, s.keep))
+	for i := s.keep - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+	}
+	err := os.Rename(s.path, s.path+".1")
+	if oerr := s.open(); oerr != nil {
+		return oerr
+	}
+	return err
+}
+
+func (s *FileSink) Close() error { return s.f.Close() }
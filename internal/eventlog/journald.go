@@ -0,0 +1,87 @@
+package eventlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// JournalSocket is where journald accepts native protocol datagrams.
+const JournalSocket = "/run/systemd/journal/socket"
+
+// JournaldSink sends entries with the journald native protocol, so every field is
+// queryable (journalctl PORTIK_EVENT=owner_changed).
+type JournaldSink struct {
+	addr *net.UnixAddr
+	conn *net.UnixConn
+}
+
+// NewJournaldSink connects to path (JournalSocket when empty).
+func NewJournaldSink(path string) (*JournaldSink, error) {
+	if path == "" {
+		path = JournalSocket
+	}
+	s := &JournaldSink{addr: &net.UnixAddr{Name: path, Net: "unixgram"}}
+	if err := s.dial(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *JournaldSink) dial() error {
+	conn, err := net.DialUnix("unixgram", nil, s.addr)
+	if err != nil {
+		return fmt.Errorf("journald: %w", err)
+	}
+	s.conn = conn
+	return nil
+}
+
+// journald priorities (syslog levels)
+var journalPriority = map[string]string{LevelError: "3", LevelWarn: "4", LevelInfo: "6"}
+
+// encode renders e as KEY=value lines; values containing a newline use the binary
+// form (KEY\n, 64-bit little-endian length, value\n).
+func encodeJournal(e Entry) []byte {
+	var b bytes.Buffer
+	put := func(k, v string) {
+		if !strings.Contains(v, "\n") {
+			fmt.Fprintf(&b, "%s=%s\n", k, v)
+			return
+		}
+		b.WriteString(k + "\n")
+		_ = binary.Write(&b, binary.LittleEndian, uint64(len(v)))
+		b.WriteString(v + "\n")
+	}
+	msg := e.Msg
+	if msg == "" {
+		msg = e.Event
+	}
+	put("MESSAGE", msg)
+	put("PRIORITY", journalPriority[e.Level])
+	put("SYSLOG_IDENTIFIER", "portik")
+	put("PORTIK_EVENT", e.Event)
+	put("PORTIK_LEVEL", e.Level)
+	for _, kv := range fields(e) {
+		put("PORTIK_"+strings.ToUpper(kv[0]), kv[1])
+	}
+	return b.Bytes()
+}
+
+func (s *JournaldSink) Write(e Entry) error {
+	msg := encodeJournal(e)
+	if _, err := s.conn.Write(msg); err != nil {
+		// journald may have restarted; reconnect once
+		s.conn.Close()
+		if derr := s.dial(); derr != nil {
+			return derr
+		}
+		_, err = s.conn.Write(msg)
+		return err
+	}
+	return nil
+}
+
+func (s *JournaldSink) Close() error { return s.conn.Close() }
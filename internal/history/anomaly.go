@@ -0,0 +1,279 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Pattern kinds produced by DetectAnomalies.
+const (
+	PatternFlapping   = "flapping"
+	PatternCrashLoop  = "crash-loop"
+	PatternSquatter   = "squatter"
+	PatternFirstSeen  = "first-seen-owner"
+	maxEvidenceEvents = 8
+)
+
+// DetectOptions tunes the anomaly detectors.
+type DetectOptions struct {
+	FlapChanges     int           // owner changes that count as flapping...
+	FlapWindow      time.Duration // ...when they happen within this window
+	CrashRestarts   int           // restarts of the same cmdline that count as a crash loop...
+	CrashWindow     time.Duration // ...when each follows the previous start within this gap
+	SquatterMinRuns int           // usual owner must have held the port this many times
+}
+
+func DefaultDetectOptions() DetectOptions {
+	return DetectOptions{
+		FlapChanges:     4,
+		FlapWindow:      10 * time.Minute,
+		CrashRestarts:   3,
+		CrashWindow:     2 * time.Minute,
+		SquatterMinRuns: 3,
+	}
+}
+
+// DetectAnomalies runs the flapping, crash-loop, squatter and first-seen detectors over
+// the full history of one port/proto (oldest first) and returns the patterns whose latest
+// evidence is at or after since. Earlier events still serve as the baseline.
+//
+// Each host's events are examined separately: imported events interleaved with local
+// ones would otherwise look like owner changes. Patterns from other hosts name the host.
+func DetectAnomalies(events []OwnershipEvent, since time.Time, opt DetectOptions) []Pattern {
+	byHost := map[string][]OwnershipEvent{}
+	for _, e := range events {
+		host := ""
+		if !e.IsLocal() {
+			host = e.Host
+		}
+		byHost[host] = append(byHost[host], e)
+	}
+
+	var all []Pattern
+	for host, evs := range byHost {
+		if len(evs) < 2 {
+			continue
+		}
+		sort.SliceStable(evs, func(i, j int) bool { return evs[i].At.Before(evs[j].At) })
+		var ps []Pattern
+		ps = append(ps, detectFlapping(evs, opt)...)
+		ps = append(ps, detectCrashLoop(evs, opt)...)
+		ps = append(ps, detectSquatter(evs, opt)...)
+		ps = append(ps, detectFirstSeen(evs)...)
+		for _, p := range ps {
+			if host != "" {
+				p.Summary += " (on " + host + ")"
+			}
+			all = append(all, p)
+		}
+	}
+
+	var out []Pattern
+	for _, p := range all {
+		if !p.At.Before(since) {
+			out = append(out, p)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].At.Before(out[j].At) })
+	return out
+}
+
+func evidenceLine(e OwnershipEvent) string {
+	s := fmt.Sprintf("%s  %s", e.At.Format(time.RFC3339), OwnerLabel(e))
+	if e.PID > 0 && !e.IsReleased() {
+		s += fmt.Sprintf("  pid=%d", e.PID)
+	}
+	return s
+}
+
+func evidenceOf(evs []OwnershipEvent) []string {
+	if len(evs) > maxEvidenceEvents {
+		evs = evs[len(evs)-maxEvidenceEvents:]
+	}
+	out := make([]string, 0, len(evs))
+	for _, e := range evs {
+		out = append(out, evidenceLine(e))
+	}
+	return out
+}
+
+// detectFlapping reports bursts of at least FlapChanges owner changes (including the
+// port becoming free) within FlapWindow. Each maximal burst yields one pattern.
+func detectFlapping(evs []OwnershipEvent, opt DetectOptions) []Pattern {
+	n := opt.FlapChanges
+	if n < 2 || opt.FlapWindow <= 0 {
+		return nil
+	}
+	var changes []OwnershipEvent
+	for i := 1; i < len(evs); i++ {
+		if OwnerLabel(evs[i]) != OwnerLabel(evs[i-1]) {
+			changes = append(changes, evs[i])
+		}
+	}
+	in := make([]bool, len(changes))
+	for j := n - 1; j < len(changes); j++ {
+		if changes[j].At.Sub(changes[j-n+1].At) <= opt.FlapWindow {
+			for k := j - n + 1; k <= j; k++ {
+				in[k] = true
+			}
+		}
+	}
+
+	var out []Pattern
+	for i := 0; i < len(changes); {
+		if !in[i] {
+			i++
+			continue
+		}
+		j := i
+		for j+1 < len(changes) && in[j+1] {
+			j++
+		}
+		burst := changes[i : j+1]
+		span := burst[len(burst)-1].At.Sub(burst[0].At)
+		owners := map[string]bool{}
+		for _, e := range burst {
+			owners[OwnerLabel(e)] = true
+		}
+		out = append(out, Pattern{
+			Kind:     PatternFlapping,
+			Severity: "warn",
+			At:       burst[len(burst)-1].At,
+			Summary:  fmt.Sprintf("Ownership flapped %d times in %s between %d owners", len(burst), FormatDuration(span), len(owners)),
+			Details:  fmt.Sprintf("threshold: %d changes within %s", n, FormatDuration(opt.FlapWindow)),
+			Evidence: evidenceOf(burst),
+		})
+		i = j + 1
+	}
+	return out
+}
+
+// detectCrashLoop reports runs where the same cmdline keeps reacquiring the port under a
+// new PID, each start within CrashWindow of the previous one.
+func detectCrashLoop(evs []OwnershipEvent, opt DetectOptions) []Pattern {
+	if opt.CrashRestarts < 1 || opt.CrashWindow <= 0 {
+		return nil
+	}
+	var out []Pattern
+	var run []OwnershipEvent
+	flush := func() {
+		if len(run)-1 >= opt.CrashRestarts {
+			last := run[len(run)-1]
+			out = append(out, Pattern{
+				Kind:     PatternCrashLoop,
+				Severity: "warn",
+				At:       last.At,
+				Summary:  fmt.Sprintf("%s restarted %d times in %s (new PID each time)", OwnerLabel(last), len(run)-1, FormatDuration(last.At.Sub(run[0].At))),
+				Details:  "cmd: " + last.Cmdline,
+				Evidence: evidenceOf(run),
+			})
+		}
+		run = nil
+	}
+	for _, e := range evs {
+		if e.IsReleased() {
+			continue
+		}
+		if e.Cmdline == "" || e.PID <= 0 {
+			flush()
+			continue
+		}
+		if len(run) > 0 {
+			prev := run[len(run)-1]
+			switch {
+			case e.Cmdline != prev.Cmdline || e.At.Sub(prev.At) > opt.CrashWindow:
+				flush()
+			case e.PID == prev.PID:
+				continue // same process, e.g. re-recorded after a docker mapping change
+			}
+		}
+		run = append(run, e)
+	}
+	flush()
+	return out
+}
+
+// detectSquatter reports owners that took the port between two tenures of the usual
+// owner (the one with the most acquisitions, at least SquatterMinRuns).
+func detectSquatter(evs []OwnershipEvent, opt DetectOptions) []Pattern {
+	// runs counts tenures (a free gap starts a new one); seq collapses to the sequence of
+	// distinct owners, ignoring free gaps
+	runs := map[string]int{}
+	var seq []OwnershipEvent
+	prev := ""
+	for _, e := range evs {
+		lbl := OwnerLabel(e)
+		if lbl != prev && !e.IsReleased() {
+			runs[lbl]++
+		}
+		prev = lbl
+		if e.IsReleased() {
+			continue
+		}
+		if len(seq) > 0 && OwnerLabel(seq[len(seq)-1]) == lbl {
+			continue
+		}
+		seq = append(seq, e)
+	}
+	usual, best := "", 0
+	for lbl, n := range runs {
+		if n > best || n == best && lbl < usual {
+			usual, best = lbl, n
+		}
+	}
+	if best < opt.SquatterMinRuns || best < 2 {
+		return nil
+	}
+
+	var out []Pattern
+	for i := 1; i+1 < len(seq); i++ {
+		lbl := OwnerLabel(seq[i])
+		if lbl == usual || OwnerLabel(seq[i-1]) != usual || OwnerLabel(seq[i+1]) != usual {
+			continue
+		}
+		out = append(out, Pattern{
+			Kind:     PatternSquatter,
+			Severity: "warn",
+			At:       seq[i+1].At,
+			Summary:  fmt.Sprintf("%s held the port between two runs of the usual owner %s", lbl, usual),
+			Details:  fmt.Sprintf("usual owner held the port %d times", best),
+			Evidence: evidenceOf(seq[i-1 : i+2]),
+		})
+	}
+	return out
+}
+
+// detectFirstSeen reports owners that had never held the port before. The very first
+// owner on record has no baseline and is not reported.
+func detectFirstSeen(evs []OwnershipEvent) []Pattern {
+	seen := map[string]bool{}
+	var out []Pattern
+	for _, e := range evs {
+		if e.IsReleased() {
+			continue
+		}
+		lbl := OwnerLabel(e)
+		if seen[lbl] {
+			continue
+		}
+		if len(seen) > 0 {
+			known := make([]string, 0, len(seen))
+			for k := range seen {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			out = append(out, Pattern{
+				Kind:     PatternFirstSeen,
+				Severity: "info",
+				At:       e.At,
+				Summary:  fmt.Sprintf("%s held the port for the first time", lbl),
+				Details:  "previous owners: " + strings.Join(known, ", "),
+				Evidence: []string{evidenceLine(e)},
+			})
+		}
+		seen[lbl] = true
+	}
+	return out
+}
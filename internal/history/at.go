@@ -0,0 +1,74 @@
+package history
+
+import (
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/model"
+)
+
+// Snapshot is the recorded state of one port/proto at a point in time.
+type Snapshot struct {
+	Key    string          `json:"key"`
+	At     time.Time       `json:"at"`
+	Sample *OwnershipEvent `json:"sample,omitempty"`      // last event at or before At
+	Next   *OwnershipEvent `json:"next_change,omitempty"` // first event after At
+}
+
+// StaleFor is how long before At the state was last sampled (0 without a sample).
+func (s Snapshot) StaleFor() time.Duration {
+	if s.Sample == nil {
+		return 0
+	}
+	return s.At.Sub(s.Sample.At)
+}
+
+// SnapshotAt finds the state of key at t on this machine (imported events from
+// other hosts are ignored). Events are only recorded on change, so the sample may
+// be much older than t while still describing it.
+func (s *Store) SnapshotAt(key string, t time.Time) Snapshot {
+	snap := Snapshot{Key: key, At: t}
+	for _, e := range localEvents(s.Ports[key]) {
+		if e.At.After(t) {
+			snap.Next = &e
+			break
+		}
+		snap.Sample = &e
+	}
+	return snap
+}
+
+// ReportFromEvent rebuilds a who-style report from a recorded event. Addresses and
+// connections are not recorded, so listeners carry only process details.
+func ReportFromEvent(e OwnershipEvent) model.Report {
+	rep := model.Report{
+		Port:      e.Port,
+		Proto:     e.Proto,
+		Generated: e.At,
+		Host:      model.HostSummary{Hostname: e.Host},
+	}
+	if !e.IsReleased() && (e.PID > 0 || e.ProcName != "" || !e.DockerMapped) {
+		state := "LISTEN"
+		if e.Proto == "udp" {
+			state = "BOUND"
+		}
+		rep.Listeners = []model.Listener{{
+			LocalPort: e.Port,
+			Family:    "unknown",
+			State:     state,
+			PID:       e.PID,
+			ProcName:  e.ProcName,
+			Cmdline:   e.Cmdline,
+			User:      e.User,
+		}}
+	}
+	if e.DockerMapped {
+		rep.Docker = model.DockerMap{
+			Checked:        true,
+			Mapped:         true,
+			ContainerID:    e.ContainerID,
+			ContainerName:  e.ContainerName,
+			ComposeService: e.ComposeService,
+		}
+	}
+	return rep
+}
@@ -1,20 +1,19 @@
 package history
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/pratik-anurag/portik/internal/config"
 	"github.com/pratik-anurag/portik/internal/model"
 )
 
-const maxEntriesPerPort = 200
-
+// Store is an in-memory snapshot of (part of) the history log.
 type Store struct {
 	Version int                         `json:"version"`
 	Ports   map[string][]OwnershipEvent `json:"ports"` // key: "5432/tcp"
@@ -22,6 +21,7 @@ type Store struct {
 
 type OwnershipEvent struct {
 	At             time.Time `json:"at"`
+	Kind           string    `json:"kind,omitempty"` // acquired|released
 	Port           int       `json:"port"`
 	Proto          string    `json:"proto"`
 	PID            int32     `json:"pid,omitempty"`
@@ -33,6 +33,29 @@ type OwnershipEvent struct {
 	ContainerName  string    `json:"container_name,omitempty"`
 	ComposeService string    `json:"compose_service,omitempty"`
 	Signature      string    `json:"signature"`
+	Host           string    `json:"host,omitempty"` // hostname the event was recorded on
+}
+
+var localHostname = sync.OnceValue(func() string {
+	h, _ := os.Hostname()
+	return h
+})
+
+// IsLocal reports whether e was recorded on this machine rather than imported
+// from another one; events from before hosts were recorded count as local.
+func (e OwnershipEvent) IsLocal() bool {
+	return e.Host == "" || e.Host == localHostname()
+}
+
+// localEvents keeps the events recorded on this machine.
+func localEvents(evs []OwnershipEvent) []OwnershipEvent {
+	var out []OwnershipEvent
+	for _, e := range evs {
+		if e.IsLocal() {
+			out = append(out, e)
+		}
+	}
+	return out
 }
 
 type View struct {
@@ -40,12 +63,16 @@ type View struct {
 	Events   []OwnershipEvent `json:"events"`
 	Top      []TopOwner       `json:"top"`
 	Patterns []Pattern        `json:"patterns,omitempty"`
+	Stats    *Stats           `json:"stats,omitempty"`
 }
 
 type Pattern struct {
-	Kind    string `json:"kind"`              // hour-of-day|day-of-week|owner-at-hour
-	Summary string `json:"summary"`           // human readable
-	Details string `json:"details,omitempty"` // extra info
+	Kind     string    `json:"kind"`               // hour-of-day|day-of-week|owner-at-hour|flapping|crash-loop|squatter|first-seen-owner
+	Severity string    `json:"severity,omitempty"` // info|warn (anomaly detectors only)
+	At       time.Time `json:"at,omitzero"`        // newest event the pattern is based on
+	Summary  string    `json:"summary"`            // human readable
+	Details  string    `json:"details,omitempty"`  // extra info
+	Evidence []string  `json:"evidence,omitempty"` // events backing the pattern
 }
 
 type TopOwner struct {
@@ -53,63 +80,73 @@ type TopOwner struct {
 	Count int    `json:"count"`
 }
 
-func historyPath() (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
-	}
-	return filepath.Join(home, ".portik", "history.json"), nil
-}
-
+// Load reads every recorded port/proto into memory.
 func Load() (*Store, error) {
-	p, err := historyPath()
+	keys, err := Keys()
 	if err != nil {
 		return nil, err
 	}
-	b, err := os.ReadFile(p)
-	if errors.Is(err, os.ErrNotExist) {
-		return &Store{Version: 1, Ports: map[string][]OwnershipEvent{}}, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-	var s Store
-	if err := json.Unmarshal(b, &s); err != nil {
-		return nil, err
+	return loadKeys(keys)
+}
+
+// LoadPorts reads only the given ports (both tcp and udp).
+func LoadPorts(ports ...int) (*Store, error) {
+	var keys []string
+	for _, p := range ports {
+		keys = append(keys, keyOf(p, "tcp"), keyOf(p, "udp"))
 	}
-	if s.Ports == nil {
-		s.Ports = map[string][]OwnershipEvent{}
+	return loadKeys(keys)
+}
+
+func loadKeys(keys []string) (*Store, error) {
+	s := &Store{Version: 2, Ports: map[string][]OwnershipEvent{}}
+	for _, k := range keys {
+		evs, err := ReadKey(k)
+		if err != nil {
+			return nil, err
+		}
+		if len(evs) > 0 {
+			s.Ports[k] = evs
+		}
 	}
-	return &s, nil
+	return s, nil
 }
 
+// Save replaces the stored events of every key present in s.
 func Save(s *Store) error {
-	p, err := historyPath()
+	dir, err := openDir()
 	if err != nil {
 		return err
 	}
-	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
-		return err
-	}
-	b, err := json.MarshalIndent(s, "", "  ")
+	unlock, err := lockDir(dir)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(p, b, 0o644)
+	defer unlock()
+	for k, evs := range s.Ports {
+		if err := writeSegmentLocked(dir, filepath.Join(dir, segmentName(k)), evs); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+// Record appends the report's ownership to history (skipping unchanged signatures).
 func Record(rep model.Report) error {
-	s, err := Load()
-	if err != nil {
-		return err
-	}
-	key := fmt.Sprintf("%d/%s", rep.Port, rep.Proto)
+	return appendEvent(EventFromReport(rep))
+}
 
+func EventFromReport(rep model.Report) OwnershipEvent {
 	ev := OwnershipEvent{
 		At:        rep.Generated,
 		Port:      rep.Port,
 		Proto:     rep.Proto,
 		Signature: rep.Signature(),
+		Host:      rep.Host.Hostname,
+		Kind:      KindReleased,
+	}
+	if len(rep.Listeners) > 0 || rep.Docker.Mapped {
+		ev.Kind = KindAcquired
 	}
 
 	if l, ok := rep.PrimaryListener(); ok {
@@ -125,23 +162,66 @@ func Record(rep model.Report) error {
 		ev.ContainerName = rep.Docker.ContainerName
 		ev.ComposeService = rep.Docker.ComposeService
 	}
+	return ev
+}
+
+// retention bounds how much history a segment keeps (zero values mean unlimited).
+type retention struct {
+	maxEntries int
+	maxAge     time.Duration
+}
 
-	events := append(s.Ports[key], ev)
+// currentRetention reads history.max_entries_per_port / history.max_age from the config
+// file; it is re-read on every compaction so edits apply without restarts.
+func currentRetention() retention {
+	cfg, _ := config.Load()
+	return retention{maxEntries: cfg.History.MaxEntriesPerPort, maxAge: cfg.History.MaxAgeDuration()}
+}
 
-	// Dedup consecutive identical signatures
-	if len(events) >= 2 {
-		last := events[len(events)-1]
-		prev := events[len(events)-2]
-		if last.Signature == prev.Signature {
-			events = events[:len(events)-1]
+// apply sorts events, drops consecutive duplicate signatures (per host) and trims to
+// maxAge and the newest maxEntries of each host.
+func (r retention) apply(evs []OwnershipEvent) []OwnershipEvent {
+	sort.SliceStable(evs, func(i, j int) bool { return evs[i].At.Before(evs[j].At) })
+	var cutoff time.Time
+	if r.maxAge > 0 {
+		cutoff = time.Now().Add(-r.maxAge)
+	}
+	lastSig := map[string]string{}
+	out := make([]OwnershipEvent, 0, len(evs))
+	for _, e := range evs {
+		if sig, ok := lastSig[e.Host]; ok && sig == e.Signature {
+			continue
+		}
+		lastSig[e.Host] = e.Signature
+		if e.At.Before(cutoff) {
+			continue
+		}
+		out = append(out, e)
+	}
+	if r.maxEntries <= 0 {
+		return out
+	}
+	// the limit is per host: importing a teammate's store must not evict this
+	// machine's own events
+	kept := map[string]int{}
+	keep := make([]bool, len(out))
+	for i := len(out) - 1; i >= 0; i-- {
+		h := out[i].Host
+		if out[i].IsLocal() {
+			h = ""
+		}
+		if kept[h] < r.maxEntries {
+			kept[h]++
+			keep[i] = true
 		}
 	}
-	if len(events) > maxEntriesPerPort {
-		events = events[len(events)-maxEntriesPerPort:]
+	trimmed := out[:0]
+	for i, e := range out {
+		if keep[i] {
+			trimmed = append(trimmed, e)
+		}
 	}
-
-	s.Ports[key] = events
-	return Save(s)
+	return trimmed
 }
 
 func (s *Store) ViewPortSince(port int, cutoff time.Time, detectPatterns bool) View {
@@ -149,7 +229,7 @@ func (s *Store) ViewPortSince(port int, cutoff time.Time, detectPatterns bool) V
 	var key string
 
 	for _, proto := range []string{"tcp", "udp"} {
-		k := fmt.Sprintf("%d/%s", port, proto)
+		k := keyOf(port, proto)
 		evs := s.Ports[k]
 		var filtered []OwnershipEvent
 		for _, e := range evs {
@@ -166,8 +246,14 @@ func (s *Store) ViewPortSince(port int, cutoff time.Time, detectPatterns bool) V
 	sort.Slice(all, func(i, j int) bool { return all[i].At.Before(all[j].At) })
 
 	view := View{Key: key, Events: all, Top: topOwners(all)}
+	if key != "" {
+		view.Stats = s.StatsForKey(key, cutoff, time.Now())
+	}
 	if detectPatterns {
 		view.Patterns = DetectPatterns(all)
+		for _, proto := range []string{"tcp", "udp"} {
+			view.Patterns = append(view.Patterns, DetectAnomalies(s.Ports[keyOf(port, proto)], cutoff, DefaultDetectOptions())...)
+		}
 	}
 	return view
 }
@@ -176,8 +262,7 @@ func (s *Store) RecentOwners(port int, proto string, n int) []OwnershipEvent {
 	if n <= 0 {
 		return nil
 	}
-	key := fmt.Sprintf("%d/%s", port, proto)
-	evs := s.Ports[key]
+	evs := s.Ports[keyOf(port, proto)]
 	if len(evs) == 0 {
 		return nil
 	}
@@ -297,6 +382,9 @@ func weekdayName(d int) string {
 func topOwners(events []OwnershipEvent) []TopOwner {
 	counts := map[string]int{}
 	for _, e := range events {
+		if e.IsReleased() {
+			continue
+		}
 		counts[OwnerLabel(e)]++
 	}
 	var tops []TopOwner
@@ -311,6 +399,9 @@ func topOwners(events []OwnershipEvent) []TopOwner {
 }
 
 func OwnerLabel(e OwnershipEvent) string {
+	if e.IsReleased() {
+		return "free"
+	}
 	if e.DockerMapped {
 		l := fmt.Sprintf("docker:%s", e.ContainerName)
 		if e.ComposeService != "" {
@@ -327,7 +418,7 @@ func OwnerLabel(e OwnershipEvent) string {
 	if e.PID > 0 {
 		return fmt.Sprintf("pid:%d", e.PID)
 	}
-	return "none"
+	return "unknown"
 }
 
 func RenderView(v View) string {
@@ -346,20 +437,54 @@ func RenderView(v View) string {
 		b.WriteString("\n")
 	}
 
+	if v.Stats != nil {
+		fmt.Fprintf(&b, "Ownership since %s\n", v.Stats.From.Format(time.RFC3339))
+		b.WriteString(RenderStats(*v.Stats, "- "))
+		b.WriteString("\n")
+	}
+
 	if len(v.Patterns) > 0 {
 		b.WriteString("Detected patterns\n")
 		for _, p := range v.Patterns {
-			fmt.Fprintf(&b, "- %s\n", p.Summary)
+			if p.Severity == "warn" {
+				fmt.Fprintf(&b, "- [%s] %s\n", p.Kind, p.Summary)
+			} else {
+				fmt.Fprintf(&b, "- %s\n", p.Summary)
+			}
 			if p.Details != "" {
 				fmt.Fprintf(&b, "  %s\n", p.Details)
 			}
+			for _, ev := range p.Evidence {
+				fmt.Fprintf(&b, "    %s\n", ev)
+			}
 		}
 		b.WriteString("\n")
 	}
 
 	b.WriteString("Events\n")
-	for _, e := range v.Events {
-		fmt.Fprintf(&b, "%s  %s\n", e.At.Format(time.RFC3339), OwnerLabel(e))
+	b.WriteString(RenderEvents(v.Events, false))
+	return b.String()
+}
+
+// RenderEvents lists events one per line (kind, owner, foreign host, command); withKey
+// adds the port/proto column for listings that span ports.
+func RenderEvents(evs []OwnershipEvent, withKey bool) string {
+	var b strings.Builder
+	local, _ := os.Hostname()
+	for _, e := range evs {
+		kind := KindAcquired
+		if e.IsReleased() {
+			kind = KindReleased
+		}
+		fmt.Fprintf(&b, "%s  ", e.At.Format(time.RFC3339))
+		if withKey {
+			fmt.Fprintf(&b, "%-10s ", keyOf(e.Port, e.Proto))
+		}
+		fmt.Fprintf(&b, "%-8s  %s", kind, OwnerLabel(e))
+		if e.Host != "" && e.Host != local {
+			fmt.Fprintf(&b, "  [%s]", e.Host)
+		}
+		b.WriteString("\n")
 		if e.Cmdline != "" {
 			fmt.Fprintf(&b, "  cmd: %s\n", e.Cmdline)
 		}
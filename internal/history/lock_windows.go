@@ -0,0 +1,68 @@
+//go:build windows
+
+package history
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/pidctl"
+)
+
+// lockDir emulates an exclusive lock with an O_EXCL lock file holding the owner's
+// pid. The lock is broken only once that process is gone, never merely because a
+// slow compaction has held it for a while.
+func lockDir(dir string) (func(), error) {
+	p := filepath.Join(dir, ".lock")
+	deadline := time.Now().Add(time.Minute)
+	for {
+		f, err := os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_, err = f.WriteString(strconv.Itoa(os.Getpid()))
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				_ = os.Remove(p)
+				return nil, err
+			}
+			return func() { _ = os.Remove(p) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if breakAbandoned(p) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting for history lock")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// breakAbandoned removes the lock file at p if the process it names has exited.
+// A file without a pid is either being written or was left by a crash before
+// the write; only the latter, detected by age, is removed.
+func breakAbandoned(p string) bool {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return errors.Is(err, os.ErrNotExist)
+	}
+	if pid, err := strconv.Atoi(string(bytes.TrimSpace(b))); err == nil && pid > 0 {
+		if pidctl.Alive(pid) {
+			return false
+		}
+	} else if st, err := os.Stat(p); err != nil || time.Since(st.ModTime()) < 5*time.Second {
+		return false
+	}
+	// re-read so a lock just retaken by another waiter is not removed
+	if again, err := os.ReadFile(p); err != nil || !bytes.Equal(again, b) {
+		return false
+	}
+	return os.Remove(p) == nil
+}
@@ -0,0 +1,392 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// On-disk layout (~/.portik/history/):
+//
+//	5432-tcp.jsonl   one append-only JSONL segment per port/proto (the index)
+//	.lock            advisory lock held by writers (append, compaction, migration)
+//	.5432-tcp.size   segment size after its last compaction
+//
+// Appends write one complete line per event under the lock. Readers take no lock: a
+// crash can at most leave a torn trailing line, which readers skip and the next append
+// terminates. Compaction rewrites a segment to a temp file and renames it into place;
+// the daemon compacts in the background, and an append compacts inline only once a
+// segment has grown to compactGrowth times its compacted size.
+
+const (
+	segmentExt = ".jsonl"
+	// compactThreshold is the smallest segment size that triggers an inline
+	// compaction on append; compactGrowth raises it for segments whose retained
+	// events are already larger, so they are not rewritten on every append.
+	compactThreshold = 256 << 10
+	compactGrowth    = 2
+	tailChunk        = 16 << 10
+)
+
+// Dir returns the history directory (~/.portik/history).
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".portik", "history"), nil
+}
+
+func legacyPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".portik", "history.json"), nil
+}
+
+func keyOf(port int, proto string) string {
+	return fmt.Sprintf("%d/%s", port, proto)
+}
+
+func segmentName(key string) string {
+	return strings.ReplaceAll(key, "/", "-") + segmentExt
+}
+
+// parseSegmentName turns "5432-tcp.jsonl" back into "5432/tcp".
+func parseSegmentName(name string) (string, bool) {
+	if !strings.HasSuffix(name, segmentExt) {
+		return "", false
+	}
+	base := strings.TrimSuffix(name, segmentExt)
+	i := strings.LastIndex(base, "-")
+	if i <= 0 {
+		return "", false
+	}
+	if _, err := strconv.Atoi(base[:i]); err != nil {
+		return "", false
+	}
+	return base[:i] + "/" + base[i+1:], true
+}
+
+// openDir makes sure the history directory exists and legacy data has been migrated.
+func openDir() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	if err := migrateLegacy(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// Keys lists every port/proto key with recorded history.
+func Keys() ([]string, error) {
+	dir, err := openDir()
+	if err != nil {
+		return nil, err
+	}
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, e := range ents {
+		if k, ok := parseSegmentName(e.Name()); ok && !e.IsDir() {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// ReadKey returns the events recorded for one port/proto key, oldest first.
+func ReadKey(key string) ([]OwnershipEvent, error) {
+	dir, err := openDir()
+	if err != nil {
+		return nil, err
+	}
+	return readSegment(filepath.Join(dir, segmentName(key)))
+}
+
+func readSegment(path string) ([]OwnershipEvent, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return decodeLines(f)
+}
+
+func decodeLines(r io.Reader) ([]OwnershipEvent, error) {
+	var out []OwnershipEvent
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 4<<20)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var ev OwnershipEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			continue // torn or foreign line; skip
+		}
+		out = append(out, ev)
+	}
+	return out, sc.Err()
+}
+
+// appendEvent appends ev to its segment unless it repeats the last recorded signature.
+func appendEvent(ev OwnershipEvent) error {
+	dir, err := openDir()
+	if err != nil {
+		return err
+	}
+	unlock, err := lockDir(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path := filepath.Join(dir, segmentName(keyOf(ev.Port, ev.Proto)))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	last, terminated, size, err := lastEvent(f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	if last != nil && last.Signature == ev.Signature && last.Host == ev.Host {
+		return f.Close()
+	}
+
+	b, err := json.Marshal(ev)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	line := make([]byte, 0, len(b)+2)
+	if !terminated {
+		line = append(line, '\n')
+	}
+	line = append(line, b...)
+	line = append(line, '\n')
+	if _, err := f.Write(line); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if size+int64(len(line)) > max(compactThreshold, compactGrowth*compactedSize(path)) {
+		return compactLocked(dir, path, currentRetention())
+	}
+	return nil
+}
+
+// lastEvent reads the final complete event of a segment without scanning the whole file.
+// terminated reports whether the file ends with a newline (or is empty).
+func lastEvent(f *os.File) (last *OwnershipEvent, terminated bool, size int64, err error) {
+	st, err := f.Stat()
+	if err != nil {
+		return nil, false, 0, err
+	}
+	size = st.Size()
+	if size == 0 {
+		return nil, true, 0, nil
+	}
+	for chunk := int64(tailChunk); ; chunk *= 4 {
+		off := size - chunk
+		if off < 0 {
+			off = 0
+		}
+		buf := make([]byte, size-off)
+		if _, err := f.ReadAt(buf, off); err != nil && !errors.Is(err, io.EOF) {
+			return nil, false, size, err
+		}
+		terminated = buf[len(buf)-1] == '\n'
+		lines := bytes.Split(bytes.TrimRight(buf, "\n"), []byte("\n"))
+		// the first line of a partial chunk may be cut; only trust it when off == 0
+		start := 0
+		if off > 0 {
+			start = 1
+		}
+		for i := len(lines) - 1; i >= start; i-- {
+			var ev OwnershipEvent
+			if json.Unmarshal(bytes.TrimSpace(lines[i]), &ev) == nil {
+				return &ev, terminated, size, nil
+			}
+		}
+		if off == 0 {
+			return nil, terminated, size, nil
+		}
+	}
+}
+
+// Compact applies retention to every segment and rewrites them compactly.
+func Compact() error {
+	dir, err := openDir()
+	if err != nil {
+		return err
+	}
+	unlock, err := lockDir(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	ret := currentRetention()
+	for _, e := range ents {
+		if _, ok := parseSegmentName(e.Name()); !ok {
+			continue
+		}
+		if err := compactLocked(dir, filepath.Join(dir, e.Name()), ret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func compactLocked(dir, path string, ret retention) error {
+	evs, err := readSegment(path)
+	if err != nil {
+		return err
+	}
+	return writeSegmentLocked(dir, path, ret.apply(evs))
+}
+
+// writeSegmentLocked atomically replaces a segment (temp file + fsync + rename) and
+// records its size. An empty event list removes the segment. Caller holds the lock.
+func writeSegmentLocked(dir, path string, evs []OwnershipEvent) error {
+	if len(evs) == 0 {
+		_ = os.Remove(sizePath(path))
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	tmp, err := os.CreateTemp(dir, ".compact-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, ev := range evs {
+		if err := enc.Encode(ev); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	syncDir(dir)
+	if st, err := os.Stat(path); err == nil {
+		// only a hint for appendEvent; a missing file means compactThreshold
+		_ = os.WriteFile(sizePath(path), []byte(strconv.FormatInt(st.Size(), 10)), 0o644)
+	}
+	return nil
+}
+
+// sizePath is where the size of segment path after its last compaction is kept.
+func sizePath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+strings.TrimSuffix(filepath.Base(path), segmentExt)+".size")
+}
+
+func compactedSize(path string) int64 {
+	b, err := os.ReadFile(sizePath(path))
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	return n
+}
+
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+}
+
+// migrateLegacy moves events from the old single-file store (~/.portik/history.json)
+// into segments, then renames the old file to history.json.migrated.
+func migrateLegacy(dir string) error {
+	legacy, err := legacyPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		return nil
+	}
+	unlock, err := lockDir(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	b, err := os.ReadFile(legacy)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // another process migrated it while we waited for the lock
+	}
+	if err != nil {
+		return err
+	}
+	var old struct {
+		Ports map[string][]OwnershipEvent `json:"ports"`
+	}
+	if err := json.Unmarshal(b, &old); err != nil {
+		return fmt.Errorf("migrate %s: %w", legacy, err)
+	}
+	for key, evs := range old.Ports {
+		path := filepath.Join(dir, segmentName(key))
+		cur, err := readSegment(path)
+		if err != nil {
+			return err
+		}
+		merged := append(append([]OwnershipEvent{}, evs...), cur...)
+		sort.SliceStable(merged, func(i, j int) bool { return merged[i].At.Before(merged[j].At) })
+		if err := writeSegmentLocked(dir, path, merged); err != nil {
+			return err
+		}
+	}
+	return os.Rename(legacy, legacy+".migrated")
+}
@@ -0,0 +1,196 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAppendConcurrentAndTornLine(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	base := time.Now().Add(-time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ev := OwnershipEvent{At: base.Add(time.Duration(i) * time.Second), Port: 5432, Proto: "tcp", PID: int32(100 + i), Signature: fmt.Sprintf("s%d", i)}
+			if err := appendEvent(ev); err != nil {
+				t.Errorf("append: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	evs, err := ReadKey("5432/tcp")
+	if err != nil || len(evs) != 20 {
+		t.Fatalf("expected 20 events, got %d (%v)", len(evs), err)
+	}
+
+	// simulate a crash mid-write, then keep appending
+	dir, _ := Dir()
+	f, err := os.OpenFile(filepath.Join(dir, "5432-tcp.jsonl"), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"at":"2026-01-01T00:00:00Z","port":54`)
+	_ = f.Close()
+
+	if err := appendEvent(OwnershipEvent{At: time.Now(), Port: 5432, Proto: "tcp", Signature: "after"}); err != nil {
+		t.Fatal(err)
+	}
+	// same signature as the last event is deduplicated
+	if err := appendEvent(OwnershipEvent{At: time.Now(), Port: 5432, Proto: "tcp", Signature: "after"}); err != nil {
+		t.Fatal(err)
+	}
+	evs, _ = ReadKey("5432/tcp")
+	if len(evs) != 21 || evs[len(evs)-1].Signature != "after" {
+		t.Fatalf("expected torn line skipped and one new event, got %d", len(evs))
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	legacy := Store{Version: 1, Ports: map[string][]OwnershipEvent{
+		"8080/tcp": {
+			{At: time.Now().Add(-2 * time.Hour), Port: 8080, Proto: "tcp", ProcName: "node", Signature: "a"},
+			{At: time.Now().Add(-time.Hour), Port: 8080, Proto: "tcp", Signature: "b"},
+		},
+	}}
+	b, _ := json.Marshal(legacy)
+	if err := os.MkdirAll(filepath.Join(home, ".portik"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".portik", "history.json"), b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Ports["8080/tcp"]) != 2 {
+		t.Fatalf("expected migrated events, got %#v", s.Ports)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".portik", "history.json.migrated")); err != nil {
+		t.Fatalf("legacy file should be renamed: %v", err)
+	}
+}
+
+func TestImportDedupAndPrune(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("PORTIK_CONFIG", filepath.Join(home, "missing.yaml"))
+
+	now := time.Now()
+	evs := []OwnershipEvent{
+		{At: now.Add(-100 * 24 * time.Hour), Port: 3000, Proto: "tcp", Signature: "old"},
+		{At: now.Add(-time.Hour), Port: 3000, Proto: "tcp", Signature: "new"},
+	}
+	res, err := Import(evs, "laptop")
+	if err != nil || res.Added != 2 {
+		t.Fatalf("import: %+v %v", res, err)
+	}
+	res, err = Import(evs, "laptop")
+	if err != nil || res.Added != 0 || res.Duplicate != 2 {
+		t.Fatalf("re-import should skip duplicates: %+v %v", res, err)
+	}
+	got, _ := ReadKey("3000/tcp")
+	if len(got) != 2 || got[0].Host != "laptop" {
+		t.Fatalf("expected host-tagged events, got %#v", got)
+	}
+
+	pr, err := Prune(now.Add(-90*24*time.Hour), true)
+	if err != nil || pr.Removed != 1 {
+		t.Fatalf("dry run: %+v %v", pr, err)
+	}
+	if got, _ := ReadKey("3000/tcp"); len(got) != 2 {
+		t.Fatalf("dry run must not write")
+	}
+	if _, err := Prune(now.Add(-90*24*time.Hour), false); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ReadKey("3000/tcp"); len(got) != 1 || got[0].Signature != "new" {
+		t.Fatalf("expected old event pruned, got %#v", got)
+	}
+}
+
+func TestImportRetentionPerHost(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cfg := filepath.Join(home, "config.yaml")
+	if err := os.WriteFile(cfg, []byte("history:\n  max_entries_per_port: 3\n  max_age: 30d\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PORTIK_CONFIG", cfg)
+
+	now := time.Now()
+	for i := range 3 {
+		ev := OwnershipEvent{At: now.Add(time.Duration(i-10) * time.Minute), Port: 5432, Proto: "tcp", Signature: fmt.Sprintf("local%d", i)}
+		if err := appendEvent(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var theirs []OwnershipEvent
+	for i := range 6 {
+		theirs = append(theirs, OwnershipEvent{At: now.Add(time.Duration(i-5) * time.Minute), Port: 5432, Proto: "tcp", Signature: fmt.Sprintf("theirs%d", i)})
+	}
+	theirs = append(theirs, OwnershipEvent{At: now.Add(-60 * 24 * time.Hour), Port: 5432, Proto: "tcp", Signature: "ancient"})
+
+	res, err := Import(theirs, "teammate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Added != 3 {
+		t.Fatalf("only the 3 events retention keeps count as added, got %+v", res)
+	}
+	got, _ := ReadKey("5432/tcp")
+	var local, imported int
+	for _, e := range got {
+		if e.Host == "teammate" {
+			imported++
+		} else {
+			local++
+		}
+	}
+	if local != 3 || imported != 3 {
+		t.Fatalf("max_entries_per_port applies per host: %d local, %d imported", local, imported)
+	}
+}
+
+func TestAppendLargeEventsCompactsOnGrowth(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cfg := filepath.Join(home, "config.yaml")
+	if err := os.WriteFile(cfg, []byte("history:\n  max_entries_per_port: 5\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PORTIK_CONFIG", cfg)
+
+	// five retained ~100 KiB events already exceed compactThreshold
+	long := strings.Repeat("x", 100<<10)
+	base := time.Now().Add(-time.Hour)
+	most := 0
+	for i := range 10 {
+		ev := OwnershipEvent{At: base.Add(time.Duration(i) * time.Second), Port: 8080, Proto: "tcp", Cmdline: long, Signature: fmt.Sprintf("s%d", i)}
+		if err := appendEvent(ev); err != nil {
+			t.Fatal(err)
+		}
+		evs, _ := ReadKey("8080/tcp")
+		most = max(most, len(evs))
+	}
+	if most <= 5 {
+		t.Fatal("a segment over compactThreshold must not be compacted on every append")
+	}
+	if evs, _ := ReadKey("8080/tcp"); len(evs) >= 10 {
+		t.Fatalf("the segment should have been compacted once it doubled, got %d events", len(evs))
+	}
+}
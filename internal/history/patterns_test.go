@@ -1,6 +1,7 @@
 package history
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -29,3 +30,54 @@ func TestDetectPatternsHour(t *testing.T) {
 		t.Fatalf("expected hour patterns, got %#v", p)
 	}
 }
+
+func TestDetectAnomalies(t *testing.T) {
+	base := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	at := func(min int) time.Time { return base.Add(time.Duration(min) * time.Minute) }
+	pg := func(min int, pid int32) OwnershipEvent {
+		return OwnershipEvent{At: at(min), Kind: KindAcquired, PID: pid, ProcName: "postgres", Cmdline: "postgres -D /data"}
+	}
+	free := func(min int) OwnershipEvent { return OwnershipEvent{At: at(min), Kind: KindReleased} }
+
+	evs := []OwnershipEvent{
+		pg(0, 10), free(60), pg(120, 11), free(180),
+		{At: at(200), Kind: KindAcquired, PID: 50, ProcName: "node", Cmdline: "node server.js"},
+		free(230), pg(240, 12),
+		// crash loop: postgres keeps dying and coming back
+		free(241), pg(242, 13), free(243), pg(244, 14), free(245), pg(246, 15),
+	}
+	got := map[string]Pattern{}
+	for _, p := range DetectAnomalies(evs, time.Time{}, DefaultDetectOptions()) {
+		got[p.Kind] = p
+	}
+	for _, kind := range []string{PatternFlapping, PatternCrashLoop, PatternSquatter, PatternFirstSeen} {
+		if _, ok := got[kind]; !ok {
+			t.Fatalf("missing %s pattern, got %#v", kind, got)
+		}
+	}
+	if sq := got[PatternSquatter]; !strings.Contains(sq.Summary, "node") || len(sq.Evidence) != 3 {
+		t.Fatalf("unexpected squatter: %#v", sq)
+	}
+	if cl := got[PatternCrashLoop]; !cl.At.Equal(at(246)) {
+		t.Fatalf("crash loop should end at the last restart: %#v", cl)
+	}
+
+	// events imported from another host do not interleave with local ones
+	imported := append([]OwnershipEvent{}, evs[:4]...)
+	for i := range 6 {
+		e := OwnershipEvent{At: at(10 + 20*i), Kind: KindAcquired, PID: int32(90 + i), ProcName: "java", Host: "teammate-laptop"}
+		imported = append(imported, e)
+	}
+	for _, p := range DetectAnomalies(imported, time.Time{}, DefaultDetectOptions()) {
+		if p.Kind == PatternFlapping || !strings.Contains(p.Summary, "teammate-laptop") && p.Kind == PatternFirstSeen {
+			t.Fatalf("imported events produced a local pattern: %#v", p)
+		}
+	}
+
+	// since filters by the newest evidence
+	for _, p := range DetectAnomalies(evs, at(245), DefaultDetectOptions()) {
+		if p.Kind == PatternFirstSeen || p.Kind == PatternSquatter {
+			t.Fatalf("%s is older than since", p.Kind)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package history
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query selects events across every recorded port. Empty fields match everything;
+// string fields compare case-insensitively.
+type Query struct {
+	Owner   string         // process name or container name
+	User    string         // process user
+	Cmd     *regexp.Regexp // matched against the command line
+	Service string         // compose service or container name
+	Proto   string         // tcp|udp
+	Since   time.Time
+	Until   time.Time // zero = now
+}
+
+// ownerFilters reports whether the query filters on who held the port; released events
+// carry no owner and are then left out.
+func (q Query) ownerFilters() bool {
+	return q.Owner != "" || q.User != "" || q.Cmd != nil || q.Service != ""
+}
+
+func (q Query) Match(e OwnershipEvent) bool {
+	if e.At.Before(q.Since) || !q.Until.IsZero() && e.At.After(q.Until) {
+		return false
+	}
+	if q.Proto != "" && !strings.EqualFold(e.Proto, q.Proto) {
+		return false
+	}
+	if q.ownerFilters() && e.IsReleased() {
+		return false
+	}
+	if q.Owner != "" && !strings.EqualFold(e.ProcName, q.Owner) && !strings.EqualFold(e.ContainerName, q.Owner) {
+		return false
+	}
+	if q.User != "" && !strings.EqualFold(e.User, q.User) {
+		return false
+	}
+	if q.Cmd != nil && !q.Cmd.MatchString(e.Cmdline) {
+		return false
+	}
+	if q.Service != "" && !strings.EqualFold(e.ComposeService, q.Service) && !strings.EqualFold(e.ContainerName, q.Service) {
+		return false
+	}
+	return true
+}
+
+// QueryRow summarizes one owner on one port/proto within the query window.
+type QueryRow struct {
+	Key     string    `json:"key"`
+	Port    int       `json:"port"`
+	Proto   string    `json:"proto"`
+	Owner   string    `json:"owner"`
+	User    string    `json:"user,omitempty"`
+	Cmdline string    `json:"cmdline,omitempty"` // most recent
+	Events  int       `json:"events"`            // events inside the window
+	First   time.Time `json:"first"`             // first event; before the window if it already held the port
+	Last    time.Time `json:"last"`
+	HeldSec int64     `json:"held_sec"`
+}
+
+// QueryResult holds the matching events and their per-port/owner summary.
+type QueryResult struct {
+	Since  time.Time        `json:"since"`
+	Until  time.Time        `json:"until"`
+	Rows   []QueryRow       `json:"rows"`
+	Events []OwnershipEvent `json:"events,omitempty"`
+}
+
+// Query runs q over every key in the store.
+func (s *Store) Query(q Query) QueryResult {
+	until := q.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+	res := QueryResult{Since: q.Since, Until: until}
+
+	keys := make([]string, 0, len(s.Ports))
+	for k := range s.Ports {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+
+	// an owner that acquired the port before the window and still held it at its
+	// start has no event inside it, but did hold the port
+	anyTime := q
+	anyTime.Since, anyTime.Until = time.Time{}, time.Time{}
+
+	for _, k := range keys {
+		var st *Stats
+		rows := map[string]*QueryRow{}
+		var order []string
+		row := func(e OwnershipEvent) *QueryRow {
+			lbl := OwnerLabel(e)
+			r := rows[lbl]
+			if r == nil {
+				if st == nil {
+					st = s.StatsForKey(k, q.Since, until)
+				}
+				r = &QueryRow{Key: k, Port: e.Port, Proto: e.Proto, Owner: lbl, First: e.At}
+				if st != nil {
+					for _, o := range st.Owners {
+						if o.Owner == lbl {
+							r.HeldSec = o.TotalSec
+						}
+					}
+				}
+				rows[lbl] = r
+				order = append(order, lbl)
+			}
+			r.Last = e.At
+			r.User = e.User
+			r.Cmdline = e.Cmdline
+			return r
+		}
+		if prior, ok := s.priorEvent(k, q.Since); ok && !prior.IsReleased() && anyTime.Match(prior) {
+			row(prior)
+		}
+		for _, e := range s.Ports[k] {
+			if !q.Match(e) {
+				continue
+			}
+			res.Events = append(res.Events, e)
+			if e.IsReleased() {
+				continue
+			}
+			row(e).Events++
+		}
+		for _, lbl := range order {
+			res.Rows = append(res.Rows, *rows[lbl])
+		}
+	}
+	sort.SliceStable(res.Events, func(i, j int) bool { return res.Events[i].At.Before(res.Events[j].At) })
+	return res
+}
+
+// priorEvent is this machine's last event for key before t: the state of the
+// port when a window starting at t opens.
+func (s *Store) priorEvent(key string, t time.Time) (OwnershipEvent, bool) {
+	var prior OwnershipEvent
+	found := false
+	for _, e := range localEvents(s.Ports[key]) {
+		if !e.At.Before(t) {
+			break
+		}
+		prior, found = e, true
+	}
+	return prior, found
+}
+
+// RenderQuery renders the per-port/owner summary as a table.
+func RenderQuery(res QueryResult) string {
+	var b strings.Builder
+	if len(res.Rows) == 0 {
+		b.WriteString("No matching history in the selected window.\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "History %s → %s\n\n", res.Since.Local().Format("2006-01-02 15:04"), res.Until.Local().Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "%-10s %-28s %6s  %-16s %-16s %s\n", "PORT", "OWNER", "EVENTS", "FIRST", "LAST", "HELD")
+	for _, r := range res.Rows {
+		fmt.Fprintf(&b, "%-10s %-28s %6d  %-16s %-16s %s\n",
+			r.Key, truncRunes(r.Owner, 28), r.Events,
+			r.First.Local().Format("01-02 15:04:05"), r.Last.Local().Format("01-02 15:04:05"),
+			FormatDuration(time.Duration(r.HeldSec)*time.Second))
+	}
+	ports := map[string]bool{}
+	for _, r := range res.Rows {
+		ports[r.Key] = true
+	}
+	fmt.Fprintf(&b, "\n%d owners across %d ports\n", len(res.Rows), len(ports))
+	return b.String()
+}
+
+// WriteQueryCSV writes the summary rows as CSV.
+func WriteQueryCSV(w io.Writer, res QueryResult) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"port", "proto", "owner", "user", "events", "first", "last", "held_sec", "cmdline"}); err != nil {
+		return err
+	}
+	for _, r := range res.Rows {
+		rec := []string{
+			strconv.Itoa(r.Port), r.Proto, r.Owner, r.User, strconv.Itoa(r.Events),
+			r.First.Format(time.RFC3339), r.Last.Format(time.RFC3339), strconv.FormatInt(r.HeldSec, 10), r.Cmdline,
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,80 @@
+package history
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestQuery(t *testing.T) {
+	now := time.Now()
+	s := &Store{Ports: map[string][]OwnershipEvent{
+		"3000/tcp": {
+			{At: now.Add(-3 * time.Hour), Kind: KindAcquired, Port: 3000, Proto: "tcp", ProcName: "node", User: "me", Cmdline: "node dev.js"},
+			{At: now.Add(-2 * time.Hour), Kind: KindReleased, Port: 3000, Proto: "tcp"},
+		},
+		"8080/tcp": {
+			{At: now.Add(-time.Hour), Kind: KindAcquired, Port: 8080, Proto: "tcp", DockerMapped: true, ContainerName: "shop-api-1", ComposeService: "api"},
+		},
+		"5353/udp": {
+			{At: now.Add(-time.Hour), Kind: KindAcquired, Port: 5353, Proto: "udp", ProcName: "node", User: "me", Cmdline: "node mdns.js"},
+		},
+	}}
+	since := now.Add(-24 * time.Hour)
+
+	res := s.Query(Query{Owner: "NODE", Since: since})
+	if len(res.Rows) != 2 || res.Rows[0].Key != "3000/tcp" || res.Rows[0].HeldSec != 3600 {
+		t.Fatalf("owner query: %+v", res.Rows)
+	}
+	if len(res.Events) != 2 {
+		t.Fatalf("released events must not match owner filters: %+v", res.Events)
+	}
+	if res := s.Query(Query{Owner: "node", Proto: "udp", Cmd: regexp.MustCompile(`mdns`), Since: since}); len(res.Rows) != 1 || res.Rows[0].Port != 5353 {
+		t.Fatalf("proto/cmd query: %+v", res.Rows)
+	}
+	if res := s.Query(Query{Service: "api", Since: since}); len(res.Rows) != 1 || res.Rows[0].Port != 8080 {
+		t.Fatalf("service query: %+v", res.Rows)
+	}
+	if res := s.Query(Query{Since: since, Until: now.Add(-150 * time.Minute)}); len(res.Events) != 1 {
+		t.Fatalf("until: %+v", res.Events)
+	}
+
+	// an owner that acquired the port before the window and held it throughout
+	s.Ports["9000/tcp"] = []OwnershipEvent{
+		{At: now.Add(-10 * 24 * time.Hour), Kind: KindAcquired, Port: 9000, Proto: "tcp", ProcName: "node", User: "me"},
+	}
+	s.Ports["9001/tcp"] = []OwnershipEvent{
+		{At: now.Add(-10 * 24 * time.Hour), Kind: KindAcquired, Port: 9001, Proto: "tcp", ProcName: "node"},
+		{At: now.Add(-9 * 24 * time.Hour), Kind: KindReleased, Port: 9001, Proto: "tcp"},
+	}
+	res = s.Query(Query{Owner: "node", Since: since})
+	if len(res.Rows) != 3 || res.Rows[2].Key != "9000/tcp" || res.Rows[2].Events != 0 || res.Rows[2].HeldSec != 24*3600 {
+		t.Fatalf("long-lived holder: %+v", res.Rows)
+	}
+}
+
+func TestSnapshotAt(t *testing.T) {
+	base := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	s := &Store{Ports: map[string][]OwnershipEvent{
+		"8080/tcp": {
+			{At: base, Kind: KindAcquired, Port: 8080, Proto: "tcp", PID: 7, ProcName: "java", Cmdline: "java -jar app.jar"},
+			{At: base.Add(10 * time.Minute), Kind: KindAcquired, Port: 8080, Proto: "tcp", PID: 99, ProcName: "node", Host: "teammate-laptop"},
+			{At: base.Add(30 * time.Minute), Kind: KindReleased, Port: 8080, Proto: "tcp"},
+		},
+	}}
+	// the imported event from another host is not this machine's past owner
+	snap := s.SnapshotAt("8080/tcp", base.Add(12*time.Minute))
+	if snap.Sample == nil || snap.Sample.PID != 7 || snap.Next == nil || snap.StaleFor() != 12*time.Minute {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+	rep := ReportFromEvent(*snap.Sample)
+	if l, ok := rep.PrimaryListener(); !ok || l.Cmdline != "java -jar app.jar" {
+		t.Fatalf("report should carry the recorded listener: %+v", rep)
+	}
+	if rep := ReportFromEvent(*s.SnapshotAt("8080/tcp", base.Add(time.Hour)).Sample); len(rep.Listeners) != 0 {
+		t.Fatalf("released sample should have no listeners: %+v", rep)
+	}
+	if snap := s.SnapshotAt("8080/tcp", base.Add(-time.Minute)); snap.Sample != nil || snap.Next == nil {
+		t.Fatalf("before first event: %+v", snap)
+	}
+}
@@ -0,0 +1,247 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Event kinds. Events recorded before kinds existed have an empty Kind; IsReleased
+// falls back to "no owner information" for those.
+const (
+	KindAcquired = "acquired"
+	KindReleased = "released"
+)
+
+// IsReleased reports whether the event records the port becoming free.
+func (e OwnershipEvent) IsReleased() bool {
+	if e.Kind != "" {
+		return e.Kind == KindReleased
+	}
+	return !e.DockerMapped && e.PID == 0 && e.ProcName == "" && e.Cmdline == ""
+}
+
+// Tenure is one continuous stretch during which a single owner held the port.
+type Tenure struct {
+	Owner   string    `json:"owner"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"` // window end when ongoing
+	Seconds int64     `json:"seconds"`
+	Ongoing bool      `json:"ongoing,omitempty"`
+}
+
+// OwnerTenure aggregates the tenures of one owner.
+type OwnerTenure struct {
+	Owner      string `json:"owner"`
+	Tenures    int    `json:"tenures"`
+	TotalSec   int64  `json:"total_sec"`
+	LongestSec int64  `json:"longest_sec"`
+	Current    bool   `json:"current,omitempty"`
+}
+
+// Stats summarizes ownership over a window. Time before the first known event in the
+// window is counted as unknown. An owner change is a different owner acquiring the port
+// than the one that last held it (a free gap in between does not make the same owner new).
+type Stats struct {
+	From                  time.Time     `json:"from"`
+	To                    time.Time     `json:"to"`
+	OccupiedSec           int64         `json:"occupied_sec"`
+	FreeSec               int64         `json:"free_sec"`
+	UnknownSec            int64         `json:"unknown_sec,omitempty"`
+	OwnerChanges          int           `json:"owner_changes"`
+	MeanBetweenChangesSec int64         `json:"mean_between_changes_sec,omitempty"`
+	Owners                []OwnerTenure `json:"owners,omitempty"`
+	Tenures               []Tenure      `json:"tenures,omitempty"`
+
+	free []Span // released stretches, for timelines
+}
+
+// ComputeStats walks evs (oldest first) over [from, to]. prior is the last event before
+// from, if known; it supplies the state at the start of the window.
+func ComputeStats(evs []OwnershipEvent, prior *OwnershipEvent, from, to time.Time) Stats {
+	st := Stats{From: from, To: to}
+	if !to.After(from) {
+		return st
+	}
+
+	type state struct {
+		known bool
+		free  bool
+		owner string
+	}
+	stateOf := func(e OwnershipEvent) state {
+		if e.IsReleased() {
+			return state{known: true, free: true}
+		}
+		return state{known: true, owner: OwnerLabel(e)}
+	}
+
+	var cur state
+	var lastOwner string
+	if prior != nil {
+		cur = stateOf(*prior)
+		lastOwner = cur.owner
+	}
+	since := from
+
+	closeState := func(at time.Time, ongoing bool) {
+		d := int64(at.Sub(since) / time.Second)
+		if d < 0 {
+			d = 0
+		}
+		switch {
+		case !cur.known:
+			st.UnknownSec += d
+		case cur.free:
+			st.FreeSec += d
+			if at.After(since) {
+				st.free = append(st.free, Span{From: since, To: at})
+			}
+		default:
+			st.OccupiedSec += d
+			st.Tenures = append(st.Tenures, Tenure{Owner: cur.owner, From: since, To: at, Seconds: d, Ongoing: ongoing})
+		}
+	}
+
+	var changes []time.Time
+	for _, e := range evs {
+		if e.At.After(to) {
+			break
+		}
+		at := e.At
+		if at.Before(from) {
+			at = from
+		}
+		next := stateOf(e)
+		if next == cur {
+			continue // same owner (e.g. restarted with a new PID) or still free
+		}
+		closeState(at, false)
+		if !next.free {
+			if lastOwner != "" && next.owner != lastOwner {
+				changes = append(changes, at)
+			}
+			lastOwner = next.owner
+		}
+		cur, since = next, at
+	}
+	closeState(to, cur.known && !cur.free)
+
+	st.OwnerChanges = len(changes)
+	if len(changes) >= 2 {
+		st.MeanBetweenChangesSec = int64(changes[len(changes)-1].Sub(changes[0])/time.Second) / int64(len(changes)-1)
+	}
+
+	byOwner := map[string]*OwnerTenure{}
+	for _, t := range st.Tenures {
+		o := byOwner[t.Owner]
+		if o == nil {
+			o = &OwnerTenure{Owner: t.Owner}
+			byOwner[t.Owner] = o
+		}
+		o.Tenures++
+		o.TotalSec += t.Seconds
+		if t.Seconds > o.LongestSec {
+			o.LongestSec = t.Seconds
+		}
+		if t.Ongoing {
+			o.Current = true
+		}
+	}
+	for _, o := range byOwner {
+		st.Owners = append(st.Owners, *o)
+	}
+	sort.Slice(st.Owners, func(i, j int) bool {
+		if st.Owners[i].TotalSec != st.Owners[j].TotalSec {
+			return st.Owners[i].TotalSec > st.Owners[j].TotalSec
+		}
+		return st.Owners[i].Owner < st.Owners[j].Owner
+	})
+	return st
+}
+
+// StatsForKey computes Stats for one port/proto key over [from, to] from this machine's
+// events (and those recorded before events carried a host); imported histories from
+// other machines would otherwise interleave.
+func (s *Store) StatsForKey(key string, from, to time.Time) *Stats {
+	evs := localEvents(s.Ports[key])
+	if len(evs) == 0 {
+		return nil
+	}
+	var prior *OwnershipEvent
+	var in []OwnershipEvent
+	for i := range evs {
+		e := evs[i]
+		if e.At.Before(from) {
+			prior = &evs[i]
+			continue
+		}
+		in = append(in, e)
+	}
+	st := ComputeStats(in, prior, from, to)
+	return &st
+}
+
+// FormatDuration renders a duration compactly: 3d4h, 2h5m, 4m10s, 12s.
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d >= 24*time.Hour:
+		days := d / (24 * time.Hour)
+		h := (d % (24 * time.Hour)) / time.Hour
+		if h == 0 {
+			return fmt.Sprintf("%dd", days)
+		}
+		return fmt.Sprintf("%dd%dh", days, h)
+	case d >= time.Hour:
+		m := (d % time.Hour) / time.Minute
+		if m == 0 {
+			return fmt.Sprintf("%dh", d/time.Hour)
+		}
+		return fmt.Sprintf("%dh%dm", d/time.Hour, m)
+	case d >= time.Minute:
+		s := (d % time.Minute) / time.Second
+		if s == 0 {
+			return fmt.Sprintf("%dm", d/time.Minute)
+		}
+		return fmt.Sprintf("%dm%ds", d/time.Minute, s)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
+
+// RenderStats renders the ownership summary and per-owner tenure as indented lines.
+func RenderStats(st Stats, indent string) string {
+	var b strings.Builder
+	total := st.OccupiedSec + st.FreeSec + st.UnknownSec
+	pct := func(v int64) int {
+		if total == 0 {
+			return 0
+		}
+		return int(v * 100 / total)
+	}
+	sec := func(v int64) string { return FormatDuration(time.Duration(v) * time.Second) }
+
+	fmt.Fprintf(&b, "%soccupied %s (%d%%), free %s (%d%%)", indent, sec(st.OccupiedSec), pct(st.OccupiedSec), sec(st.FreeSec), pct(st.FreeSec))
+	if st.UnknownSec > 0 {
+		fmt.Fprintf(&b, ", unknown %s", sec(st.UnknownSec))
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "%s%d owner changes", indent, st.OwnerChanges)
+	if st.MeanBetweenChangesSec > 0 {
+		fmt.Fprintf(&b, ", mean %s between changes", sec(st.MeanBetweenChangesSec))
+	}
+	b.WriteString("\n")
+	for _, o := range st.Owners {
+		fmt.Fprintf(&b, "%s%s: %s", indent, o.Owner, sec(o.TotalSec))
+		if o.Tenures > 1 {
+			fmt.Fprintf(&b, " over %d tenures (longest %s)", o.Tenures, sec(o.LongestSec))
+		}
+		if o.Current {
+			b.WriteString(", holding now")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
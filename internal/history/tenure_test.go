@@ -0,0 +1,71 @@
+package history
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestComputeStats(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(10 * time.Hour)
+	at := func(h int) time.Time { return from.Add(time.Duration(h) * time.Hour) }
+
+	prior := OwnershipEvent{At: from.Add(-time.Hour), Kind: KindAcquired, PID: 1, ProcName: "postgres"}
+	evs := []OwnershipEvent{
+		{At: at(2), Kind: KindReleased},
+		{At: at(3), Kind: KindAcquired, PID: 2, ProcName: "postgres"}, // same owner again: not a change
+		{At: at(5), Kind: KindAcquired, PID: 3, ProcName: "node"},
+		{At: at(6), PID: 0}, // legacy free event without kind
+		{At: at(8), Kind: KindAcquired, PID: 4, ProcName: "python"},
+	}
+	st := ComputeStats(evs, &prior, from, to)
+
+	if st.OccupiedSec != 7*3600 || st.FreeSec != 3*3600 || st.UnknownSec != 0 {
+		t.Fatalf("occupied/free: %+v", st)
+	}
+	if st.OwnerChanges != 2 || st.MeanBetweenChangesSec != 3*3600 {
+		t.Fatalf("changes: %d mean %d", st.OwnerChanges, st.MeanBetweenChangesSec)
+	}
+	if len(st.Owners) != 3 || st.Owners[0].Owner != "postgres" || st.Owners[0].Tenures != 2 || st.Owners[0].TotalSec != 4*3600 {
+		t.Fatalf("owners: %+v", st.Owners)
+	}
+	last := st.Tenures[len(st.Tenures)-1]
+	if last.Owner != "python" || !last.Ongoing || last.Seconds != 2*3600 {
+		t.Fatalf("last tenure: %+v", last)
+	}
+
+	// without a prior event the start of the window is unknown
+	st = ComputeStats(evs, nil, from, to)
+	if st.UnknownSec != 2*3600 || st.OwnerChanges != 2 {
+		t.Fatalf("no prior: %+v", st)
+	}
+	// a newer event imported from another host does not switch the stats to that host
+	s := &Store{Ports: map[string][]OwnershipEvent{"5432/tcp": append(append([]OwnershipEvent{prior}, evs...),
+		OwnershipEvent{At: at(9), Kind: KindAcquired, PID: 9, ProcName: "java", Host: "teammate-laptop"})}}
+	if st := s.StatsForKey("5432/tcp", from, to); st == nil || st.OwnerChanges != 2 || st.Owners[0].Owner != "postgres" {
+		t.Fatalf("stats should describe this machine: %+v", st)
+	}
+}
+
+func TestBuildTimeline(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(4 * time.Hour)
+	s := &Store{Ports: map[string][]OwnershipEvent{
+		"3000/tcp": {
+			{At: from, Kind: KindAcquired, ProcName: "node"},
+			{At: from.Add(time.Hour), Kind: KindReleased},
+			{At: from.Add(2 * time.Hour), Kind: KindAcquired, ProcName: "jest"},
+		},
+	}}
+	tl := s.BuildTimeline([]string{"3000/tcp", "3001/tcp"}, from, to)
+	if len(tl.Ports) != 1 || len(tl.Ports[0].Lanes) != 3 {
+		t.Fatalf("unexpected timeline: %+v", tl)
+	}
+	out := RenderTimeline(tl, 48)
+	for _, want := range []string{"3000/tcp", "node", "jest", "free", "█", "░"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+}
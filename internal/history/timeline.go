@@ -0,0 +1,174 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Timeline is the per-owner occupancy of one or more ports across a window.
+type Timeline struct {
+	From  time.Time      `json:"from"`
+	To    time.Time      `json:"to"`
+	Ports []TimelinePort `json:"ports"`
+}
+
+type TimelinePort struct {
+	Key   string         `json:"key"`
+	Lanes []TimelineLane `json:"lanes"`
+}
+
+// TimelineLane is one owner's tenures on a port; the "free" lane holds released stretches.
+type TimelineLane struct {
+	Owner string `json:"owner"`
+	Spans []Span `json:"spans"`
+}
+
+type Span struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+const freeLane = "free"
+
+// BuildTimeline collects lanes for the given keys over [from, to]. Keys without any
+// known state in the window are left out.
+func (s *Store) BuildTimeline(keys []string, from, to time.Time) Timeline {
+	tl := Timeline{From: from, To: to}
+	for _, k := range keys {
+		st := s.StatsForKey(k, from, to)
+		if st == nil || st.OccupiedSec+st.FreeSec == 0 && len(st.Tenures) == 0 {
+			continue
+		}
+		p := TimelinePort{Key: k}
+		idx := map[string]int{}
+		for _, t := range st.Tenures {
+			i, ok := idx[t.Owner]
+			if !ok {
+				i = len(p.Lanes)
+				idx[t.Owner] = i
+				p.Lanes = append(p.Lanes, TimelineLane{Owner: t.Owner})
+			}
+			p.Lanes[i].Spans = append(p.Lanes[i].Spans, Span{From: t.From, To: t.To})
+		}
+		if len(st.free) > 0 {
+			p.Lanes = append(p.Lanes, TimelineLane{Owner: freeLane, Spans: st.free})
+		}
+		tl.Ports = append(tl.Ports, p)
+	}
+	sort.SliceStable(tl.Ports, func(i, j int) bool { return keyLess(tl.Ports[i].Key, tl.Ports[j].Key) })
+	return tl
+}
+
+func keyLess(a, b string) bool {
+	var pa, pb int
+	var qa, qb string
+	fmt.Sscanf(a, "%d/%s", &pa, &qa)
+	fmt.Sscanf(b, "%d/%s", &pb, &qb)
+	if pa != pb {
+		return pa < pb
+	}
+	return qa < qb
+}
+
+// RenderTimeline draws the timeline as a terminal Gantt chart: one lane per owner and
+// port, `width` columns of bars, and a time axis at the bottom.
+func RenderTimeline(tl Timeline, width int) string {
+	var b strings.Builder
+	if len(tl.Ports) == 0 {
+		b.WriteString("No history for these ports in the selected window.\n")
+		return b.String()
+	}
+
+	labelW := 8
+	for _, p := range tl.Ports {
+		for _, l := range p.Lanes {
+			if n := len([]rune(l.Owner)) + 3; n > labelW {
+				labelW = n
+			}
+		}
+	}
+	if labelW > 30 {
+		labelW = 30
+	}
+	cols := width - labelW - 2
+	if cols < 20 {
+		cols = 20
+	}
+	span := tl.To.Sub(tl.From)
+	if span <= 0 {
+		span = time.Second
+	}
+
+	fmt.Fprintf(&b, "Timeline %s → %s (%s, 1 col ≈ %s)\n\n",
+		tl.From.Local().Format("2006-01-02 15:04"), tl.To.Local().Format("2006-01-02 15:04"),
+		FormatDuration(span), FormatDuration(span/time.Duration(cols)))
+
+	for _, p := range tl.Ports {
+		b.WriteString(p.Key)
+		b.WriteString("\n")
+		for _, l := range p.Lanes {
+			fill := '█'
+			if l.Owner == freeLane {
+				fill = '░'
+			}
+			row := []rune(strings.Repeat(" ", cols))
+			for _, s := range l.Spans {
+				c0 := int(float64(s.From.Sub(tl.From)) / float64(span) * float64(cols))
+				c1 := int(float64(s.To.Sub(tl.From))/float64(span)*float64(cols) + 0.999)
+				if c1 <= c0 {
+					c1 = c0 + 1
+				}
+				for c := clampCol(c0, cols); c < clampCol(c1, cols+1) && c < cols; c++ {
+					row[c] = fill
+				}
+			}
+			fmt.Fprintf(&b, "  %-*s│%s│\n", labelW-2, truncRunes(l.Owner, labelW-3), string(row))
+		}
+	}
+
+	axis, labels := timeAxis(tl.From, span, cols)
+	fmt.Fprintf(&b, "%s└%s┘\n", strings.Repeat(" ", labelW), axis)
+	fmt.Fprintf(&b, "%s %s\n", strings.Repeat(" ", labelW), strings.TrimRight(labels, " "))
+	return b.String()
+}
+
+// timeAxis returns the axis line with tick marks and the label line beneath it.
+func timeAxis(from time.Time, span time.Duration, cols int) (string, string) {
+	layout := "15:04"
+	if span > 36*time.Hour {
+		layout = "01-02"
+	}
+	labelLen := len(layout)
+	step := labelLen + 6
+	axis := []rune(strings.Repeat("─", cols))
+	labels := []rune(strings.Repeat(" ", cols+labelLen))
+	for c := 0; c < cols; c += step {
+		axis[c] = '┬'
+		at := from.Add(time.Duration(float64(span) * float64(c) / float64(cols)))
+		copy(labels[c:], []rune(at.Local().Format(layout)))
+	}
+	return string(axis), string(labels)
+}
+
+func clampCol(c, hi int) int {
+	if c < 0 {
+		return 0
+	}
+	if c > hi {
+		return hi
+	}
+	return c
+}
+
+func truncRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 1 {
+		return string(r[:n])
+	}
+	return string(r[:n-1]) + "…"
+}
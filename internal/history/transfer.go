@@ -0,0 +1,276 @@
+package history
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// EventsSince returns events at or after since (zero = everything), oldest first.
+// With no ports, every recorded port/proto is read.
+func EventsSince(since time.Time, ports ...int) ([]OwnershipEvent, error) {
+	var keys []string
+	if len(ports) == 0 {
+		k, err := Keys()
+		if err != nil {
+			return nil, err
+		}
+		keys = k
+	} else {
+		for _, p := range ports {
+			keys = append(keys, keyOf(p, "tcp"), keyOf(p, "udp"))
+		}
+	}
+	var out []OwnershipEvent
+	for _, k := range keys {
+		evs, err := ReadKey(k)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range evs {
+			if !e.At.Before(since) {
+				out = append(out, e)
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].At.Before(out[j].At) })
+	return out, nil
+}
+
+var csvHeader = []string{
+	"at", "kind", "host", "port", "proto", "pid", "proc_name", "user", "cmdline",
+	"docker_mapped", "container_id", "container_name", "compose_service", "signature",
+}
+
+// Export writes events as jsonl (one OwnershipEvent per line, re-importable) or csv.
+func Export(w io.Writer, format string, evs []OwnershipEvent) error {
+	switch format {
+	case "jsonl", "":
+		enc := json.NewEncoder(w)
+		for _, e := range evs {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, e := range evs {
+			kind := KindAcquired
+			if e.IsReleased() {
+				kind = KindReleased
+			}
+			rec := []string{
+				e.At.Format(time.RFC3339Nano), kind, e.Host, strconv.Itoa(e.Port), e.Proto,
+				strconv.Itoa(int(e.PID)), e.ProcName, e.User, e.Cmdline,
+				strconv.FormatBool(e.DockerMapped), e.ContainerID, e.ContainerName, e.ComposeService, e.Signature,
+			}
+			if err := cw.Write(rec); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unsupported export format %q (csv|jsonl)", format)
+	}
+}
+
+// ReadFile reads events to import: a jsonl export, a history directory of segments, or a
+// legacy history.json store.
+func ReadFile(path string) ([]OwnershipEvent, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if st.IsDir() {
+		ents, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		var out []OwnershipEvent
+		for _, e := range ents {
+			if _, ok := parseSegmentName(e.Name()); !ok {
+				continue
+			}
+			evs, err := readSegment(filepath.Join(path, e.Name()))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, evs...)
+		}
+		return out, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var legacy struct {
+		Ports map[string][]OwnershipEvent `json:"ports"`
+	}
+	if json.Unmarshal(b, &legacy) == nil && legacy.Ports != nil {
+		var out []OwnershipEvent
+		for _, evs := range legacy.Ports {
+			out = append(out, evs...)
+		}
+		return out, nil
+	}
+	return decodeLines(bytes.NewReader(b))
+}
+
+type ImportResult struct {
+	Read      int `json:"read"`
+	Added     int `json:"added"`
+	Duplicate int `json:"duplicate"`
+	Ports     int `json:"ports"`
+}
+
+// Import merges events from another store. Events without a host are tagged with host;
+// events already present (same host, signature and timestamp) are skipped.
+func Import(evs []OwnershipEvent, host string) (ImportResult, error) {
+	res := ImportResult{Read: len(evs)}
+	byKey := map[string][]OwnershipEvent{}
+	for _, e := range evs {
+		if e.Port <= 0 || e.Proto == "" || e.Signature == "" {
+			continue
+		}
+		if e.Host == "" {
+			e.Host = host
+		}
+		k := keyOf(e.Port, e.Proto)
+		byKey[k] = append(byKey[k], e)
+	}
+
+	dir, err := openDir()
+	if err != nil {
+		return res, err
+	}
+	unlock, err := lockDir(dir)
+	if err != nil {
+		return res, err
+	}
+	defer unlock()
+
+	ret := currentRetention()
+	for k, incoming := range byKey {
+		path := filepath.Join(dir, segmentName(k))
+		cur, err := readSegment(path)
+		if err != nil {
+			return res, err
+		}
+		seen := map[string]bool{}
+		for _, e := range cur {
+			seen[importKey(e)] = true
+		}
+		fresh := map[string]bool{}
+		for _, e := range incoming {
+			if seen[importKey(e)] {
+				res.Duplicate++
+				continue
+			}
+			seen[importKey(e)] = true
+			fresh[importKey(e)] = true
+			cur = append(cur, e)
+		}
+		// count what retention keeps, not what was offered: events past
+		// max_age are dropped straight away
+		kept := ret.apply(cur)
+		added := 0
+		for _, e := range kept {
+			if fresh[importKey(e)] {
+				added++
+			}
+		}
+		if added == 0 {
+			continue
+		}
+		if err := writeSegmentLocked(dir, path, kept); err != nil {
+			return res, err
+		}
+		res.Added += added
+		res.Ports++
+	}
+	return res, nil
+}
+
+func importKey(e OwnershipEvent) string {
+	return e.Host + "|" + e.Signature + "|" + strconv.FormatInt(e.At.UnixNano(), 10)
+}
+
+type PruneResult struct {
+	Ports   int `json:"ports"`
+	Removed int `json:"removed"`
+	Kept    int `json:"kept"`
+}
+
+// Prune drops events older than cutoff (zero = only apply configured retention) from the
+// given ports (none = all ports). With dryRun nothing is written.
+func Prune(cutoff time.Time, dryRun bool, ports ...int) (PruneResult, error) {
+	var res PruneResult
+	dir, err := openDir()
+	if err != nil {
+		return res, err
+	}
+	keys, err := Keys()
+	if err != nil {
+		return res, err
+	}
+	want := map[int]bool{}
+	for _, p := range ports {
+		want[p] = true
+	}
+
+	unlock, err := lockDir(dir)
+	if err != nil {
+		return res, err
+	}
+	defer unlock()
+
+	ret := currentRetention()
+	for _, k := range keys {
+		var port int
+		var proto string
+		if _, err := fmt.Sscanf(k, "%d/%s", &port, &proto); err != nil {
+			continue
+		}
+		if len(want) > 0 && !want[port] {
+			continue
+		}
+		path := filepath.Join(dir, segmentName(k))
+		evs, err := readSegment(path)
+		if err != nil {
+			return res, err
+		}
+		kept := evs[:0:0]
+		for _, e := range evs {
+			if !e.At.Before(cutoff) {
+				kept = append(kept, e)
+			}
+		}
+		kept = ret.apply(kept)
+		res.Kept += len(kept)
+		if len(kept) == len(evs) {
+			continue
+		}
+		res.Ports++
+		res.Removed += len(evs) - len(kept)
+		if dryRun {
+			continue
+		}
+		if err := writeSegmentLocked(dir, path, kept); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
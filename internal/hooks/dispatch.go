@@ -0,0 +1,199 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Options configures where events are delivered.
+type Options struct {
+	Command  string             // run through the shell with the payload on stdin
+	Webhook  string             // POSTed the payload
+	Template *template.Template // payload template; nil = the Event as JSON
+
+	Retries int           // extra attempts after a failed delivery
+	Backoff time.Duration // delay before the first retry, doubled on each retry
+	Timeout time.Duration // per attempt
+
+	// At most Rate events are delivered per RatePer; the rest are dropped.
+	Rate    int
+	RatePer time.Duration
+
+	Logf func(format string, a ...any) // delivery failures and drops
+}
+
+// Enabled reports whether there is anywhere to deliver to.
+func (o Options) Enabled() bool { return o.Command != "" || o.Webhook != "" }
+
+const maxBackoff = time.Minute
+
+// Dispatcher delivers events in order from a background goroutine, so a slow
+// endpoint never holds up polling.
+type Dispatcher struct {
+	opt    Options
+	client *http.Client
+	queue  chan Event
+	done   chan struct{}
+
+	mu   sync.Mutex
+	sent []time.Time // accepted events within the rate window
+}
+
+func NewDispatcher(opt Options) *Dispatcher {
+	if opt.Timeout <= 0 {
+		opt.Timeout = 10 * time.Second
+	}
+	if opt.Backoff <= 0 {
+		opt.Backoff = time.Second
+	}
+	if opt.Logf == nil {
+		opt.Logf = func(format string, a ...any) { fmt.Fprintf(os.Stderr, "hooks: "+format+"\n", a...) }
+	}
+	d := &Dispatcher{
+		opt:    opt,
+		client: &http.Client{Timeout: opt.Timeout},
+		queue:  make(chan Event, 64),
+		done:   make(chan struct{}),
+	}
+	go d.loop()
+	return d
+}
+
+// Send queues ev for delivery unless the rate limit is exhausted or the queue is full.
+func (d *Dispatcher) Send(ev Event) bool {
+	if !d.allow(time.Now()) {
+		d.opt.Logf("rate limit reached, dropped %s for %d/%s", ev.Event, ev.Port, ev.Proto)
+		return false
+	}
+	select {
+	case d.queue <- ev:
+		return true
+	default:
+		d.opt.Logf("queue full, dropped %s for %d/%s", ev.Event, ev.Port, ev.Proto)
+		return false
+	}
+}
+
+func (d *Dispatcher) allow(now time.Time) bool {
+	if d.opt.Rate <= 0 {
+		return true
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	cut := 0
+	for cut < len(d.sent) && now.Sub(d.sent[cut]) >= d.opt.RatePer {
+		cut++
+	}
+	d.sent = d.sent[cut:]
+	if len(d.sent) >= d.opt.Rate {
+		return false
+	}
+	d.sent = append(d.sent, now)
+	return true
+}
+
+// Close stops accepting events and waits up to timeout for queued ones to be delivered.
+func (d *Dispatcher) Close(timeout time.Duration) {
+	close(d.queue)
+	select {
+	case <-d.done:
+	case <-time.After(timeout):
+	}
+}
+
+func (d *Dispatcher) loop() {
+	defer close(d.done)
+	for ev := range d.queue {
+		body, err := Payload(d.opt.Template, ev)
+		if err != nil {
+			d.opt.Logf("payload for %s on %d/%s: %v", ev.Event, ev.Port, ev.Proto, err)
+			continue
+		}
+		if d.opt.Command != "" {
+			d.retry("command", func() error { return d.runCommand(ev, body) })
+		}
+		if d.opt.Webhook != "" {
+			d.retry("webhook", func() error { return d.post(body) })
+		}
+	}
+}
+
+// permanent marks a failure that retrying will not fix.
+type permanent struct{ error }
+
+func (d *Dispatcher) retry(what string, attempt func() error) {
+	wait := d.opt.Backoff
+	for i := 0; ; i++ {
+		err := attempt()
+		if err == nil {
+			return
+		}
+		_, perm := err.(permanent)
+		if perm || i >= d.opt.Retries {
+			d.opt.Logf("%s failed after %d attempt(s): %v", what, i+1, err)
+			return
+		}
+		time.Sleep(wait)
+		wait = min(2*wait, maxBackoff)
+	}
+}
+
+func (d *Dispatcher) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, d.opt.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return permanent{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "portik-hooks")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("webhook: %s", resp.Status)
+	default:
+		return permanent{fmt.Errorf("webhook: %s", resp.Status)}
+	}
+}
+
+func (d *Dispatcher) runCommand(ev Event, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.opt.Timeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", d.opt.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", d.opt.Command)
+	}
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"PORTIK_EVENT="+ev.Event,
+		"PORTIK_PORT="+strconv.Itoa(ev.Port),
+		"PORTIK_PROTO="+ev.Proto,
+		"PORTIK_OLD_OWNER="+ev.OldOwner,
+		"PORTIK_NEW_OWNER="+ev.NewOwner,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if len(out) > 0 {
+			return fmt.Errorf("%w: %s", err, bytes.TrimSpace(out))
+		}
+		return err
+	}
+	return nil
+}
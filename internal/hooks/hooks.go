@@ -0,0 +1,240 @@
+// Package hooks turns successive reports of a port into change events and delivers
+// them to a shell command and/or a webhook, with retries and a rate limit.
+package hooks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/history"
+	"github.com/pratik-anurag/portik/internal/model"
+)
+
+// Event kinds.
+const (
+	EventOwnerChanged = "owner_changed"
+	EventPortFreed    = "port_freed"
+	EventDiagnostic   = "diagnostic"
+)
+
+// Event is the default JSON payload and the data templates are executed with.
+type Event struct {
+	Event      string            `json:"event"`
+	At         time.Time         `json:"at"`
+	Host       string            `json:"host,omitempty"`
+	Port       int               `json:"port"`
+	Proto      string            `json:"proto"`
+	OldOwner   string            `json:"old_owner,omitempty"`
+	NewOwner   string            `json:"new_owner"`
+	Diagnostic *model.Diagnostic `json:"diagnostic,omitempty"`
+	Report     model.Report      `json:"report"`
+}
+
+var severityRank = map[string]int{"info": 1, "warn": 2, "error": 3}
+
+// ValidSeverity reports whether s is info|warn|error, or "off".
+func ValidSeverity(s string) bool {
+	return s == "off" || severityRank[s] > 0
+}
+
+// Detector compares each report of a port with the previous one. The first report of
+// a port sets the ownership baseline; diagnostics it already carries do fire.
+type Detector struct {
+	// MinSeverity is the lowest diagnostic severity (info|warn|error) that raises an
+	// event when it newly appears; "off" or "" disables diagnostic events.
+	MinSeverity string
+
+	last map[string]model.Report
+}
+
+func key(rep model.Report) string { return fmt.Sprintf("%d/%s", rep.Port, rep.Proto) }
+
+func ownerOf(rep model.Report) string {
+	return history.OwnerLabel(history.EventFromReport(rep))
+}
+
+// Events returns what changed since the previous report of the same port.
+func (d *Detector) Events(rep model.Report) []Event {
+	if d.last == nil {
+		d.last = map[string]model.Report{}
+	}
+	prev, seen := d.last[key(rep)]
+	d.last[key(rep)] = rep
+
+	base := Event{At: rep.Generated, Host: rep.Host.Hostname, Port: rep.Port, Proto: rep.Proto, NewOwner: ownerOf(rep), Report: rep}
+	if seen {
+		base.OldOwner = ownerOf(prev)
+	}
+	var out []Event
+	if seen && prev.Signature() != rep.Signature() {
+		ev := base
+		ev.Event = EventOwnerChanged
+		if history.EventFromReport(rep).IsReleased() {
+			ev.Event = EventPortFreed
+		}
+		out = append(out, ev)
+	}
+
+	floor := severityRank[d.MinSeverity]
+	if floor == 0 {
+		return out
+	}
+	had := map[string]bool{}
+	for _, dg := range prev.Diagnostics {
+		if severityRank[dg.Severity] >= floor {
+			had[dg.Kind] = true
+		}
+	}
+	for _, dg := range rep.Diagnostics {
+		if severityRank[dg.Severity] < floor || had[dg.Kind] {
+			continue
+		}
+		had[dg.Kind] = true
+		ev := base
+		ev.Event = EventDiagnostic
+		ev.Diagnostic = &dg
+		out = append(out, ev)
+	}
+	return out
+}
+
+// ParseTemplate parses a payload template given inline or as @file. Templates see an
+// Event and can use {{json .X}} to embed a value as JSON.
+func ParseTemplate(s string) (*template.Template, error) {
+	if rest, ok := strings.CutPrefix(s, "@"); ok {
+		b, err := os.ReadFile(rest)
+		if err != nil {
+			return nil, err
+		}
+		s = string(b)
+	}
+	return template.New("payload").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Option("missingkey=error").Parse(s)
+}
+
+// ParseRate parses "N/s", "N/m" or "N/h" (also "N/30s") into a count and window.
+func ParseRate(s string) (int, time.Duration, error) {
+	n, unit, ok := strings.Cut(s, "/")
+	var count int
+	if _, err := fmt.Sscanf(n, "%d", &count); !ok || err != nil || count <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate %q (e.g. 10/m)", s)
+	}
+	switch unit {
+	case "s":
+		return count, time.Second, nil
+	case "m":
+		return count, time.Minute, nil
+	case "h":
+		return count, time.Hour, nil
+	}
+	per, err := time.ParseDuration(unit)
+	if err != nil || per <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate %q (e.g. 10/m)", s)
+	}
+	return count, per, nil
+}
+
+// Payload renders ev with tmpl, or as indented JSON when tmpl is nil.
+func Payload(tmpl *template.Template, ev Event) ([]byte, error) {
+	if tmpl == nil {
+		return json.MarshalIndent(ev, "", "  ")
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, ev); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+// Config is the user-facing hook configuration, from flags or daemon.yaml.
+type Config struct {
+	OnChange string        `yaml:"on_change" json:"on_change,omitempty"`
+	Webhook  string        `yaml:"webhook" json:"webhook,omitempty"`
+	Template string        `yaml:"template" json:"template,omitempty"` // inline or @file
+	Severity string        `yaml:"severity" json:"severity,omitempty"` // info|warn|error|off
+	Rate     string        `yaml:"rate" json:"rate,omitempty"`         // N/s|m|h
+	Retries  *int          `yaml:"retries" json:"retries,omitempty"`
+	Backoff  time.Duration `yaml:"backoff" json:"backoff,omitempty"`
+	Timeout  time.Duration `yaml:"timeout" json:"timeout,omitempty"`
+}
+
+// DefaultConfig holds the defaults for every field but the targets.
+func DefaultConfig() Config {
+	retries := 3
+	return Config{Severity: "warn", Rate: "30/m", Retries: &retries, Backoff: time.Second, Timeout: 10 * time.Second}
+}
+
+// Merge returns c with the fields set in over replacing its own.
+func (c Config) Merge(over Config) Config {
+	if over.OnChange != "" {
+		c.OnChange = over.OnChange
+	}
+	if over.Webhook != "" {
+		c.Webhook = over.Webhook
+	}
+	if over.Template != "" {
+		c.Template = over.Template
+	}
+	if over.Severity != "" {
+		c.Severity = over.Severity
+	}
+	if over.Rate != "" {
+		c.Rate = over.Rate
+	}
+	if over.Retries != nil {
+		c.Retries = over.Retries
+	}
+	if over.Backoff != 0 {
+		c.Backoff = over.Backoff
+	}
+	if over.Timeout != 0 {
+		c.Timeout = over.Timeout
+	}
+	return c
+}
+
+// Options validates c; the zero Options (not Enabled) means no hooks. Unset fields
+// take their defaults.
+func (c Config) Options() (Options, error) {
+	c = DefaultConfig().Merge(c)
+	var o Options
+	if c.OnChange == "" && c.Webhook == "" {
+		return o, nil
+	}
+	if c.Webhook != "" {
+		if u, err := url.Parse(c.Webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return o, fmt.Errorf("invalid webhook %q (http[s]://host/path)", c.Webhook)
+		}
+	}
+	if !ValidSeverity(c.Severity) {
+		return o, fmt.Errorf("invalid severity %q (info|warn|error|off)", c.Severity)
+	}
+	n, per, err := ParseRate(c.Rate)
+	if err != nil {
+		return o, err
+	}
+	if *c.Retries < 0 || c.Backoff <= 0 || c.Timeout <= 0 {
+		return o, errors.New("retries must be >= 0, backoff and timeout > 0")
+	}
+	o = Options{
+		Command: c.OnChange, Webhook: c.Webhook,
+		Retries: *c.Retries, Backoff: c.Backoff, Timeout: c.Timeout,
+		Rate: n, RatePer: per,
+	}
+	if c.Template != "" {
+		if o.Template, err = ParseTemplate(c.Template); err != nil {
+			return o, fmt.Errorf("template: %w", err)
+		}
+	}
+	return o, nil
+}
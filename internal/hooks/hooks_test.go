@@ -0,0 +1,138 @@
+package hooks
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/model"
+)
+
+func report(port int, pid int32, name string, diags ...model.Diagnostic) model.Report {
+	rep := model.Report{Port: port, Proto: "tcp", Generated: time.Now(), Diagnostics: diags}
+	if pid > 0 {
+		rep.Listeners = []model.Listener{{LocalPort: port, PID: pid, ProcName: name, State: "LISTEN"}}
+	}
+	return rep
+}
+
+func TestDetector(t *testing.T) {
+	d := &Detector{MinSeverity: "warn"}
+	inUse := model.Diagnostic{Kind: "in-use", Severity: "info"}
+	zombie := model.Diagnostic{Kind: "zombie", Severity: "warn"}
+
+	if evs := d.Events(report(3000, 10, "node", inUse)); len(evs) != 0 {
+		t.Fatalf("first report is the baseline: %+v", evs)
+	}
+	if evs := d.Events(report(4000, 12, "java", zombie)); len(evs) != 1 || evs[0].Event != EventDiagnostic || evs[0].OldOwner != "" {
+		t.Fatalf("a diagnostic present on first sight fires: %+v", evs)
+	}
+	if evs := d.Events(report(3000, 10, "node", inUse)); len(evs) != 0 {
+		t.Fatalf("no change: %+v", evs)
+	}
+	evs := d.Events(report(3000, 11, "vite", inUse, zombie))
+	if len(evs) != 2 || evs[0].Event != EventOwnerChanged || evs[0].OldOwner != "node" || evs[0].NewOwner != "vite" ||
+		evs[1].Event != EventDiagnostic || evs[1].Diagnostic.Kind != "zombie" {
+		t.Fatalf("owner change + zombie: %+v", evs)
+	}
+	if evs := d.Events(report(3000, 11, "vite", inUse, zombie)); len(evs) != 0 {
+		t.Fatalf("a diagnostic fires only when it appears: %+v", evs)
+	}
+	evs = d.Events(report(3000, 0, ""))
+	if len(evs) != 1 || evs[0].Event != EventPortFreed || evs[0].NewOwner != "free" {
+		t.Fatalf("freed: %+v", evs)
+	}
+}
+
+func TestWebhookRetriesAndTemplate(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, string(b))
+		if len(bodies) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	tmpl, err := ParseTemplate(`{"text": {{json (printf "%d/%s: %s -> %s" .Port .Proto .OldOwner .NewOwner)}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDispatcher(Options{Webhook: srv.URL, Template: tmpl, Retries: 3, Backoff: time.Millisecond, Logf: t.Logf})
+	d.Send(Event{Event: EventOwnerChanged, Port: 3000, Proto: "tcp", OldOwner: "node", NewOwner: "free"})
+	d.Close(5 * time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 3 {
+		t.Fatalf("want 2 failures then success, got %d attempts", len(bodies))
+	}
+	var got map[string]string
+	if err := json.Unmarshal([]byte(bodies[2]), &got); err != nil || got["text"] != "3000/tcp: node -> free" {
+		t.Fatalf("payload %q: %v", bodies[2], err)
+	}
+}
+
+func TestWebhookNoRetryOnClientError(t *testing.T) {
+	var n int
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		n++
+		mu.Unlock()
+		var ev Event
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil || ev.Report.Port != 8080 {
+			t.Errorf("default payload: %+v, %v", ev, err)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	d := NewDispatcher(Options{Webhook: srv.URL, Retries: 3, Backoff: time.Millisecond, Logf: t.Logf})
+	d.Send(Event{Event: EventPortFreed, Port: 8080, Proto: "tcp", Report: model.Report{Port: 8080}})
+	d.Close(5 * time.Second)
+	if n != 1 {
+		t.Fatalf("4xx must not be retried, got %d attempts", n)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	d := NewDispatcher(Options{Webhook: "http://127.0.0.1:1/", Rate: 2, RatePer: time.Hour, Logf: func(string, ...any) {}})
+	defer d.Close(0)
+	now := time.Now()
+	if !d.allow(now) || !d.allow(now) || d.allow(now.Add(time.Minute)) {
+		t.Fatal("third event within the window must be dropped")
+	}
+	if !d.allow(now.Add(time.Hour)) {
+		t.Fatal("the window must slide")
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	d := NewDispatcher(Options{Command: `echo "$PORTIK_EVENT $PORTIK_PORT $PORTIK_NEW_OWNER" > ` + out + `; cat >> ` + out, Logf: t.Logf})
+	d.Send(Event{Event: EventOwnerChanged, Port: 5432, Proto: "tcp", NewOwner: "postgres"})
+	d.Close(5 * time.Second)
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(b), "owner_changed 5432 postgres\n{") {
+		t.Fatalf("command output %q", b)
+	}
+}
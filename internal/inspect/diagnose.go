@@ -155,13 +155,17 @@ func Diagnose(rep model.Report) []model.Diagnostic {
 
 	// docker
 	if rep.Docker.Mapped {
+		action := "Use: portik restart <port> --docker --container"
+		if rep.Docker.ComposeService != "" {
+			action = "Use: portik restart <port> --compose (or --recreate to apply config changes)"
+		}
 		out = append(out, model.Diagnostic{
 			Kind:     "docker",
 			Severity: "info",
 			Summary:  "Port is mapped from a Docker container",
 			Details: fmt.Sprintf("Host port %d/%s is mapped to %s (%s) service=%s containerPort=%s",
 				rep.Port, rep.Proto, rep.Docker.ContainerID, rep.Docker.ContainerName, rep.Docker.ComposeService, rep.Docker.ContainerPort),
-			Action: "Use: portik restart <port> --docker --container",
+			Action: action,
 		})
 	}
 
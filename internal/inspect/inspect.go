@@ -2,7 +2,9 @@ package inspect
 
 import (
 	"fmt"
+	"maps"
 	"os/user"
+	"slices"
 	"time"
 
 	"github.com/pratik-anurag/portik/internal/docker"
@@ -22,21 +24,7 @@ func InspectPort(port int, proto string, opt Options) (model.Report, error) {
 		return model.Report{}, fmt.Errorf("unsupported proto: %s", proto)
 	}
 
-	u, _ := user.Current()
-	hs := platform.HostSummary()
-
-	rep := model.Report{
-		Port:      port,
-		Proto:     proto,
-		Generated: time.Now(),
-		Host: model.HostSummary{
-			OS:       hs.OS,
-			Arch:     hs.Arch,
-			Hostname: hs.Hostname,
-			Kernel:   hs.Kernel,
-		},
-		User: model.UserSummary{Username: safeUsername(u)},
-	}
+	rep := newReport(port, proto)
 
 	listeners, conns, err := sockets.Inspect(port, proto, opt.IncludeConnections)
 	if err != nil {
@@ -60,6 +48,86 @@ func InspectPort(port int, proto string, opt Options) (model.Report, error) {
 	return rep, nil
 }
 
+// Discover returns a report for every port with a listening socket, sorted by port,
+// from one system-wide enumeration instead of a lookup per port. With
+// IncludeConnections, TCP reports also carry their connections, from one more
+// enumeration.
+func Discover(proto string, opt Options) ([]model.Report, error) {
+	if proto != "tcp" && proto != "udp" {
+		return nil, fmt.Errorf("unsupported proto: %s", proto)
+	}
+	listeners, err := sockets.Listening(proto)
+	if err != nil {
+		return nil, err
+	}
+	proc.EnrichAll(listeners)
+
+	byPort := map[int][]model.Listener{}
+	for _, l := range listeners {
+		if l.LocalPort > 0 {
+			byPort[l.LocalPort] = append(byPort[l.LocalPort], l)
+		}
+	}
+	ports := slices.Sorted(maps.Keys(byPort))
+	var mapped map[int]model.DockerMap
+	if opt.EnableDocker {
+		mapped = docker.MapPorts(ports, proto)
+	}
+	var conns map[int][]model.Conn
+	if opt.IncludeConnections && proto == "tcp" {
+		all, err := sockets.Connections()
+		if err != nil {
+			return nil, err
+		}
+		conns = connsByPort(all, byPort)
+	}
+
+	base := newReport(0, proto)
+	out := make([]model.Report, 0, len(ports))
+	for _, p := range ports {
+		rep := base
+		rep.Port = p
+		rep.Listeners = byPort[p]
+		rep.Docker = mapped[p]
+		rep.Connections = conns[p]
+		rep.Diagnostics = Diagnose(rep)
+		out = append(out, rep)
+	}
+	return out, nil
+}
+
+// connsByPort assigns each connection to the listening ports it involves, on
+// either end, as a per-port inspection would list it.
+func connsByPort(conns []model.Conn, listening map[int][]model.Listener) map[int][]model.Conn {
+	out := map[int][]model.Conn{}
+	for _, c := range conns {
+		if _, ok := listening[c.LocalPort]; ok {
+			out[c.LocalPort] = append(out[c.LocalPort], c)
+		}
+		if _, ok := listening[c.RemotePort]; ok && c.RemotePort != c.LocalPort {
+			out[c.RemotePort] = append(out[c.RemotePort], c)
+		}
+	}
+	return out
+}
+
+func newReport(port int, proto string) model.Report {
+	u, _ := user.Current()
+	hs := platform.HostSummary()
+	return model.Report{
+		Port:      port,
+		Proto:     proto,
+		Generated: time.Now(),
+		Host: model.HostSummary{
+			OS:       hs.OS,
+			Arch:     hs.Arch,
+			Hostname: hs.Hostname,
+			Kernel:   hs.Kernel,
+		},
+		User: model.UserSummary{Username: safeUsername(u)},
+	}
+}
+
 func safeUsername(u *user.User) string {
 	if u == nil {
 		return ""
@@ -0,0 +1,21 @@
+package inspect
+
+import (
+	"testing"
+
+	"github.com/pratik-anurag/portik/internal/model"
+)
+
+func TestConnsByPort(t *testing.T) {
+	listening := map[int][]model.Listener{5432: {{LocalPort: 5432}}, 8080: {{LocalPort: 8080}}}
+	conns := []model.Conn{
+		{LocalPort: 5432, RemotePort: 50000, State: "ESTAB"},     // client of postgres
+		{LocalPort: 50001, RemotePort: 5432, State: "TIME-WAIT"}, // local client side
+		{LocalPort: 8080, RemotePort: 5432, State: "ESTAB"},      // app on 8080 talking to postgres
+		{LocalPort: 40000, RemotePort: 443, State: "ESTAB"},      // unrelated
+	}
+	got := connsByPort(conns, listening)
+	if len(got[5432]) != 3 || len(got[8080]) != 1 || len(got) != 2 {
+		t.Fatalf("want 3 connections on 5432 and 1 on 8080, got %v", got)
+	}
+}
@@ -32,11 +32,12 @@ type UserSummary struct {
 }
 
 type Listener struct {
-	LocalIP   string `json:"local_ip"`
-	LocalPort int    `json:"local_port"`
-	Family    string `json:"family"` // ipv4|ipv6|unknown
-	State     string `json:"state"`  // LISTEN|BOUND
-	PID       int32  `json:"pid,omitempty"`
+	LocalIP   string  `json:"local_ip"`
+	LocalPort int     `json:"local_port"`
+	Family    string  `json:"family"` // ipv4|ipv6|unknown
+	State     string  `json:"state"`  // LISTEN|BOUND
+	PID       int32   `json:"pid,omitempty"`
+	PIDs      []int32 `json:"pids,omitempty"` // every process sharing the socket, when more than one
 
 	ProcName string `json:"proc_name,omitempty"`
 	Cmdline  string `json:"cmdline,omitempty"`
@@ -59,8 +60,10 @@ type Conn struct {
 type DockerMap struct {
 	Checked        bool   `json:"checked"`
 	Mapped         bool   `json:"mapped"`
+	Runtime        string `json:"runtime,omitempty"` // docker|podman
 	ContainerID    string `json:"container_id,omitempty"`
 	ContainerName  string `json:"container_name,omitempty"`
+	ComposeProject string `json:"compose_project,omitempty"`
 	ComposeService string `json:"compose_service,omitempty"`
 	ContainerPort  string `json:"container_port,omitempty"` // like 5432/tcp
 }
@@ -0,0 +1,18 @@
+// Package pidctl starts, probes and stops background processes by pid: the daemon
+// and lease holders. It has no dependencies beyond the standard library, so that
+// the public portiktest package stays light.
+package pidctl
+
+import "time"
+
+// WaitExit waits up to timeout for pid to exit.
+func WaitExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !Alive(pid) {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return !Alive(pid)
+}
@@ -0,0 +1,36 @@
+//go:build windows
+
+package pidctl
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func Alive(pid int) bool {
+	h, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == 259 // STILL_ACTIVE
+}
+
+func Detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: 0x00000008 | syscall.CREATE_NEW_PROCESS_GROUP} // DETACHED_PROCESS
+}
+
+// Stop kills pid; Windows has no SIGTERM, so it gets no chance to clean up (the
+// daemon does not compact history on exit).
+func Stop(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
@@ -80,3 +80,36 @@ func parsePort(s string) (int, error) {
 	}
 	return n, nil
 }
+
+// Filter selects ports by an include and an exclude spec. An empty include selects
+// every port.
+type Filter struct {
+	include, exclude map[int]bool
+}
+
+// ParseFilter parses two ParseSpec specs; either may be empty.
+func ParseFilter(include, exclude string) (Filter, error) {
+	var f Filter
+	for _, s := range []struct {
+		spec string
+		set  *map[int]bool
+	}{{include, &f.include}, {exclude, &f.exclude}} {
+		if strings.TrimSpace(s.spec) == "" {
+			continue
+		}
+		list, err := ParseSpec(s.spec)
+		if err != nil {
+			return Filter{}, err
+		}
+		*s.set = make(map[int]bool, len(list))
+		for _, p := range list {
+			(*s.set)[p] = true
+		}
+	}
+	return f, nil
+}
+
+// Match reports whether f selects port.
+func (f Filter) Match(port int) bool {
+	return (f.include == nil || f.include[port]) && !f.exclude[port]
+}
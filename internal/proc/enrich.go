@@ -3,6 +3,7 @@ package proc
 import (
 	"bytes"
 	"os/exec"
+	"strconv"
 	"strings"
 
 	"github.com/pratik-anurag/portik/internal/model"
@@ -18,6 +19,33 @@ func Enrich(l *model.Listener) {
 	l.IsZombie = strings.Contains(strings.ToUpper(psField(l.PID, "stat=")), "Z")
 }
 
+// EnrichAll enriches many listeners with one ps call per field instead of per listener.
+func EnrichAll(ls []model.Listener) {
+	var pids []string
+	seen := map[int32]bool{}
+	for _, l := range ls {
+		if l.PID > 0 && !seen[l.PID] {
+			seen[l.PID] = true
+			pids = append(pids, itoa32(l.PID))
+		}
+	}
+	if len(pids) == 0 {
+		return
+	}
+	list := strings.Join(pids, ",")
+	comm, user, command, stat := psFields(list, "comm="), psFields(list, "user="), psFields(list, "command="), psFields(list, "stat=")
+	for i := range ls {
+		l := &ls[i]
+		if l.PID <= 0 {
+			continue
+		}
+		l.ProcName = firstNonEmpty(l.ProcName, comm[l.PID])
+		l.User = firstNonEmpty(l.User, user[l.PID])
+		l.Cmdline = firstNonEmpty(l.Cmdline, compact(command[l.PID]))
+		l.IsZombie = strings.Contains(strings.ToUpper(stat[l.PID]), "Z")
+	}
+}
+
 func EnrichConn(c *model.Conn) {
 	if c.PID <= 0 {
 		return
@@ -33,6 +61,22 @@ func psField(pid int32, format string) string {
 	return strings.TrimSpace(buf.String())
 }
 
+// psFields returns format for each of the comma-separated pids.
+func psFields(pids, format string) map[int32]string {
+	cmd := exec.Command("ps", "-p", pids, "-o", "pid=,"+format)
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	_ = cmd.Run()
+	out := map[int32]string{}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		pid, v, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if n, err := strconv.ParseInt(pid, 10, 32); err == nil {
+			out[int32(n)] = strings.TrimSpace(v)
+		}
+	}
+	return out
+}
+
 func compact(s string) string {
 	s = strings.TrimSpace(s)
 	s = strings.ReplaceAll(s, "\n", " ")
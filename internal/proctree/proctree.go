@@ -11,13 +11,14 @@ import (
 type Proc struct {
 	PID     int32  `json:"pid"`
 	PPID    int32  `json:"ppid,omitempty"`
+	PGID    int32  `json:"pgid,omitempty"`
 	User    string `json:"user,omitempty"`
 	Name    string `json:"name,omitempty"`
 	Cmdline string `json:"cmdline,omitempty"`
 }
 
 type StartedBy struct {
-	Kind    string `json:"kind"` // systemd|container|launchd|unknown
+	Kind    string `json:"kind"` // systemd|container|launchd|pm2|supervisord|nodemon|shell|unknown
 	Details string `json:"details,omitempty"`
 }
 
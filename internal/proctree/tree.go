@@ -0,0 +1,211 @@
+package proctree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// All lists every process visible to ps, with PGID filled in.
+func All() ([]Proc, error) {
+	out, err := exec.Command("ps", "-A", "-o", "pid=,ppid=,pgid=,user=,comm=").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parsePsTable(string(out)), nil
+}
+
+// parsePsTable parses "pid ppid pgid user comm" lines; comm may contain spaces.
+func parsePsTable(out string) []Proc {
+	var procs []Proc
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Fields(line)
+		if len(f) < 5 {
+			continue
+		}
+		pid := atoi32(f[0])
+		if pid <= 0 {
+			continue
+		}
+		procs = append(procs, Proc{
+			PID:  pid,
+			PPID: atoi32(f[1]),
+			PGID: atoi32(f[2]),
+			User: f[3],
+			Name: strings.Join(f[4:], " "),
+		})
+	}
+	return procs
+}
+
+// Subtree returns the given roots and all their descendants, parents before
+// children. Roots already inside another root's subtree are not repeated.
+func Subtree(procs []Proc, roots ...int32) []Proc {
+	byPID := map[int32]Proc{}
+	children := map[int32][]int32{}
+	for _, p := range procs {
+		byPID[p.PID] = p
+		if p.PPID != p.PID {
+			children[p.PPID] = append(children[p.PPID], p.PID)
+		}
+	}
+	var out []Proc
+	seen := map[int32]bool{}
+	queue := slices.Clone(roots)
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		if seen[pid] {
+			continue
+		}
+		seen[pid] = true
+		p, ok := byPID[pid]
+		if !ok {
+			p = Proc{PID: pid}
+		}
+		out = append(out, p)
+		queue = append(queue, children[pid]...)
+	}
+	return out
+}
+
+// Group returns the members of process group pgid.
+func Group(procs []Proc, pgid int32) []Proc {
+	var out []Proc
+	for _, p := range procs {
+		if p.PGID == pgid {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Supervisor guesses what (re)started pid by walking its parent chain: a known
+// process manager (pm2, supervisord, nodemon), a shell that is the direct parent
+// (a restart loop), then systemd/launchd/container as reported by Build.
+func Supervisor(pid int32) StartedBy {
+	chain, started := Build(pid, 15)
+	for i, p := range chain {
+		if i == 0 {
+			continue
+		}
+		if kind := supervisorKind(p); kind != "" {
+			return StartedBy{Kind: kind, Details: describe(p)}
+		}
+		if i == 1 && (IsShell(p.Name) || IsShell(firstWord(p.Cmdline))) {
+			return StartedBy{Kind: "shell", Details: describe(p)}
+		}
+	}
+	return started
+}
+
+// supervisorKind matches the program an ancestor runs, never a substring of its
+// arguments: a path like ~/pm2-notes or a flag mentioning nodemon is not pm2 or
+// nodemon.
+func supervisorKind(p Proc) string {
+	name := strings.ToLower(p.Name)
+	switch prog := Program(p.Cmdline); {
+	case name == "nodemon" || prog == "nodemon":
+		return "nodemon"
+	case name == "pm2" || strings.HasPrefix(name, "pm2 ") || prog == "pm2": // the daemon is "PM2 vX: God Daemon"
+		return "pm2"
+	case name == "supervisord" || prog == "supervisord":
+		return "supervisord"
+	}
+	return ""
+}
+
+// Program is the lower-cased basename of argv[0], or of the script for
+// interpreters (node /usr/bin/nodemon, python3 /usr/bin/supervisord), without a
+// .js/.py extension.
+func Program(cmdline string) string {
+	f := strings.Fields(cmdline)
+	if len(f) == 0 {
+		return ""
+	}
+	prog := strings.ToLower(filepath.Base(f[0]))
+	if isInterpreter(prog) {
+		prog = ""
+		for _, a := range f[1:] {
+			if !strings.HasPrefix(a, "-") {
+				prog = strings.ToLower(filepath.Base(a))
+				break
+			}
+		}
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(prog, ".js"), ".py")
+}
+
+func isInterpreter(prog string) bool {
+	switch {
+	case prog == "node" || prog == "nodejs" || prog == "bun" || prog == "deno":
+		return true
+	case strings.HasPrefix(prog, "python"), strings.HasPrefix(prog, "perl"), strings.HasPrefix(prog, "ruby"):
+		return true
+	}
+	return false
+}
+
+// IsShell reports whether name (a comm or argv[0], possibly a path or a login
+// shell's "-bash") is a Unix shell.
+func IsShell(name string) bool {
+	name = strings.TrimPrefix(filepath.Base(strings.TrimSpace(name)), "-") // login shells: -bash
+	switch name {
+	case "sh", "bash", "zsh", "dash", "ksh", "fish", "ash":
+		return true
+	}
+	return false
+}
+
+// firstWord returns argv[0]; scripts run via a shebang show the script as comm
+// but the interpreter here.
+func firstWord(cmdline string) string {
+	if f := strings.Fields(cmdline); len(f) > 0 {
+		return f[0]
+	}
+	return ""
+}
+
+func describe(p Proc) string {
+	s := "pid " + itoa32(p.PID)
+	if p.Cmdline != "" {
+		return s + ": " + p.Cmdline
+	}
+	if p.Name != "" {
+		return s + ": " + p.Name
+	}
+	return s
+}
+
+// SystemdUnit returns the systemd service whose cgroup holds pid, and whether it
+// is a user unit (under user@UID.service) rather than a system one. Session and
+// terminal scopes are not services and yield "".
+func SystemdUnit(pid int32) (unit string, user bool) {
+	b, err := os.ReadFile("/proc/" + itoa32(pid) + "/cgroup")
+	if err != nil {
+		return "", false
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		// hierarchy-ID:controllers:path; cgroup v2 has a single "0::" line
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 || (parts[1] != "" && !strings.Contains(parts[1], "name=systemd")) {
+			continue
+		}
+		inUser := false
+		for _, seg := range strings.Split(parts[2], "/") {
+			if strings.HasPrefix(seg, "user@") && strings.HasSuffix(seg, ".service") {
+				inUser = true
+				continue
+			}
+			if strings.HasSuffix(seg, ".service") {
+				unit, user = seg, inUser
+			}
+		}
+		if unit != "" {
+			return unit, user
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,120 @@
+package proctree
+
+import (
+	"slices"
+	"testing"
+)
+
+const psTable = `    1     0     1 root     systemd
+  100     1   100 root     nginx
+  101   100   100 www-data nginx
+  102   100   100 www-data nginx
+  150   101   100 www-data php helper
+  200     1   200 me       Google Chrome Helper
+`
+
+func pids(ps []Proc) []int32 {
+	out := make([]int32, len(ps))
+	for i, p := range ps {
+		out[i] = p.PID
+	}
+	return out
+}
+
+func TestParsePsTable(t *testing.T) {
+	procs := parsePsTable(psTable + "garbage\n\n")
+	if len(procs) != 6 {
+		t.Fatalf("want 6 processes, got %+v", procs)
+	}
+	if p := procs[4]; p.PID != 150 || p.PPID != 101 || p.PGID != 100 || p.User != "www-data" || p.Name != "php helper" {
+		t.Fatalf("comm with a space: %+v", p)
+	}
+	if p := procs[5]; p.Name != "Google Chrome Helper" {
+		t.Fatalf("comm with spaces: %+v", p)
+	}
+}
+
+func TestSubtreeAndGroup(t *testing.T) {
+	procs := parsePsTable(psTable)
+
+	// a prefork master: its workers and their children, parents first
+	if got := pids(Subtree(procs, 100)); !slices.Equal(got, []int32{100, 101, 102, 150}) {
+		t.Fatalf("Subtree(100) = %v", got)
+	}
+	// a worker already under another root is not repeated
+	if got := pids(Subtree(procs, 100, 101)); !slices.Equal(got, []int32{100, 101, 102, 150}) {
+		t.Fatalf("Subtree(100, 101) = %v", got)
+	}
+	// an unknown root is still returned, on its own
+	if got := pids(Subtree(procs, 999)); !slices.Equal(got, []int32{999}) {
+		t.Fatalf("Subtree(999) = %v", got)
+	}
+	if got := pids(Group(procs, 100)); !slices.Equal(got, []int32{100, 101, 102, 150}) {
+		t.Fatalf("Group(100) = %v", got)
+	}
+	if got := Group(procs, 4242); len(got) != 0 {
+		t.Fatalf("Group(4242) = %v", got)
+	}
+}
+
+func TestSupervisorKind(t *testing.T) {
+	for _, tc := range []struct {
+		name, cmdline, want string
+	}{
+		{"PM2 v5.3.0: God", "PM2 v5.3.0: God Daemon (/home/me/.pm2)", "pm2"},
+		{"node", "node /usr/lib/node_modules/pm2/bin/pm2 start app.js", "pm2"},
+		{"node", "node /usr/bin/nodemon server.js", "nodemon"},
+		{"nodemon", "", "nodemon"},
+		{"python3", "/usr/bin/python3 /usr/bin/supervisord -n -c /etc/supervisord.conf", "supervisord"},
+		{"supervisord", "", "supervisord"},
+		// paths and arguments that merely mention a manager
+		{"bash", "bash /home/me/pm2-notes/run.sh", ""},
+		{"vim", "vim ~/pm2-notes/todo.md", ""},
+		{"vim", "vim nodemon.json", ""},
+		{"python3", "python3 -u supervisord_wrapper_test.py", ""},
+		{"node", "node server.js --watch-with-nodemon", ""},
+		{"-bash", "-bash", ""},
+	} {
+		if got := supervisorKind(Proc{Name: tc.name, Cmdline: tc.cmdline}); got != tc.want {
+			t.Errorf("supervisorKind(%q, %q) = %q, want %q", tc.name, tc.cmdline, got, tc.want)
+		}
+	}
+}
+
+func TestProgram(t *testing.T) {
+	for cmdline, want := range map[string]string{
+		"/usr/sbin/nginx -g daemon off;":                "nginx",
+		"node --inspect /srv/app/server.js":             "server",
+		"/venv/bin/python3.12 /venv/bin/gunicorn app":   "gunicorn",
+		"python3 -m http.server 8000":                   "http.server",
+		"ruby /usr/local/bin/puma -C config/puma.rb":    "puma",
+		"nginx: master process /usr/sbin/nginx":         "nginx:",
+		"/USR/SBIN/NGINX":                               "nginx",
+		"":                                              "",
+		"node":                                          "",
+		"/opt/homebrew/bin/supervisord -c /etc/sv.conf": "supervisord",
+	} {
+		if got := Program(cmdline); got != want {
+			t.Errorf("Program(%q) = %q, want %q", cmdline, got, want)
+		}
+	}
+}
+
+func TestIsShell(t *testing.T) {
+	for name, want := range map[string]bool{
+		"bash":       true,
+		"-bash":      true, // login shell
+		"-zsh":       true,
+		"/bin/zsh":   true,
+		"fish":       true,
+		" sh ":       true,
+		"bashful":    false,
+		"node":       false,
+		"login":      false,
+		"/bin/bash5": false,
+	} {
+		if got := IsShell(name); got != want {
+			t.Errorf("IsShell(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pratik-anurag/portik/internal/audit"
+)
+
+// Audit renders audit entries oldest first, one line each plus the method.
+func Audit(entries []audit.Entry) string {
+	var b strings.Builder
+	b.WriteString("TIME                 USER        ACTION    PORT        TARGET                  RESULT\n")
+	b.WriteString("───────────────────  ──────────  ────────  ──────────  ──────────────────────  ──────────────────────\n")
+	fromTUI := false
+	for _, e := range entries {
+		who := e.User
+		if e.SudoUser != "" {
+			who = e.SudoUser + "(sudo)"
+		}
+		if e.Source == "tui" {
+			e.Action += "*"
+			fromTUI = true
+		}
+		port := "-"
+		if e.Port > 0 {
+			port = fmt.Sprintf("%d/%s", e.Port, e.Proto)
+		}
+		target := e.Owner
+		if e.PID > 0 {
+			target = fmt.Sprintf("%d %s", e.PID, e.Owner)
+		}
+		if e.Container != "" {
+			target = strings.TrimSpace(target + " [" + e.Container + "]")
+		}
+		result := e.Result
+		if !e.OK {
+			result = "FAILED: " + result
+		}
+		if e.Force {
+			result += " (--force)"
+		}
+		fmt.Fprintf(&b, "%-19s  %-10s  %-8s  %-10s  %-22s  %s\n",
+			e.Time.Local().Format("2006-01-02 15:04:05"), trunc(who, 10), trunc(e.Action, 8), port,
+			trunc(dash(target), 22), trunc(result, 60))
+		if e.Method != "" {
+			fmt.Fprintf(&b, "%-19s  ↳ %s\n", "", trunc(e.Method, 100))
+		}
+	}
+	if fromTUI {
+		b.WriteString("* from the TUI\n")
+	}
+	return b.String()
+}
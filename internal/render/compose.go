@@ -0,0 +1,70 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pratik-anurag/portik/internal/compose"
+)
+
+func Compose(p compose.Project, rows []compose.PortStatus, conflicts []compose.Conflict, opt Options) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s (%s)\n", label("COMPOSE", opt), p.Name, p.File)
+
+	if len(rows) == 0 {
+		b.WriteString("  (no published ports)\n")
+		return b.String()
+	}
+
+	b.WriteString("\n")
+	b.WriteString("  PORT         SERVICE         STATUS    OWNER                 PID     CONTAINER\n")
+	b.WriteString("  -----------  --------------  --------  --------------------  ------  ---------\n")
+	for _, r := range rows {
+		owner := r.Owner
+		if r.Error != "" {
+			owner = "ERR: " + r.Error
+		}
+		pid := "-"
+		if r.PID > 0 {
+			pid = fmt.Sprintf("%d", r.PID)
+		}
+		fmt.Fprintf(&b, "  %-11s  %-14s  %-8s  %-20s  %-6s  %s\n",
+			fmt.Sprintf("%d/%s", r.HostPort, r.Proto),
+			trunc(r.Service, 14),
+			composeStatus(r.Status, opt),
+			trunc(dash(owner), 20),
+			pid,
+			dash(r.Container),
+		)
+	}
+
+	b.WriteString("\n")
+	if len(conflicts) == 0 {
+		fmt.Fprintf(&b, "%s none — `docker compose up` should be able to bind every port\n", label("CONFLICTS", opt))
+		return b.String()
+	}
+	b.WriteString(label("CONFLICTS", opt))
+	b.WriteString("\n")
+	for _, c := range conflicts {
+		fmt.Fprintf(&b, "  - %s %s\n", severityLabel("error", opt), c.Summary)
+		if c.Action != "" {
+			fmt.Fprintf(&b, "    %s\n", c.Action)
+		}
+	}
+	return b.String()
+}
+
+func composeStatus(s string, opt Options) string {
+	padded := fmt.Sprintf("%-8s", s)
+	if !opt.Color {
+		return padded
+	}
+	switch s {
+	case "free":
+		return ansiGreen + padded + ansiReset
+	case "project":
+		return ansiCyan + padded + ansiReset
+	default:
+		return ansiRed + padded + ansiReset
+	}
+}
@@ -0,0 +1,36 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pratik-anurag/portik/internal/sys"
+)
+
+// KillPlanRow is one target of a batch kill.
+type KillPlanRow struct {
+	Target string // "1234 node" or "web (3f2a1b9c0d4e)"
+	User   string
+	Ports  string
+	Action string
+}
+
+func KillPlan(rows []KillPlanRow) string {
+	var b strings.Builder
+	b.WriteString("TARGET                  USER        PORTS           ACTION\n")
+	b.WriteString("──────────────────────  ──────────  ──────────────  ──────────────────────\n")
+	for _, r := range rows {
+		fmt.Fprintf(&b, "%-22s  %-10s  %-14s  %s\n", trunc(r.Target, 22), trunc(dash(r.User), 10), trunc(r.Ports, 14), r.Action)
+	}
+	return b.String()
+}
+
+// KillResult renders the outcome for one batch target.
+func KillResult(r KillPlanRow, res sys.ActionResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-22s  %-14s  %s\n", trunc(r.Target, 22), trunc(r.Ports, 14), res.Summary)
+	if res.ExitCode != 0 && res.Details != "" {
+		fmt.Fprintf(&b, "%-22s  %s\n", "", trunc(res.Details, 100))
+	}
+	return b.String()
+}
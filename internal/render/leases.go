@@ -0,0 +1,30 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/reserve"
+)
+
+// Leases renders active port leases. PID is the holder of a detached
+// reservation or the process a broker lease was handed to.
+func Leases(ls []reserve.Lease, now time.Time) string {
+	var b strings.Builder
+	b.WriteString("PORT         BIND             LABEL               PID      USER        EXPIRES\n")
+	b.WriteString("───────────  ───────────────  ──────────────────  ───────  ──────────  ──────────────────────\n")
+	for _, l := range ls {
+		expires := "when released"
+		if l.Client {
+			expires = fmt.Sprintf("when pid %d exits", l.PID)
+		}
+		if !l.Until.IsZero() {
+			expires = fmt.Sprintf("in %s (%s)", l.Until.Sub(now).Round(time.Second), l.Until.Local().Format("15:04:05"))
+		}
+		fmt.Fprintf(&b, "%-11s  %-15s  %-18s  %-7d  %-10s  %s\n",
+			fmt.Sprintf("%d/%s", l.Port, l.Proto), trunc(l.Bind, 15), trunc(dash(l.Label), 18), l.PID,
+			trunc(dash(l.User), 10), expires)
+	}
+	return b.String()
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pratik-anurag/portik/internal/history"
 	"github.com/pratik-anurag/portik/internal/model"
 	"github.com/pratik-anurag/portik/internal/proctree"
 	"github.com/pratik-anurag/portik/internal/sys"
@@ -16,6 +17,7 @@ type Options struct {
 	Verbose      bool
 	NoHints      bool
 	RecentOwners []OwnerEvent
+	AsOf         *history.Snapshot // set when the report was rebuilt from history (who --at)
 }
 
 type OwnerEvent struct {
@@ -27,6 +29,13 @@ func Who(rep model.Report, opt Options) string {
 	opt = normalizeOptions(opt)
 	var b strings.Builder
 	fmt.Fprintf(&b, "%s %d/%s\n", label("PORT", opt), rep.Port, rep.Proto)
+	if opt.AsOf != nil {
+		b.WriteString(asOfLines(*opt.AsOf, opt))
+		if opt.AsOf.Sample == nil {
+			b.WriteString("  (owner unknown: nothing recorded yet)\n")
+			return b.String()
+		}
+	}
 
 	if len(rep.Listeners) == 0 {
 		b.WriteString("  (no listeners)\n")
@@ -310,3 +319,23 @@ const (
 	ansiBlue   = "\x1b[34m"
 	ansiCyan   = "\x1b[36m"
 )
+
+func asOfLines(s history.Snapshot, opt Options) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s (reconstructed from history)\n", label("AS OF", opt), s.At.Local().Format("2006-01-02 15:04:05"))
+	if s.Sample == nil {
+		b.WriteString("  no sample recorded at or before this time\n")
+	} else {
+		fmt.Fprintf(&b, "  nearest sample: %s (%s earlier)", s.Sample.At.Local().Format("2006-01-02 15:04:05"), history.FormatDuration(s.StaleFor()))
+		if s.Sample.Host != "" {
+			fmt.Fprintf(&b, " on %s", s.Sample.Host)
+		}
+		b.WriteString("\n")
+	}
+	if s.Next != nil {
+		fmt.Fprintf(&b, "  next change: %s (%s later) → %s\n", s.Next.At.Local().Format("2006-01-02 15:04:05"), history.FormatDuration(s.Next.At.Sub(s.At)), history.OwnerLabel(*s.Next))
+	} else if s.Sample != nil {
+		b.WriteString("  no later change recorded\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package reserve
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// AllocOptions configures Allocate. With no range, the port is an ephemeral one.
+type AllocOptions struct {
+	FreeOptions
+	Label string
+	TTL   time.Duration // 0 = until released or PID exits
+	PID   int           // client the port is leased to; 0 = this process
+}
+
+// Allocate is the port broker. Probing for a free port and releasing it races
+// with every other caller doing the same; Allocate instead picks a port that is
+// neither leased nor bound while holding the lease directory lock and records a
+// client lease for it, so concurrent callers (parallel test processes) never
+// get the same port until it is released.
+func Allocate(opt AllocOptions) (Lease, error) {
+	if opt.Bind == "" {
+		opt.Bind = "127.0.0.1"
+	}
+	if opt.PID <= 0 {
+		opt.PID = os.Getpid()
+	}
+	unlock, err := lockLeases()
+	if err != nil {
+		return Lease{}, err
+	}
+	defer unlock()
+
+	opt.Leased = leasedIn(activeLeases(), opt.Proto)
+	var port int
+	if opt.RangeStart == 0 && opt.RangeEnd == 0 {
+		port, err = FindFreeEphemeral(opt.FreeOptions)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		port, err = FindFreeInRange(ctx, opt.FreeOptions)
+	}
+	if err != nil {
+		return Lease{}, err
+	}
+	l := Lease{Port: port, Proto: opt.Proto, Bind: opt.Bind, Label: opt.Label, PID: opt.PID, Client: true, Created: time.Now()}
+	if opt.TTL > 0 {
+		l.Until = l.Created.Add(opt.TTL)
+	}
+	if err := writeLease(&l); err != nil {
+		return Lease{}, err
+	}
+	return l, nil
+}
+
+// lockLeases takes the broker lock on LeaseDir.
+func lockLeases() (unlock func(), err error) {
+	dir, err := LeaseDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return lockDir(dir)
+}
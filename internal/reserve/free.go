@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"strings"
 	"time"
 )
 
@@ -16,6 +17,9 @@ type FreeOptions struct {
 	RangeEnd   int
 	// Attempts for random sampling in range; if 0 uses full scan
 	Attempts int
+	// Leased ports (see Leased) are taken even when the bind test would pass,
+	// e.g. a lease on 127.0.0.1 while testing another address.
+	Leased map[int]Lease
 }
 
 func FindFreeInRange(ctx context.Context, opt FreeOptions) (int, error) {
@@ -36,6 +40,9 @@ func FindFreeInRange(ctx context.Context, opt FreeOptions) (int, error) {
 	total := opt.RangeEnd - opt.RangeStart + 1
 
 	try := func(p int) bool {
+		if _, ok := opt.Leased[p]; ok {
+			return false
+		}
 		ok, _ := isBindable(opt.Proto, opt.Bind, p)
 		return ok
 	}
@@ -69,6 +76,15 @@ func FindFreeInRange(ctx context.Context, opt FreeOptions) (int, error) {
 		}
 	}
 
+	var leased []string
+	for p := opt.RangeStart; p <= opt.RangeEnd; p++ {
+		if l, ok := opt.Leased[p]; ok {
+			leased = append(leased, fmt.Sprintf("%d %s", p, l.Describe()))
+		}
+	}
+	if len(leased) > 0 {
+		return 0, fmt.Errorf("no free port found in range %d-%d (%s)", opt.RangeStart, opt.RangeEnd, strings.Join(leased, "; "))
+	}
 	return 0, fmt.Errorf("no free port found in range %d-%d", opt.RangeStart, opt.RangeEnd)
 }
 
@@ -80,8 +96,23 @@ func FindFreeEphemeral(opt FreeOptions) (int, error) {
 		return 0, fmt.Errorf("invalid proto: %s", opt.Proto)
 	}
 
-	if opt.Proto == "tcp" {
-		ln, err := net.Listen("tcp", net.JoinHostPort(opt.Bind, "0"))
+	// the kernel never hands out a port bound on the same address, but a lease
+	// may be held on another one
+	for range 8 {
+		p, err := ephemeral(opt.Proto, opt.Bind)
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := opt.Leased[p]; !ok {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("no free ephemeral port outside leased ports")
+}
+
+func ephemeral(proto, bind string) (int, error) {
+	if proto == "tcp" {
+		ln, err := net.Listen("tcp", net.JoinHostPort(bind, "0"))
 		if err != nil {
 			return 0, err
 		}
@@ -89,7 +120,7 @@ func FindFreeEphemeral(opt FreeOptions) (int, error) {
 		return ln.Addr().(*net.TCPAddr).Port, nil
 	}
 
-	pc, err := net.ListenPacket("udp", net.JoinHostPort(opt.Bind, "0"))
+	pc, err := net.ListenPacket("udp", net.JoinHostPort(bind, "0"))
 	if err != nil {
 		return 0, err
 	}
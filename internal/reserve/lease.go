@@ -0,0 +1,293 @@
+package reserve
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pratik-anurag/portik/internal/pidctl"
+)
+
+// Lease is a reservation recorded as one JSON file under LeaseDir. It ends at
+// Until, when it is released, or when PID exits.
+//
+// A detached reservation (reserve --detach) is held: PID is a holder process
+// (`portik reserve hold`) keeping the port bound. A broker lease (Allocate) is a
+// client lease: the port is left unbound for PID, the process it was handed to,
+// to bind itself.
+type Lease struct {
+	Port    int       `json:"port"`
+	Proto   string    `json:"proto"`
+	Bind    string    `json:"bind"`
+	Label   string    `json:"label,omitempty"`
+	PID     int       `json:"pid"`              // holder, or the client of a broker lease
+	Client  bool      `json:"client,omitempty"` // broker lease; PID is not a holder
+	User    string    `json:"user,omitempty"`
+	Created time.Time `json:"created"`
+	Until   time.Time `json:"until,omitzero"` // zero = until released
+}
+
+// HolderArgs is how the holder process is invoked (after the portik executable).
+var HolderArgs = []string{"reserve", "hold"}
+
+// LeaseDir returns $PORTIK_LEASE_DIR or ~/.portik/leases.
+func LeaseDir() (string, error) {
+	if d := strings.TrimSpace(os.Getenv("PORTIK_LEASE_DIR")); d != "" {
+		return d, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".portik", "leases"), nil
+}
+
+// LeasePath is the file recording the lease on port/proto.
+func LeasePath(proto string, port int) (string, error) {
+	dir, err := LeaseDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%d.json", proto, port)), nil
+}
+
+// Expired reports whether the lease's TTL has passed.
+func (l Lease) Expired(now time.Time) bool {
+	return !l.Until.IsZero() && !now.Before(l.Until)
+}
+
+// Describe is a one-line "who leased it" for free/use/scan and error messages.
+func (l Lease) Describe() string {
+	s := "leased"
+	if l.Label != "" {
+		s += fmt.Sprintf(" as %q", l.Label)
+	}
+	if l.User != "" {
+		s += " by " + l.User
+	}
+	return s + " " + l.Expiry()
+}
+
+// Expiry is "until 15:04:05", "until released" or, for a client lease,
+// "until pid N exits".
+func (l Lease) Expiry() string {
+	if l.Until.IsZero() && l.Client {
+		return fmt.Sprintf("until pid %d exits", l.PID)
+	}
+	if l.Until.IsZero() {
+		return "until released"
+	}
+	return "until " + l.Until.Local().Format("15:04:05")
+}
+
+// WriteLease records l under the broker lock, replacing any earlier record on
+// the same port unless it is another process's active lease. It fills in
+// Created and User when they are empty.
+func WriteLease(l *Lease) error {
+	unlock, err := lockLeases()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if cur, ok := leasedIn(activeLeases(), l.Proto)[l.Port]; ok && cur.PID != l.PID {
+		return fmt.Errorf("%d/%s is already %s (pid %d)", l.Port, l.Proto, cur.Describe(), cur.PID)
+	}
+	return writeLease(l)
+}
+
+// writeLease writes the record; the caller holds the broker lock.
+func writeLease(l *Lease) error {
+	path, err := LeasePath(l.Proto, l.Port)
+	if err != nil {
+		return err
+	}
+	if l.Created.IsZero() {
+		l.Created = time.Now()
+	}
+	if l.User == "" {
+		if u, err := user.Current(); err == nil {
+			l.User = u.Username
+		}
+	}
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".lease-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// RemoveLease deletes the record of the lease on port/proto if it still names pid.
+func RemoveLease(proto string, port, pid int) {
+	path, err := LeasePath(proto, port)
+	if err != nil {
+		return
+	}
+	unlock, err := lockLeases()
+	if err != nil {
+		return
+	}
+	defer unlock()
+	if l, err := readLease(path); err == nil && l.PID == pid {
+		_ = os.Remove(path)
+	}
+}
+
+// Leases returns the active leases, sorted by port. Records whose holder has
+// exited or whose TTL has passed are removed, under the broker lock so that a
+// record Allocate has just rewritten is never collected.
+func Leases() ([]Lease, error) {
+	dir, err := LeaseDir()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	unlock, err := lockLeases()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return activeLeases(), nil
+}
+
+// activeLeases is Leases for a caller that holds the broker lock.
+func activeLeases() []Lease {
+	dir, err := LeaseDir()
+	if err != nil {
+		return nil
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	now := time.Now()
+	var out []Lease
+	for _, f := range files {
+		l, err := readLease(f)
+		if err != nil {
+			continue
+		}
+		if l.PID <= 0 || !l.live() || l.Expired(now) {
+			_ = os.Remove(f)
+			continue
+		}
+		out = append(out, l)
+	}
+	slices.SortFunc(out, func(a, b Lease) int {
+		if a.Port != b.Port {
+			return a.Port - b.Port
+		}
+		return strings.Compare(a.Proto, b.Proto)
+	})
+	return out
+}
+
+// Leased returns the active leases for proto by port. Lookup errors are treated
+// as "no leases": callers still bind-test every port.
+func Leased(proto string) map[int]Lease {
+	ls, _ := Leases()
+	return leasedIn(ls, proto)
+}
+
+func leasedIn(ls []Lease, proto string) map[int]Lease {
+	out := map[int]Lease{}
+	for _, l := range ls {
+		if l.Proto == proto {
+			out[l.Port] = l
+		}
+	}
+	return out
+}
+
+// FindLeases returns the active leases matching key, a port number or a label.
+// proto narrows the match; empty matches both.
+func FindLeases(key, proto string) ([]Lease, error) {
+	ls, err := Leases()
+	if err != nil {
+		return nil, err
+	}
+	port, perr := strconv.Atoi(key)
+	var out []Lease
+	for _, l := range ls {
+		if proto != "" && l.Proto != proto {
+			continue
+		}
+		if perr == nil && l.Port == port || perr != nil && l.Label == key {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+// Release stops the lease's holder, which frees the port, and removes the record.
+// A client lease is only removed: its process is left alone.
+func Release(l Lease) error {
+	if !l.Client && isHolder(l.PID) {
+		if err := pidctl.Stop(l.PID); err != nil {
+			return err
+		}
+		if !pidctl.WaitExit(l.PID, 3*time.Second) {
+			return fmt.Errorf("holder pid %d did not exit", l.PID)
+		}
+	}
+	// the record may already name someone else if the lease expired
+	RemoveLease(l.Proto, l.Port, l.PID)
+	return nil
+}
+
+func (l Lease) live() bool {
+	if l.Client {
+		return pidctl.Alive(l.PID)
+	}
+	return isHolder(l.PID)
+}
+
+// isHolder reports whether pid is still a lease holder, guarding against an
+// unrelated process that reused the pid of a holder killed without cleaning up
+// (and against unreaped holders). Where ps is unavailable a live pid is trusted.
+func isHolder(pid int) bool {
+	if !pidctl.Alive(pid) {
+		return false
+	}
+	out, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "command=").Output()
+	if err != nil {
+		return true
+	}
+	return strings.Contains(string(out), strings.Join(HolderArgs, " "))
+}
+
+func readLease(path string) (Lease, error) {
+	var l Lease
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return l, err
+	}
+	err = json.Unmarshal(b, &l)
+	return l, err
+}
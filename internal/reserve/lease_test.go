@@ -0,0 +1,84 @@
+package reserve
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestExpired(t *testing.T) {
+	now := time.Now()
+	if (Lease{}).Expired(now) {
+		t.Fatal("a lease without Until never expires")
+	}
+	if (Lease{Until: now.Add(time.Minute)}).Expired(now) {
+		t.Fatal("a lease before its Until is not expired")
+	}
+	if !(Lease{Until: now}).Expired(now) {
+		t.Fatal("a lease expires at Until")
+	}
+}
+
+func TestLeases(t *testing.T) {
+	t.Setenv("PORTIK_LEASE_DIR", t.TempDir())
+	me := os.Getpid()
+	dead := exec.Command("true")
+	if err := dead.Run(); err != nil {
+		t.Skip("no true(1):", err)
+	}
+
+	// client leases name this (live) test process; holder checks don't apply
+	for _, l := range []Lease{
+		{Port: 9001, Proto: "tcp", Label: "api", PID: me, Client: true},
+		{Port: 9001, Proto: "udp", Label: "dns", PID: me, Client: true},
+		{Port: 9002, Proto: "tcp", Label: "api", PID: me, Client: true, Until: time.Now().Add(time.Hour)},
+		{Port: 9003, Proto: "tcp", Label: "gone", PID: dead.Process.Pid, Client: true},
+		{Port: 9004, Proto: "tcp", Label: "old", PID: me, Client: true, Until: time.Now().Add(-time.Second)},
+	} {
+		if err := WriteLease(&l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := WriteLease(&Lease{Port: 9001, Proto: "tcp", PID: dead.Process.Pid}); err == nil {
+		t.Fatal("another process's active lease must not be replaced")
+	}
+
+	ls, err := Leases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ls) != 3 {
+		t.Fatalf("want 3 active leases, got %+v", ls)
+	}
+	for _, port := range []int{9003, 9004} {
+		path, _ := LeasePath("tcp", port)
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("record for %d should have been removed (dead pid / expired): %v", port, err)
+		}
+	}
+
+	byPort, _ := FindLeases("9001", "")
+	if len(byPort) != 2 {
+		t.Fatalf("port 9001 matches tcp and udp, got %+v", byPort)
+	}
+	if tcp, _ := FindLeases("9001", "tcp"); len(tcp) != 1 || tcp[0].Label != "api" {
+		t.Fatalf("proto narrows the match, got %+v", tcp)
+	}
+	byLabel, _ := FindLeases("api", "")
+	if len(byLabel) != 2 || byLabel[0].Port != 9001 || byLabel[1].Port != 9002 {
+		t.Fatalf("label api matches 9001 and 9002, got %+v", byLabel)
+	}
+	if none, _ := FindLeases("9999", ""); len(none) != 0 {
+		t.Fatalf("unknown port, got %+v", none)
+	}
+
+	RemoveLease("tcp", 9002, me+1)
+	if got, _ := FindLeases("9002", "tcp"); len(got) != 1 {
+		t.Fatal("RemoveLease must leave a record naming another pid")
+	}
+	RemoveLease("tcp", 9002, me)
+	if got, _ := FindLeases("9002", "tcp"); len(got) != 0 {
+		t.Fatalf("RemoveLease with the record's pid removes it, got %+v", got)
+	}
+}
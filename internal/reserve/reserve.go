@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"os"
 	"time"
 )
 
@@ -11,21 +12,27 @@ type Reservation struct {
 	Port  int       `json:"port"`
 	Proto string    `json:"proto"`
 	Bind  string    `json:"bind"`
-	Until time.Time `json:"until"`
+	Until time.Time `json:"until,omitzero"` // ctx deadline; zero = until cancelled
 }
 
 type Handle interface {
 	Close() error
+	// File returns a duplicate of the bound socket, to hand to another process.
+	File() (*os.File, error)
 }
 
 type tcpHandle struct{ ln net.Listener }
 
 func (h tcpHandle) Close() error { return h.ln.Close() }
 
+func (h tcpHandle) File() (*os.File, error) { return h.ln.(*net.TCPListener).File() }
+
 type udpHandle struct{ pc net.PacketConn }
 
 func (h udpHandle) Close() error { return h.pc.Close() }
 
+func (h udpHandle) File() (*os.File, error) { return h.pc.(*net.UDPConn).File() }
+
 // Reserve binds a port (or port=0 for ephemeral) and holds it until ctx is done.
 // Returns reservation info + a handle you must Close (usually via defer).
 func Reserve(ctx context.Context, proto, bind string, port int) (Reservation, Handle, error) {
@@ -44,7 +51,8 @@ func Reserve(ctx context.Context, proto, bind string, port int) (Reservation, Ha
 			return Reservation{}, nil, err
 		}
 		p := ln.Addr().(*net.TCPAddr).Port
-		res := Reservation{Port: p, Proto: "tcp", Bind: bind, Until: time.Now().Add(0)}
+		res := Reservation{Port: p, Proto: "tcp", Bind: bind}
+		res.Until, _ = ctx.Deadline()
 		go func() {
 			<-ctx.Done()
 			_ = ln.Close()
@@ -57,7 +65,8 @@ func Reserve(ctx context.Context, proto, bind string, port int) (Reservation, Ha
 		return Reservation{}, nil, err
 	}
 	p := pc.LocalAddr().(*net.UDPAddr).Port
-	res := Reservation{Port: p, Proto: "udp", Bind: bind, Until: time.Now().Add(0)}
+	res := Reservation{Port: p, Proto: "udp", Bind: bind}
+	res.Until, _ = ctx.Deadline()
 	go func() {
 		<-ctx.Done()
 		_ = pc.Close()
@@ -17,6 +17,10 @@ import (
 // postgres 8123 me  6u  IPv6 ... TCP [::1]:5432 (LISTEN)
 var reLsof = regexp.MustCompile(`^(?P<cmd>\S+)\s+(?P<pid>\d+)\s+(?P<user>\S+)\s+.*\sTCP\s+(?P<addr>\S+)\s+\((?P<state>[^)]+)\)\s*$`)
 
+// lsof -nP -iUDP
+// mDNSRespo 310 _mdnsresponder 7u IPv4 ... UDP *:5353
+var reLsofUDP = regexp.MustCompile(`^(?P<cmd>\S+)\s+(?P<pid>\d+)\s+(?P<user>\S+)\s+.*\sUDP\s+(?P<addr>\S+)\s*$`)
+
 func inspectDarwin(port int, proto string, includeConnections bool) ([]model.Listener, []model.Conn, error) {
 	var listeners []model.Listener
 	var conns []model.Conn
@@ -73,6 +77,85 @@ func inspectDarwin(port int, proto string, includeConnections bool) ([]model.Lis
 	return listeners, conns, nil
 }
 
+func listeningDarwin(proto string) ([]model.Listener, error) {
+	re, args := reLsof, []string{"-nP", "-iTCP", "-sTCP:LISTEN"}
+	if proto == "udp" {
+		re, args = reLsofUDP, []string{"-nP", "-iUDP"}
+	}
+	out, err := exec.Command("lsof", args...).Output()
+	if err != nil && len(out) == 0 {
+		// lsof exits 1 when nothing matches
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("lsof: %w", err)
+	}
+	var listeners []model.Listener
+	for _, line := range splitLines(out) {
+		m := re.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		addr := m[re.SubexpIndex("addr")]
+		if strings.Contains(addr, "->") {
+			continue // connected UDP socket
+		}
+		pid, err := strconv.Atoi(m[re.SubexpIndex("pid")])
+		if err != nil {
+			continue
+		}
+		state := "LISTEN"
+		if proto == "udp" {
+			state = "UNCONN"
+		}
+		ip, p := parseLsofAddr(addr)
+		listeners = append(listeners, model.Listener{
+			LocalIP:   ip,
+			LocalPort: p,
+			Family:    familyFromIP(ip),
+			State:     state,
+			PID:       int32(pid),
+			ProcName:  m[re.SubexpIndex("cmd")],
+			User:      m[re.SubexpIndex("user")],
+		})
+	}
+	return listeners, nil
+}
+
+func connectionsDarwin() ([]model.Conn, error) {
+	out, err := exec.Command("lsof", "-nP", "-iTCP").Output()
+	if err != nil && len(out) == 0 {
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil, nil // nothing matched
+		}
+		return nil, fmt.Errorf("lsof: %w", err)
+	}
+	var conns []model.Conn
+	for _, line := range splitLines(out) {
+		m := reLsof.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		state := strings.ToUpper(strings.TrimSpace(m[reLsof.SubexpIndex("state")]))
+		pid, err := strconv.Atoi(m[reLsof.SubexpIndex("pid")])
+		if err != nil || state == "LISTEN" {
+			continue
+		}
+		lip, lp, rip, rp := parseLsofConn(m[reLsof.SubexpIndex("addr")])
+		conns = append(conns, model.Conn{
+			LocalIP:    lip,
+			LocalPort:  lp,
+			RemoteIP:   rip,
+			RemotePort: rp,
+			Family:     familyFromIP(lip),
+			State:      state,
+			PID:        int32(pid),
+			ProcName:   m[reLsof.SubexpIndex("cmd")],
+		})
+	}
+	return conns, nil
+}
+
 func splitLines(b []byte) []string {
 	s := strings.TrimSpace(string(bytes.TrimSpace(b)))
 	if s == "" {
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os/exec"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/pratik-anurag/portik/internal/model"
@@ -33,6 +34,71 @@ func inspectLinux(port int, proto string, includeConnections bool) ([]model.List
 	ssArgs = append(ssArgs, fmt.Sprintf("sport = :%d", port))
 
 	out, _ := exec.Command("ss", ssArgs...).Output()
+	listeners = parseSSListeners(out)
+
+	if includeConnections && proto == "tcp" {
+		args := []string{"-H", "-tanp", fmt.Sprintf("( sport = :%d or dport = :%d )", port, port)}
+		out2, _ := exec.Command("ss", args...).Output()
+		conns = parseSSConns(out2)
+	}
+
+	return listeners, conns, nil
+}
+
+func listeningLinux(proto string) ([]model.Listener, error) {
+	flags := "-ltnp"
+	if proto == "udp" {
+		flags = "-lunp"
+	}
+	out, err := exec.Command("ss", "-H", flags).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ss: %w", err)
+	}
+	return parseSSListeners(out), nil
+}
+
+func connectionsLinux() ([]model.Conn, error) {
+	out, err := exec.Command("ss", "-H", "-tanp").Output()
+	if err != nil {
+		return nil, fmt.Errorf("ss: %w", err)
+	}
+	conns := parseSSConns(out)
+	kept := conns[:0]
+	for _, c := range conns {
+		if c.State != "LISTEN" {
+			kept = append(kept, c)
+		}
+	}
+	return kept, nil
+}
+
+// parseSSConns parses `ss -tanp` lines into connections.
+func parseSSConns(out []byte) []model.Conn {
+	var conns []model.Conn
+	for _, line := range splitLines(out) {
+		m := reSS.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		pid, pname := parseUsers(m[reSS.SubexpIndex("users")])
+		lip, lp := splitHostPort(m[reSS.SubexpIndex("laddr")])
+		rip, rp := splitHostPort(m[reSS.SubexpIndex("raddr")])
+		conns = append(conns, model.Conn{
+			LocalIP:    lip,
+			LocalPort:  lp,
+			RemoteIP:   rip,
+			RemotePort: rp,
+			Family:     familyFromIP(lip),
+			State:      strings.ToUpper(m[reSS.SubexpIndex("state")]),
+			PID:        int32(pid),
+			ProcName:   pname,
+		})
+	}
+	return conns
+}
+
+func parseSSListeners(out []byte) []model.Listener {
+	var listeners []model.Listener
 	for _, line := range splitLines(out) {
 		line = strings.TrimSpace(line)
 		if line == "" {
@@ -44,52 +110,24 @@ func inspectLinux(port int, proto string, includeConnections bool) ([]model.List
 		}
 		laddr := m[reSS.SubexpIndex("laddr")]
 		state := strings.ToUpper(m[reSS.SubexpIndex("state")])
-		pid, pname := parseUsers(m[reSS.SubexpIndex("users")])
+		users := m[reSS.SubexpIndex("users")]
+		pid, pname := parseUsers(users)
 		ip, p := splitHostPort(laddr)
 
-		listeners = append(listeners, model.Listener{
+		l := model.Listener{
 			LocalIP:   ip,
 			LocalPort: p,
 			Family:    familyFromIP(ip),
 			State:     state,
 			PID:       int32(pid),
 			ProcName:  pname,
-		})
-	}
-
-	if includeConnections && proto == "tcp" {
-		args := []string{"-H", "-tanp", fmt.Sprintf("( sport = :%d or dport = :%d )", port, port)}
-		out2, _ := exec.Command("ss", args...).Output()
-		for _, line := range splitLines(out2) {
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
-			m := reSS.FindStringSubmatch(line)
-			if m == nil {
-				continue
-			}
-			state := strings.ToUpper(m[reSS.SubexpIndex("state")])
-			laddr := m[reSS.SubexpIndex("laddr")]
-			raddr := m[reSS.SubexpIndex("raddr")]
-			pid, pname := parseUsers(m[reSS.SubexpIndex("users")])
-			lip, lp := splitHostPort(laddr)
-			rip, rp := splitHostPort(raddr)
-
-			conns = append(conns, model.Conn{
-				LocalIP:    lip,
-				LocalPort:  lp,
-				RemoteIP:   rip,
-				RemotePort: rp,
-				Family:     familyFromIP(lip),
-				State:      state,
-				PID:        int32(pid),
-				ProcName:   pname,
-			})
 		}
+		if pids := usersPIDs(users); len(pids) > 1 {
+			l.PIDs = pids
+		}
+		listeners = append(listeners, l)
 	}
-
-	return listeners, conns, nil
+	return listeners
 }
 
 func splitLines(b []byte) []string {
@@ -113,6 +151,19 @@ func parseUsers(users string) (pid int, proc string) {
 	return
 }
 
+// usersPIDs returns every distinct pid sharing the socket: a prefork master and its
+// workers all hold the listening fd.
+func usersPIDs(users string) []int32 {
+	var pids []int32
+	for _, m := range reUsersPid.FindAllStringSubmatch(users, -1) {
+		pid := int32(parseInt(m[1]))
+		if !slices.Contains(pids, pid) {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
 func splitHostPort(addr string) (string, int) {
 	addr = strings.TrimSpace(addr)
 
@@ -0,0 +1,45 @@
+//go:build linux
+
+package sockets
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestUsersPIDs(t *testing.T) {
+	for _, tc := range []struct {
+		users string
+		want  []int32
+	}{
+		{`users:(("postgres",pid=8123,fd=7))`, []int32{8123}},
+		// prefork: the master and every worker share the socket, some on several fds
+		{`users:(("nginx",pid=912,fd=6),("nginx",pid=911,fd=6),("nginx",pid=900,fd=6),("nginx",pid=900,fd=7))`, []int32{912, 911, 900}},
+		{``, nil},
+		{`users:(("weird",fd=3))`, nil},
+	} {
+		if got := usersPIDs(tc.users); !slices.Equal(got, tc.want) {
+			t.Errorf("usersPIDs(%q) = %v, want %v", tc.users, got, tc.want)
+		}
+	}
+}
+
+func TestParseSSConns(t *testing.T) {
+	out := []byte(`ESTAB      0 0 127.0.0.1:5432 127.0.0.1:50412 users:(("postgres",pid=8200,fd=9))
+TIME-WAIT  0 0 127.0.0.1:50400 127.0.0.1:5432
+CLOSE-WAIT 1 0 [::1]:8080 [::1]:40222 users:(("node",pid=77,fd=21))
+`)
+	conns := parseSSConns(out)
+	if len(conns) != 3 {
+		t.Fatalf("want 3 connections, got %+v", conns)
+	}
+	if c := conns[0]; c.State != "ESTAB" || c.LocalPort != 5432 || c.RemotePort != 50412 || c.PID != 8200 || c.ProcName != "postgres" {
+		t.Fatalf("first: %+v", c)
+	}
+	if c := conns[1]; c.State != "TIME-WAIT" || c.RemotePort != 5432 || c.PID != 0 {
+		t.Fatalf("second: %+v", c)
+	}
+	if c := conns[2]; c.State != "CLOSE-WAIT" || c.LocalIP != "::1" || c.Family != "ipv6" {
+		t.Fatalf("third: %+v", c)
+	}
+}
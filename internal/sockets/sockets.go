@@ -7,3 +7,15 @@ import "github.com/pratik-anurag/portik/internal/model"
 func Inspect(port int, proto string, includeConnections bool) ([]model.Listener, []model.Conn, error) {
 	return inspect(port, proto, includeConnections)
 }
+
+// Listening returns every listening socket of proto on the system, from a single
+// enumeration. Listeners are not enriched.
+func Listening(proto string) ([]model.Listener, error) {
+	return listening(proto)
+}
+
+// Connections returns every TCP connection (any state but LISTEN) on the system,
+// from a single enumeration.
+func Connections() ([]model.Conn, error) {
+	return connections()
+}
@@ -7,3 +7,11 @@ import "github.com/pratik-anurag/portik/internal/model"
 func inspect(port int, proto string, includeConnections bool) ([]model.Listener, []model.Conn, error) {
 	return inspectDarwin(port, proto, includeConnections)
 }
+
+func listening(proto string) ([]model.Listener, error) {
+	return listeningDarwin(proto)
+}
+
+func connections() ([]model.Conn, error) {
+	return connectionsDarwin()
+}
@@ -11,3 +11,11 @@ import (
 func inspect(port int, proto string, includeConnections bool) ([]model.Listener, []model.Conn, error) {
 	return nil, nil, fmt.Errorf("unsupported OS for socket inspection")
 }
+
+func listening(proto string) ([]model.Listener, error) {
+	return nil, fmt.Errorf("unsupported OS for socket inspection")
+}
+
+func connections() ([]model.Conn, error) {
+	return nil, fmt.Errorf("unsupported OS for socket inspection")
+}
@@ -0,0 +1,56 @@
+//go:build linux
+
+package sys
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CaptureProcess reads argv, cwd, environment and credentials from /proc. Files
+// that cannot be read (another user's process without root) are left empty.
+func CaptureProcess(pid int32, cmdline string) ProcessContext {
+	pc := ProcessContext{PID: pid, Cmdline: cmdline, UID: -1, GID: -1}
+	dir := "/proc/" + itoa32(pid)
+	if b, err := os.ReadFile(dir + "/cmdline"); err == nil {
+		pc.Argv = splitNUL(b)
+	}
+	if b, err := os.ReadFile(dir + "/environ"); err == nil {
+		pc.Env = splitNUL(b)
+	}
+	if cwd, err := os.Readlink(dir + "/cwd"); err == nil {
+		pc.Cwd = cwd
+	}
+	if exe, err := os.Readlink(dir + "/exe"); err == nil && !strings.HasSuffix(exe, " (deleted)") {
+		pc.Exe = exe
+	}
+	if b, err := os.ReadFile(dir + "/status"); err == nil {
+		for _, line := range strings.Split(string(b), "\n") {
+			f := strings.Fields(line)
+			if len(f) < 2 {
+				continue
+			}
+			switch f[0] {
+			case "Uid:":
+				pc.UID, _ = strconv.Atoi(f[1])
+			case "Gid:":
+				pc.GID, _ = strconv.Atoi(f[1])
+			}
+		}
+	}
+	return pc
+}
+
+func splitNUL(b []byte) []string {
+	b = bytes.TrimRight(b, "\x00")
+	if len(b) == 0 {
+		return nil
+	}
+	var out []string
+	for _, p := range bytes.Split(b, []byte{0}) {
+		out = append(out, string(p))
+	}
+	return out
+}
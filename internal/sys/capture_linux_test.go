@@ -0,0 +1,23 @@
+package sys
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitNUL(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"\x00", nil},
+		{"nginx\x00-g\x00daemon off;\x00", []string{"nginx", "-g", "daemon off;"}},
+		{"nginx: master process /usr/sbin/nginx", []string{"nginx: master process /usr/sbin/nginx"}}, // rewritten title, no NULs
+		{"a\x00\x00b\x00", []string{"a", "", "b"}},                                                   // empty argument
+	} {
+		if got := splitNUL([]byte(tc.in)); !slices.Equal(got, tc.want) {
+			t.Errorf("splitNUL(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
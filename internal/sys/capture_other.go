@@ -0,0 +1,29 @@
+//go:build !linux && !windows
+
+package sys
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// CaptureProcess collects what ps and lsof expose without /proc: the working
+// directory and credentials. argv and the environment are not available
+// unflattened, so the restart re-runs cmdline through a shell.
+func CaptureProcess(pid int32, cmdline string) ProcessContext {
+	pc := ProcessContext{PID: pid, Cmdline: cmdline, UID: -1, GID: -1}
+	if f := strings.Fields(psField(pid, "uid=,rgid=")); len(f) == 2 {
+		pc.UID, _ = strconv.Atoi(f[0])
+		pc.GID, _ = strconv.Atoi(f[1])
+	}
+	out, err := exec.Command("lsof", "-a", "-p", itoa32(pid), "-d", "cwd", "-Fn").Output()
+	if err == nil {
+		for _, line := range strings.Split(string(out), "\n") {
+			if cwd, ok := strings.CutPrefix(line, "n"); ok && strings.HasPrefix(cwd, "/") {
+				pc.Cwd = cwd
+			}
+		}
+	}
+	return pc
+}
@@ -0,0 +1,62 @@
+package sys
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// ComposeRestart describes a compose-level restart of a single service.
+type ComposeRestart struct {
+	Runtime     string // docker|podman (default docker)
+	Project     string
+	Service     string
+	WorkingDir  string
+	ConfigFiles []string
+	Recreate    bool // `up -d --force-recreate <service>` instead of `restart <service>`
+}
+
+// ComposeArgs returns the full argv (runtime first) used to restart the service, so
+// callers can show it in confirmation prompts.
+func ComposeArgs(opt ComposeRestart, timeout time.Duration) []string {
+	rt := opt.Runtime
+	if rt == "" {
+		rt = "docker"
+	}
+	args := []string{rt, "compose", "-p", opt.Project}
+	if opt.WorkingDir != "" && dirExists(opt.WorkingDir) {
+		args = append(args, "--project-directory", opt.WorkingDir)
+	}
+	if filesExist(opt.ConfigFiles) {
+		for _, f := range opt.ConfigFiles {
+			args = append(args, "-f", f)
+		}
+	}
+	sec := int(timeout.Seconds())
+	if sec < 1 {
+		sec = 1
+	}
+	if opt.Recreate {
+		args = append(args, "up", "-d", "--force-recreate", "-t", fmt.Sprintf("%d", sec), opt.Service)
+	} else {
+		args = append(args, "restart", "-t", fmt.Sprintf("%d", sec), opt.Service)
+	}
+	return args
+}
+
+func dirExists(p string) bool {
+	st, err := os.Stat(p)
+	return err == nil && st.IsDir()
+}
+
+func filesExist(files []string) bool {
+	if len(files) == 0 {
+		return false
+	}
+	for _, f := range files {
+		if st, err := os.Stat(f); err != nil || st.IsDir() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package sys
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestComposeArgs(t *testing.T) {
+	dir := t.TempDir()
+	base, override := filepath.Join(dir, "compose.yaml"), filepath.Join(dir, "compose.override.yaml")
+	for _, f := range []string{base, override} {
+		if err := os.WriteFile(f, []byte("services: {}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		opt     ComposeRestart
+		timeout time.Duration
+		want    []string
+	}{
+		{"docker by default", ComposeRestart{Project: "shop", Service: "db"}, 10 * time.Second,
+			[]string{"docker", "compose", "-p", "shop", "restart", "-t", "10", "db"}},
+		{"podman", ComposeRestart{Runtime: "podman", Project: "shop", Service: "db"}, 10 * time.Second,
+			[]string{"podman", "compose", "-p", "shop", "restart", "-t", "10", "db"}},
+		{"project dir and files", ComposeRestart{Project: "shop", Service: "web", WorkingDir: dir, ConfigFiles: []string{base, override}}, 5 * time.Second,
+			[]string{"docker", "compose", "-p", "shop", "--project-directory", dir, "-f", base, "-f", override, "restart", "-t", "5", "web"}},
+		// labels from another machine: a missing file drops every -f, a missing dir
+		// drops --project-directory, and compose falls back to -p alone
+		{"missing files", ComposeRestart{Project: "shop", Service: "web", WorkingDir: filepath.Join(dir, "gone"), ConfigFiles: []string{base, filepath.Join(dir, "gone.yaml")}}, 5 * time.Second,
+			[]string{"docker", "compose", "-p", "shop", "restart", "-t", "5", "web"}},
+		{"recreate", ComposeRestart{Runtime: "podman", Project: "shop", Service: "web", ConfigFiles: []string{base}, Recreate: true}, 30 * time.Second,
+			[]string{"podman", "compose", "-p", "shop", "-f", base, "up", "-d", "--force-recreate", "-t", "30", "web"}},
+		{"timeout floor", ComposeRestart{Project: "shop", Service: "db"}, 200 * time.Millisecond,
+			[]string{"docker", "compose", "-p", "shop", "restart", "-t", "1", "db"}},
+	} {
+		if got := ComposeArgs(tc.opt, tc.timeout); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
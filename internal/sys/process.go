@@ -0,0 +1,103 @@
+package sys
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ProcessContext is how a process was launched, captured before it is stopped so a
+// restart can relaunch it the same way. Fields the platform cannot provide stay
+// empty and the restart falls back to portik's own (directory, environment) or to
+// re-running Cmdline through a shell (argv).
+type ProcessContext struct {
+	PID     int32
+	Argv    []string // original argv, unflattened
+	Cmdline string   // flattened command line as reported by ps
+	Exe     string   // resolved executable, when known
+	Cwd     string
+	Env     []string
+	UID     int // -1 if unknown
+	GID     int // -1 if unknown
+}
+
+// Describe renders the command the restart will run, for prompts.
+func (pc ProcessContext) Describe() string {
+	if len(pc.Argv) == 0 {
+		return pc.Cmdline
+	}
+	parts := make([]string, len(pc.Argv))
+	for i, a := range pc.Argv {
+		parts[i] = shellQuote(a)
+	}
+	return strings.Join(parts, " ")
+}
+
+// RestartLogPath returns ~/.portik/logs/restart-<port>.log, where the output of a
+// restarted process goes.
+func RestartLogPath(port int) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".portik", "logs", "restart-"+strconv.Itoa(port)+".log"), nil
+}
+
+// lookExe resolves argv[0] the way the original exec did: relative to the
+// process's cwd when it contains a slash, otherwise through its own PATH. It
+// returns "" unless that names an executable file; a process that rewrote its
+// title ("nginx: master process /usr/sbin/nginx") has no usable argv[0].
+func lookExe(argv0, cwd string, env []string) string {
+	switch {
+	case argv0 == "":
+		return ""
+	case filepath.IsAbs(argv0):
+		return executable(argv0)
+	case strings.Contains(argv0, "/"):
+		return executable(filepath.Join(cwd, argv0))
+	}
+	path := os.Getenv("PATH")
+	for _, kv := range env {
+		if v, ok := strings.CutPrefix(kv, "PATH="); ok {
+			path = v
+		}
+	}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(cwd, dir)
+		}
+		if p := executable(filepath.Join(dir, argv0)); p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+// executable returns p if it is an executable regular file, else "".
+func executable(p string) string {
+	if st, err := os.Stat(p); err == nil && !st.IsDir() && st.Mode()&0o111 != 0 {
+		return p
+	}
+	return ""
+}
+
+// sameFile reports whether a and b name the same file, following symlinks.
+func sameFile(a, b string) bool {
+	sa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	sb, err := os.Stat(b)
+	return err == nil && os.SameFile(sa, sb)
+}
+
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`!*?[]{}()<>|&;#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
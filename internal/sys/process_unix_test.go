@@ -0,0 +1,102 @@
+//go:build !windows
+
+package sys
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeExe creates an executable script at dir/name.
+func writeExe(t *testing.T, dir, name string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLookExe(t *testing.T) {
+	cwd := t.TempDir()
+	bin := filepath.Join(cwd, "bin")
+	server := writeExe(t, cwd, "bin/server")
+	if err := os.WriteFile(filepath.Join(cwd, "notes"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env := []string{"HOME=/nowhere", "PATH=/nonexistent:" + bin}
+
+	for _, tc := range []struct{ argv0, want string }{
+		{server, server},
+		{"./bin/server", server},
+		{"bin/server", server},
+		{"server", server}, // through the process's PATH, not portik's
+		{filepath.Join(cwd, "missing"), ""},
+		{"./notes", ""}, // not executable
+		{"./bin", ""},   // a directory
+		{"nginx: master process /usr/sbin/nginx -g daemon off;", ""},
+		{"", ""},
+	} {
+		if got := lookExe(tc.argv0, cwd, env); got != tc.want {
+			t.Errorf("lookExe(%q) = %q, want %q", tc.argv0, got, tc.want)
+		}
+	}
+}
+
+func TestRelaunchCommand(t *testing.T) {
+	cwd := t.TempDir()
+	server := writeExe(t, cwd, "server")
+	writeExe(t, cwd, "other") // on PATH, but not what the process runs
+	env := []string{"PATH=" + cwd}
+
+	for _, tc := range []struct {
+		name     string
+		pc       ProcessContext
+		wantPath string // "" = expect an error; "sh" = login shell fallback
+		wantArgs []string
+	}{
+		{"argv", ProcessContext{Argv: []string{"./server", "-p", "80"}, Exe: server, Cwd: cwd, Env: env},
+			server, []string{"./server", "-p", "80"}},
+		{"argv without exe", ProcessContext{Argv: []string{"server"}, Cwd: cwd, Env: env},
+			server, []string{"server"}},
+		{"rewritten title", ProcessContext{Argv: []string{"nginx: master process /usr/sbin/nginx"}, Exe: server, Cwd: cwd},
+			server, []string{"nginx: master process /usr/sbin/nginx"}},
+		{"title names another program", ProcessContext{Argv: []string{"other", "--flag"}, Exe: server, Cwd: cwd, Env: env},
+			server, []string{"other", "--flag"}},
+		{"rewritten title, exe unknown", ProcessContext{Argv: []string{"postgres: checkpointer"}, Cmdline: "postgres: checkpointer", Cwd: cwd},
+			"", nil},
+		{"argv unresolved, exe unknown", ProcessContext{Argv: []string{"gone", "serve"}, Cmdline: "gone serve", Cwd: cwd, Env: env},
+			"sh", []string{"sh", "-lc", "gone serve"}},
+		{"no argv", ProcessContext{Cmdline: "npm run dev"}, "sh", []string{"sh", "-lc", "npm run dev"}},
+		{"nothing", ProcessContext{}, "", nil},
+		{"exe deleted since", ProcessContext{Argv: []string{"/nonexistent/server"}, Exe: filepath.Join(cwd, "upgraded-away")},
+			"", nil},
+	} {
+		cmd, err := relaunchCommand(tc.pc)
+		switch {
+		case tc.wantPath == "":
+			if err == nil {
+				t.Errorf("%s: want an error, got %s %q", tc.name, cmd.Path, cmd.Args)
+			}
+			continue
+		case err != nil:
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if tc.wantPath == "sh" {
+			if filepath.Base(cmd.Path) != "sh" {
+				t.Errorf("%s: want a login shell, got %s", tc.name, cmd.Path)
+			}
+		} else if cmd.Path != tc.wantPath {
+			t.Errorf("%s: path %s, want %s", tc.name, cmd.Path, tc.wantPath)
+		}
+		if !slices.Equal(cmd.Args, tc.wantArgs) {
+			t.Errorf("%s: args %q, want %q", tc.name, cmd.Args, tc.wantArgs)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package sys
+
+import (
+	"strings"
+
+	"github.com/pratik-anurag/portik/internal/proctree"
+)
+
+// reloadSignals maps well-known servers (matched on the process name, or the
+// program of the cmdline) to the signal that makes them reload gracefully.
+var reloadSignals = []struct {
+	names  []string
+	signal string
+	effect string
+}{
+	{[]string{"nginx"}, "HUP", "re-reads config, replaces workers"},
+	{[]string{"gunicorn"}, "HUP", "re-reads config, replaces workers"},
+	{[]string{"uwsgi"}, "HUP", "graceful reload"},
+	{[]string{"unicorn"}, "HUP", "re-reads config, replaces workers"},
+	{[]string{"postgres", "postmaster"}, "HUP", "re-reads postgresql.conf (pg_ctl reload)"},
+	{[]string{"sshd"}, "HUP", "re-reads sshd_config"},
+	{[]string{"named", "dnsmasq", "unbound"}, "HUP", "re-reads config"},
+	{[]string{"httpd", "apache2"}, "USR1", "graceful restart"},
+	{[]string{"puma"}, "USR2", "restarts workers in place"},
+	{[]string{"haproxy"}, "USR2", "master-worker reload"},
+	{[]string{"php-fpm"}, "USR2", "graceful reload"},
+}
+
+// ReloadSignal returns the conventional reload signal for a process, the server it
+// was matched as, and what the signal does. Only the process name and the program
+// the cmdline runs (argv[0], or the script of an interpreter) are matched, never
+// its arguments: "myapp sshd-proxy" is not sshd.
+func ReloadSignal(procName, cmdline string) (signal, server, effect string, ok bool) {
+	cands := []string{strings.ToLower(procName)}
+	if prog := proctree.Program(cmdline); prog != "" {
+		cands = append(cands, prog)
+	}
+	for _, c := range cands {
+		c = strings.TrimSuffix(c, ":") // "nginx: master process"
+		for _, r := range reloadSignals {
+			for _, n := range r.names {
+				if matchesServer(c, n) {
+					return r.signal, n, r.effect, true
+				}
+			}
+		}
+	}
+	return "", "", "", false
+}
+
+// matchesServer accepts the name itself or a versioned/suffixed variant:
+// php-fpm8.2, gunicorn3, httpd.worker.
+func matchesServer(c, name string) bool {
+	rest, ok := strings.CutPrefix(c, name)
+	if !ok {
+		return false
+	}
+	if rest == "" {
+		return true
+	}
+	r := rest[0]
+	return r == '-' || r == '.' || r == '_' || (r >= '0' && r <= '9')
+}
@@ -0,0 +1,49 @@
+package sys
+
+import "testing"
+
+func TestReloadSignal(t *testing.T) {
+	for _, tc := range []struct {
+		name, cmdline  string
+		signal, server string
+	}{
+		{"nginx", "nginx: master process /usr/sbin/nginx -g daemon off;", "HUP", "nginx"},
+		{"python3", "/venv/bin/python3 /venv/bin/gunicorn app:app -w 4", "HUP", "gunicorn"},
+		{"python3", "python3 -m gunicorn app:app", "HUP", "gunicorn"},
+		{"php-fpm8.2", "php-fpm: master process (/etc/php/8.2/fpm/php-fpm.conf)", "USR2", "php-fpm"},
+		{"postgres", "/usr/lib/postgresql/16/bin/postgres -D /var/lib/postgresql/16/main", "HUP", "postgres"},
+		{"httpd", "/usr/sbin/httpd -DFOREGROUND", "USR1", "httpd"},
+		{"ruby", "puma 6.4.0 (tcp://0.0.0.0:3000) [app]", "USR2", "puma"},
+		// arguments never decide the server
+		{"myapp", "myapp sshd-proxy", "", ""},
+		{"node", "node app.js --nginx", "", ""},
+		{"node", "node /srv/nginx-config-ui/server.js", "", ""},
+		{"bash", "bash -c gunicorn app:app", "", ""},
+		{"", "", "", ""},
+	} {
+		sig, server, _, ok := ReloadSignal(tc.name, tc.cmdline)
+		if sig != tc.signal || server != tc.server || ok != (tc.signal != "") {
+			t.Errorf("ReloadSignal(%q, %q) = %q, %q, %v; want %q, %q", tc.name, tc.cmdline, sig, server, ok, tc.signal, tc.server)
+		}
+	}
+}
+
+func TestMatchesServer(t *testing.T) {
+	for _, tc := range []struct {
+		c, name string
+		want    bool
+	}{
+		{"nginx", "nginx", true},
+		{"php-fpm8.2", "php-fpm", true},
+		{"gunicorn3", "gunicorn", true},
+		{"httpd.worker", "httpd", true},
+		{"uwsgi_python3", "uwsgi", true},
+		{"nginxproxy", "nginx", false},
+		{"my-nginx", "nginx", false},
+		{"", "nginx", false},
+	} {
+		if got := matchesServer(tc.c, tc.name); got != tc.want {
+			t.Errorf("matchesServer(%q, %q) = %v, want %v", tc.c, tc.name, got, tc.want)
+		}
+	}
+}
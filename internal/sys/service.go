@@ -0,0 +1,236 @@
+package sys
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/pratik-anurag/portik/internal/proctree"
+)
+
+// ServiceRestart is a restart delegated to the service manager that owns a process,
+// instead of killing it and re-running its command line.
+type ServiceRestart struct {
+	Manager string // systemd|launchd|pm2|supervisord
+	Name    string // unit, launchd service target, pm2 app or supervisord program
+	User    bool   // systemd --user unit
+}
+
+// ServiceArgs returns the argv used to restart the service, for prompts.
+func ServiceArgs(s ServiceRestart) []string {
+	switch s.Manager {
+	case "systemd":
+		if s.User {
+			return []string{"systemctl", "--user", "restart", s.Name}
+		}
+		return []string{"systemctl", "restart", s.Name}
+	case "launchd":
+		return []string{"launchctl", "kickstart", "-k", s.Name}
+	case "pm2":
+		return []string{"pm2", "restart", s.Name}
+	case "supervisord":
+		return []string{"supervisorctl", "restart", s.Name}
+	}
+	return nil
+}
+
+// ManagedBy reports the service manager that owns pid. ok is false when nothing
+// manages it and a plain re-run is appropriate. An error means a manager was
+// found in the parent chain but the process could not be mapped to one of its
+// services; re-running the command line would then fight the manager.
+func ManagedBy(pid int32) (s ServiceRestart, ok bool, err error) {
+	chain, _ := proctree.Build(pid, 15)
+	pids := make([]int32, 0, len(chain))
+	for _, p := range chain {
+		if p.PID > 1 {
+			pids = append(pids, p.PID)
+		}
+	}
+
+	switch by := proctree.Supervisor(pid); by.Kind {
+	case "pm2":
+		name, err := pm2App(pids)
+		if err != nil {
+			return s, false, fmt.Errorf("started by pm2 (%s) but not found in `pm2 jlist`: %w", by.Details, err)
+		}
+		return ServiceRestart{Manager: "pm2", Name: name}, true, nil
+	case "supervisord":
+		name, err := supervisorProgram(pids)
+		if err != nil {
+			return s, false, fmt.Errorf("started by supervisord (%s) but not found in `supervisorctl status`: %w", by.Details, err)
+		}
+		return ServiceRestart{Manager: "supervisord", Name: name}, true, nil
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		if unit, user := proctree.SystemdUnit(pid); unit != "" && unitOwns(chain, unit, user) {
+			return ServiceRestart{Manager: "systemd", Name: unit, User: user}, true, nil
+		}
+	case "darwin":
+		if target := launchdJob(chain); target != "" {
+			return ServiceRestart{Manager: "launchd", Name: target}, true, nil
+		}
+	}
+	return s, false, nil
+}
+
+// unitOwns checks that the unit's main process is pid or one of its ancestors,
+// reached without passing through an interactive session. Without logind, a
+// command typed in an ssh login lives in ssh.service's cgroup; that unit must not
+// be restarted on its behalf.
+func unitOwns(chain []proctree.Proc, unit string, user bool) bool {
+	args := []string{"show", "-p", "MainPID", "--value", unit}
+	if user {
+		args = append([]string{"--user"}, args...)
+	}
+	out, err := exec.Command("systemctl", args...).Output()
+	if err != nil {
+		return false
+	}
+	main, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil || main <= 0 {
+		return false
+	}
+	for _, p := range chain {
+		if int(p.PID) == main {
+			return true
+		}
+		if strings.HasPrefix(p.Name, "-") || strings.HasPrefix(p.Name, "sshd") {
+			return false // login shell or ssh session
+		}
+	}
+	return false
+}
+
+// RestartService runs the manager's restart command.
+func RestartService(s ServiceRestart) ActionResult {
+	argv := ServiceArgs(s)
+	if len(argv) == 0 {
+		return ActionResult{ExitCode: 1, Summary: "Unknown service manager " + s.Manager}
+	}
+	if _, err := exec.LookPath(argv[0]); err != nil {
+		return ActionResult{ExitCode: 1, Summary: argv[0] + " not found", Details: err.Error()}
+	}
+	out, err := exec.Command(argv[0], argv[1:]...).CombinedOutput()
+	if err != nil {
+		return ActionResult{ExitCode: 1, Summary: strings.Join(argv[:2], " ") + " failed", Details: string(bytes.TrimSpace(out))}
+	}
+	return ActionResult{ExitCode: 0, Summary: s.Manager + " restarted " + s.Name, Details: string(bytes.TrimSpace(out))}
+}
+
+// pm2App finds the pm2 app whose pid is in pids (the listener or, in cluster mode,
+// one of its ancestors).
+func pm2App(pids []int32) (string, error) {
+	out, err := exec.Command("pm2", "jlist").Output()
+	if err != nil {
+		return "", err
+	}
+	var apps []struct {
+		PID  int32  `json:"pid"`
+		Name string `json:"name"`
+	}
+	// pm2 may print "[PM2] ..." banners before the JSON array
+	for rest := out; ; {
+		i := bytes.IndexByte(rest, '[')
+		if i < 0 {
+			return "", errors.New("no JSON in output")
+		}
+		if json.Unmarshal(rest[i:], &apps) == nil {
+			break
+		}
+		rest = rest[i+1:]
+	}
+	for _, pid := range pids {
+		for _, a := range apps {
+			if a.PID == pid && a.Name != "" {
+				return a.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no app with pid %v", pids)
+}
+
+// supervisorProgram parses `supervisorctl status` lines such as
+// "web:web_00   RUNNING   pid 1234, uptime 0:01:02".
+func supervisorProgram(pids []int32) (string, error) {
+	out, err := exec.Command("supervisorctl", "status").Output()
+	if len(out) == 0 && err != nil {
+		return "", err
+	}
+	running := map[int32]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		f := strings.Fields(line)
+		for i := 0; i+1 < len(f); i++ {
+			if f[i] == "pid" {
+				if n, err := strconv.Atoi(strings.TrimSuffix(f[i+1], ",")); err == nil {
+					running[int32(n)] = f[0]
+				}
+			}
+		}
+	}
+	for _, pid := range pids {
+		if name, ok := running[pid]; ok {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no program with pid %v", pids)
+}
+
+// launchdJob returns the kickstart target (gui/UID/label or system/label) of the
+// launchd job that runs pid or one of its ancestors, reached without passing
+// through a shell or login session. Terminal, iTerm2 and VS Code are launchd jobs
+// too (application.* labels); a dev server started from one must not restart the
+// whole terminal.
+func launchdJob(chain []proctree.Proc) string {
+	out, err := exec.Command("launchctl", "list").Output()
+	if err != nil {
+		return ""
+	}
+	domain := "gui/" + strconv.Itoa(os.Getuid()) + "/"
+	if os.Getuid() == 0 {
+		domain = "system/"
+	}
+	if label := jobIn(chain, launchdJobs(string(out))); label != "" {
+		return domain + label
+	}
+	return ""
+}
+
+// launchdJobs parses `launchctl list` ("PID\tStatus\tLabel", PID "-" when not
+// running) into label by pid, leaving out application.* entries.
+func launchdJobs(out string) map[int32]string {
+	jobs := map[int32]string{}
+	for _, line := range strings.Split(out, "\n") {
+		f := strings.Fields(line)
+		if len(f) != 3 || strings.HasPrefix(f[2], "application.") {
+			continue
+		}
+		if n, err := strconv.Atoi(f[0]); err == nil && n > 0 {
+			jobs[int32(n)] = f[2]
+		}
+	}
+	return jobs
+}
+
+// jobIn returns the job label of the first process in chain (listener first)
+// that jobs knows, stopping at a shell, login or ssh session.
+func jobIn(chain []proctree.Proc, jobs map[int32]string) string {
+	for _, p := range chain {
+		if label, ok := jobs[p.PID]; ok {
+			return label
+		}
+		name := filepath.Base(p.Name)
+		if proctree.IsShell(name) || name == "login" || strings.HasPrefix(name, "sshd") || name == "tmux" || name == "screen" {
+			return ""
+		}
+	}
+	return ""
+}
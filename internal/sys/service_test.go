@@ -0,0 +1,44 @@
+package sys
+
+import (
+	"testing"
+
+	"github.com/pratik-anurag/portik/internal/proctree"
+)
+
+const launchctlList = `PID	Status	Label
+-	0	com.apple.SafariHistoryServiceAgent
+412	0	application.com.apple.Terminal.1234567.1234573
+587	0	application.com.microsoft.VSCode.1234567.1234590
+733	0	com.example.web
+-	78	com.example.crashed
+`
+
+func TestLaunchdJobs(t *testing.T) {
+	jobs := launchdJobs(launchctlList)
+	if len(jobs) != 1 || jobs[733] != "com.example.web" {
+		t.Fatalf("want only the running non-application job, got %v", jobs)
+	}
+
+	// npm run dev typed into Terminal: node <- zsh <- login <- Terminal
+	fromTerminal := []proctree.Proc{
+		{PID: 900, Name: "node"},
+		{PID: 880, Name: "-zsh"},
+		{PID: 870, Name: "login"},
+		{PID: 412, Name: "/System/Applications/Utilities/Terminal.app/Contents/MacOS/Terminal"},
+	}
+	if got := jobIn(fromTerminal, jobs); got != "" {
+		t.Fatalf("a terminal's child is not a launchd job, got %q", got)
+	}
+	// even if Terminal were listed without the application. prefix, the walk
+	// stops at the shell
+	if got := jobIn(fromTerminal, map[int32]string{412: "com.apple.Terminal"}); got != "" {
+		t.Fatalf("the walk must stop at the shell, got %q", got)
+	}
+
+	// a job's worker: node <- node (the job's main process)
+	worker := []proctree.Proc{{PID: 950, Name: "node"}, {PID: 733, Name: "node"}}
+	if got := jobIn(worker, jobs); got != "com.example.web" {
+		t.Fatalf("want com.example.web, got %q", got)
+	}
+}
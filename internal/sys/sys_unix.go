@@ -4,12 +4,13 @@ package sys
 
 import (
 	"bytes"
-	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"os/user"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -44,67 +45,352 @@ func TerminateProcess(pid int32, timeout time.Duration) ActionResult {
 	if err != nil {
 		return ActionResult{ExitCode: 1, Summary: "Failed to find process", Details: err.Error()}
 	}
-	_ = p.Signal(syscall.SIGTERM)
+	return terminate(func(sig syscall.Signal) { _ = p.Signal(sig) }, func() bool { return processAlive(pid) }, timeout, "Process")
+}
+
+// TerminateGroup signals every member of process group pgid (SIGTERM, then SIGKILL
+// after timeout). It refuses portik's own group.
+func TerminateGroup(pgid int32, timeout time.Duration) ActionResult {
+	if pgid <= 1 || int(pgid) == syscall.Getpgrp() {
+		return ActionResult{ExitCode: 1, Summary: "Refusing to signal process group", Details: fmt.Sprintf("pgid %d is portik's own group or init", pgid)}
+	}
+	if err := syscall.Kill(-int(pgid), 0); errors.Is(err, syscall.ESRCH) {
+		return ActionResult{ExitCode: 1, Summary: "Failed to find process group", Details: err.Error()}
+	}
+	send := func(sig syscall.Signal) { _ = syscall.Kill(-int(pgid), sig) }
+	alive := func() bool { return !errors.Is(syscall.Kill(-int(pgid), 0), syscall.ESRCH) }
+	return terminate(send, alive, timeout, "Process group")
+}
+
+// TerminateTree signals a set of processes, typically a subtree from proctree.
+// The whole set is stopped before SIGTERM so a parent cannot respawn a child
+// between signals.
+func TerminateTree(pids []int32, timeout time.Duration) ActionResult {
+	var procs []*os.Process
+	for _, pid := range pids {
+		if pid <= 1 || int(pid) == os.Getpid() {
+			continue
+		}
+		if p, err := os.FindProcess(int(pid)); err == nil {
+			procs = append(procs, p)
+		}
+	}
+	if len(procs) == 0 {
+		return ActionResult{ExitCode: 1, Summary: "Failed to find processes"}
+	}
+	send := func(sig syscall.Signal) {
+		if sig == syscall.SIGTERM {
+			for _, p := range procs {
+				_ = p.Signal(syscall.SIGSTOP)
+			}
+			defer func() {
+				for _, p := range procs {
+					_ = p.Signal(syscall.SIGCONT)
+				}
+			}()
+		}
+		for _, p := range procs {
+			_ = p.Signal(sig)
+		}
+	}
+	alive := func() bool { return len(aliveAmong(pids)) > 0 }
+	what := fmt.Sprintf("Process tree (%d processes)", len(procs))
+	if len(procs) == 1 {
+		what = "Process tree (1 process)"
+	}
+	res := terminate(send, alive, timeout, what)
+	if left := aliveAmong(pids); len(left) > 0 {
+		res.Details = fmt.Sprintf("still alive: %v", left)
+	}
+	return res
+}
+
+var signals = map[string]syscall.Signal{
+	"HUP": syscall.SIGHUP, "INT": syscall.SIGINT, "QUIT": syscall.SIGQUIT, "KILL": syscall.SIGKILL,
+	"TERM": syscall.SIGTERM, "USR1": syscall.SIGUSR1, "USR2": syscall.SIGUSR2, "WINCH": syscall.SIGWINCH,
+	"ALRM": syscall.SIGALRM, "CONT": syscall.SIGCONT, "STOP": syscall.SIGSTOP, "TSTP": syscall.SIGTSTP,
+	"TTIN": syscall.SIGTTIN, "TTOU": syscall.SIGTTOU,
+}
+
+// ParseSignal accepts HUP, SIGHUP, hup or a signal number.
+func ParseSignal(name string) (syscall.Signal, error) {
+	n := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "SIG")
+	if sig, ok := signals[n]; ok {
+		return sig, nil
+	}
+	if num, err := strconv.Atoi(n); err == nil && num > 0 && num < 65 {
+		return syscall.Signal(num), nil
+	}
+	return 0, fmt.Errorf("unknown signal %q", name)
+}
+
+// SignalName returns the SIG-prefixed name of sig.
+func SignalName(sig syscall.Signal) string {
+	for name, s := range signals {
+		if s == sig {
+			return "SIG" + name
+		}
+	}
+	return "signal " + strconv.Itoa(int(sig))
+}
+
+// SignalProcesses sends sig once to each pid, without waiting or escalating; for
+// reloads (HUP, USR1, ...) and other signals the caller checks the outcome itself.
+func SignalProcesses(pids []int32, sig syscall.Signal) ActionResult {
+	var failed []string
+	for _, pid := range pids {
+		if err := syscall.Kill(int(pid), sig); err != nil {
+			failed = append(failed, fmt.Sprintf("pid %d: %v", pid, err))
+		}
+	}
+	if len(failed) > 0 {
+		return ActionResult{ExitCode: 1, Summary: "Failed to send " + SignalName(sig), Details: strings.Join(failed, "\n")}
+	}
+	return ActionResult{ExitCode: 0, Summary: fmt.Sprintf("Sent %s to %s", SignalName(sig), pidList(pids))}
+}
+
+// SignalGroup sends sig once to process group pgid.
+func SignalGroup(pgid int32, sig syscall.Signal) ActionResult {
+	if pgid <= 1 || int(pgid) == syscall.Getpgrp() {
+		return ActionResult{ExitCode: 1, Summary: "Refusing to signal process group", Details: fmt.Sprintf("pgid %d is portik's own group or init", pgid)}
+	}
+	if err := syscall.Kill(-int(pgid), sig); err != nil {
+		return ActionResult{ExitCode: 1, Summary: "Failed to send " + SignalName(sig), Details: err.Error()}
+	}
+	return ActionResult{ExitCode: 0, Summary: fmt.Sprintf("Sent %s to process group %d", SignalName(sig), pgid)}
+}
+
+func pidList(pids []int32) string {
+	if len(pids) == 1 {
+		return "pid " + itoa32(pids[0])
+	}
+	s := make([]string, len(pids))
+	for i, pid := range pids {
+		s[i] = itoa32(pid)
+	}
+	return "pids " + strings.Join(s, ", ")
+}
+
+// ProcessGroup returns the process group id of pid.
+func ProcessGroup(pid int32) (int32, error) {
+	pgid, err := syscall.Getpgid(int(pid))
+	return int32(pgid), err
+}
+
+func terminate(send func(syscall.Signal), alive func() bool, timeout time.Duration, what string) ActionResult {
+	send(syscall.SIGTERM)
 
 	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
-		if !processAlive(pid) {
-			return ActionResult{ExitCode: 0, Summary: "Process terminated"}
+		if !alive() {
+			return ActionResult{ExitCode: 0, Summary: what + " terminated"}
 		}
 		time.Sleep(150 * time.Millisecond)
 	}
-	_ = p.Signal(syscall.SIGKILL)
+	send(syscall.SIGKILL)
 	time.Sleep(200 * time.Millisecond)
-	if processAlive(pid) {
-		return ActionResult{ExitCode: 1, Summary: "Failed to kill process", Details: "process still alive after SIGKILL"}
+	if alive() {
+		return ActionResult{ExitCode: 1, Summary: "Failed to kill " + strings.ToLower(what[:1]) + what[1:], Details: "still alive after SIGKILL"}
 	}
-	return ActionResult{ExitCode: 0, Summary: "Process killed (SIGKILL after timeout)"}
+	return ActionResult{ExitCode: 0, Summary: what + " killed (SIGKILL after timeout)"}
 }
 
-func SmartRestart(pid int32, cmdline string, timeout time.Duration) ActionResult {
-	killRes := TerminateProcess(pid, timeout)
+// SmartRestart stops the process and relaunches it from its captured context: same
+// argv, working directory, environment and user, in a new session with stdin from
+// /dev/null and stdout/stderr appended to logPath. The caller waits for readiness.
+func SmartRestart(pc ProcessContext, timeout time.Duration, logPath string) ActionResult {
+	cmd, err := relaunchCommand(pc)
+	if err != nil {
+		return ActionResult{ExitCode: 1, Summary: "Cannot restart process", Details: err.Error()}
+	}
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o755); err != nil {
+		return ActionResult{ExitCode: 1, Summary: "Cannot open restart log", Details: err.Error()}
+	}
+	logf, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return ActionResult{ExitCode: 1, Summary: "Cannot open restart log", Details: err.Error()}
+	}
+	defer logf.Close()
+
+	killRes := TerminateProcess(pc.PID, timeout)
 	if killRes.ExitCode != 0 {
 		return ActionResult{ExitCode: 1, Summary: "Failed to stop process", Details: killRes.Summary + ": " + killRes.Details}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, "sh", "-lc", cmdline)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	fmt.Fprintf(logf, "--- portik restart %s: %s (cwd %s)\n", time.Now().Format(time.RFC3339), pc.Describe(), cmd.Dir)
 	cmd.Stdin = nil
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
-
+	cmd.Stdout = logf
+	cmd.Stderr = logf
 	if err := cmd.Start(); err != nil {
 		return ActionResult{ExitCode: 1, Summary: "Failed to start process", Details: err.Error()}
 	}
-	return ActionResult{ExitCode: 0, Summary: "Process restarted", Details: fmt.Sprintf("Started pid %d", cmd.Process.Pid)}
+
+	// Catch commands that die straight away (bad cwd, missing env) while the
+	// exit status is still ours to read; anything alive after that is left to run.
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	select {
+	case err := <-exited:
+		return ActionResult{ExitCode: 1, Summary: "Restarted process exited immediately",
+			Details: fmt.Sprintf("%v (see %s)", err, logPath)}
+	case <-time.After(300 * time.Millisecond):
+	}
+	return ActionResult{ExitCode: 0, Summary: "Process restarted",
+		Details: fmt.Sprintf("Started pid %d, logging to %s", cmd.Process.Pid, logPath)}
+}
+
+// relaunchCommand builds the detached command for pc. argv[0] is trusted only if
+// it resolves to the process's executable; a rewritten title (nginx, postgres,
+// node's process.title) runs Exe with the captured argv instead, and without
+// either it falls back to the flattened cmdline through a login shell. The
+// executable is checked here, before the caller stops the running process.
+func relaunchCommand(pc ProcessContext) (*exec.Cmd, error) {
+	var cmd *exec.Cmd
+	if len(pc.Argv) > 0 {
+		exe := lookExe(pc.Argv[0], pc.Cwd, pc.Env)
+		if exe == "" || pc.Exe != "" && !sameFile(exe, pc.Exe) {
+			exe = pc.Exe
+		}
+		if exe != "" {
+			cmd = &exec.Cmd{Path: exe, Args: pc.Argv}
+		}
+	}
+	if cmd == nil {
+		// a rewritten title is all the cmdline holds too; a shell cannot run it
+		if len(pc.Argv) == 1 && strings.Contains(pc.Argv[0], " ") {
+			return nil, fmt.Errorf("process title %q is not a command and the executable is unknown", pc.Argv[0])
+		}
+		if strings.TrimSpace(pc.Cmdline) == "" {
+			return nil, errors.New("command line not available")
+		}
+		cmd = exec.Command("sh", "-lc", pc.Cmdline)
+		if cmd.Err != nil {
+			return nil, cmd.Err
+		}
+	}
+	if executable(cmd.Path) == "" {
+		return nil, fmt.Errorf("executable %q not found", cmd.Path)
+	}
+	if dirExists(pc.Cwd) {
+		cmd.Dir = pc.Cwd
+	}
+	if len(pc.Env) > 0 {
+		cmd.Env = pc.Env
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	// Running as root (sudo portik restart) must not promote the service to root.
+	if os.Geteuid() == 0 && pc.UID > 0 {
+		cred := &syscall.Credential{Uid: uint32(pc.UID), Gid: uint32(max(pc.GID, 0))}
+		if u, err := user.LookupId(strconv.Itoa(pc.UID)); err == nil {
+			if ids, err := u.GroupIds(); err == nil {
+				for _, id := range ids {
+					if g, err := strconv.Atoi(id); err == nil {
+						cred.Groups = append(cred.Groups, uint32(g))
+					}
+				}
+			}
+		}
+		cmd.SysProcAttr.Credential = cred
+	}
+	return cmd, nil
 }
 
-func RestartDockerContainer(containerID string, timeout time.Duration) ActionResult {
+// RestartContainer restarts a single container with the given runtime (docker|podman).
+func RestartContainer(runtime, containerID string, timeout time.Duration) ActionResult {
 	if containerID == "" {
 		return ActionResult{ExitCode: 1, Summary: "Missing container ID"}
 	}
-	if _, err := exec.LookPath("docker"); err != nil {
-		return ActionResult{ExitCode: 1, Summary: "docker not found", Details: err.Error()}
+	if runtime == "" {
+		runtime = "docker"
+	}
+	if _, err := exec.LookPath(runtime); err != nil {
+		return ActionResult{ExitCode: 1, Summary: runtime + " not found", Details: err.Error()}
 	}
 	sec := int(timeout.Seconds())
 	if sec < 1 {
 		sec = 1
 	}
-	out, err := exec.Command("docker", "restart", "-t", fmt.Sprintf("%d", sec), containerID).CombinedOutput()
+	out, err := exec.Command(runtime, "restart", "-t", fmt.Sprintf("%d", sec), containerID).CombinedOutput()
 	if err != nil {
-		return ActionResult{ExitCode: 1, Summary: "docker restart failed", Details: string(bytes.TrimSpace(out))}
+		return ActionResult{ExitCode: 1, Summary: runtime + " restart failed", Details: string(bytes.TrimSpace(out))}
 	}
 	return ActionResult{ExitCode: 0, Summary: "Container restarted", Details: string(bytes.TrimSpace(out))}
 }
 
+// StopContainer stops a single container with the given runtime (docker|podman).
+func StopContainer(runtime, containerID string, timeout time.Duration) ActionResult {
+	if containerID == "" {
+		return ActionResult{ExitCode: 1, Summary: "Missing container ID"}
+	}
+	if runtime == "" {
+		runtime = "docker"
+	}
+	if _, err := exec.LookPath(runtime); err != nil {
+		return ActionResult{ExitCode: 1, Summary: runtime + " not found", Details: err.Error()}
+	}
+	sec := int(timeout.Seconds())
+	if sec < 1 {
+		sec = 1
+	}
+	out, err := exec.Command(runtime, "stop", "-t", fmt.Sprintf("%d", sec), containerID).CombinedOutput()
+	if err != nil {
+		return ActionResult{ExitCode: 1, Summary: runtime + " stop failed", Details: string(bytes.TrimSpace(out))}
+	}
+	return ActionResult{ExitCode: 0, Summary: "Container stopped", Details: string(bytes.TrimSpace(out))}
+}
+
+// RestartComposeService restarts (or recreates) a compose service so compose applies
+// dependency ordering and config changes.
+func RestartComposeService(opt ComposeRestart, timeout time.Duration) ActionResult {
+	if opt.Project == "" || opt.Service == "" {
+		return ActionResult{ExitCode: 1, Summary: "Missing compose project or service"}
+	}
+	argv := ComposeArgs(opt, timeout)
+	if _, err := exec.LookPath(argv[0]); err != nil {
+		return ActionResult{ExitCode: 1, Summary: argv[0] + " not found", Details: err.Error()}
+	}
+	cmd := exec.Command(argv[0], argv[1:]...)
+	if dirExists(opt.WorkingDir) {
+		cmd.Dir = opt.WorkingDir
+	}
+	verb := "restart"
+	if opt.Recreate {
+		verb = "up --force-recreate"
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return ActionResult{ExitCode: 1, Summary: "compose " + verb + " failed", Details: string(bytes.TrimSpace(out))}
+	}
+	return ActionResult{ExitCode: 0, Summary: "Compose service " + opt.Service + " restarted (" + verb + ")", Details: string(bytes.TrimSpace(out))}
+}
+
 func processAlive(pid int32) bool {
 	cmd := exec.Command("ps", "-p", itoa32(pid))
 	return cmd.Run() == nil
 }
 
+// aliveAmong returns the pids that still exist, using a single ps call. Zombies
+// are dead already; they only wait for a parent outside the set to reap them.
+func aliveAmong(pids []int32) []int32 {
+	if len(pids) == 0 {
+		return nil
+	}
+	list := make([]string, len(pids))
+	for i, pid := range pids {
+		list[i] = itoa32(pid)
+	}
+	out, _ := exec.Command("ps", "-p", strings.Join(list, ","), "-o", "pid=,stat=").Output()
+	var alive []int32
+	for _, line := range strings.Split(string(out), "\n") {
+		var pid int32
+		var stat string
+		if _, err := fmt.Sscanf(line, "%d %s", &pid, &stat); err == nil && !strings.HasPrefix(stat, "Z") {
+			alive = append(alive, pid)
+		}
+	}
+	return alive
+}
+
 func psField(pid int32, format string) string {
 	cmd := exec.Command("ps", "-p", itoa32(pid), "-o", format)
 	var buf bytes.Buffer
@@ -2,7 +2,11 @@
 
 package sys
 
-import "time"
+import (
+	"errors"
+	"syscall"
+	"time"
+)
 
 type ActionResult struct {
 	ExitCode int    `json:"exit_code"`
@@ -16,10 +20,48 @@ func TerminateProcess(pid int32, timeout time.Duration) ActionResult {
 	return ActionResult{ExitCode: 1, Summary: "Not implemented on Windows yet"}
 }
 
-func SmartRestart(pid int32, cmdline string, timeout time.Duration) ActionResult {
+func TerminateGroup(pgid int32, timeout time.Duration) ActionResult {
 	return ActionResult{ExitCode: 1, Summary: "Not implemented on Windows yet"}
 }
 
-func RestartDockerContainer(containerID string, timeout time.Duration) ActionResult {
+func TerminateTree(pids []int32, timeout time.Duration) ActionResult {
+	return ActionResult{ExitCode: 1, Summary: "Not implemented on Windows yet"}
+}
+
+func ParseSignal(name string) (syscall.Signal, error) {
+	return 0, errors.New("signals are not supported on Windows")
+}
+
+func SignalName(sig syscall.Signal) string { return sig.String() }
+
+func SignalProcesses(pids []int32, sig syscall.Signal) ActionResult {
+	return ActionResult{ExitCode: 1, Summary: "Not implemented on Windows yet"}
+}
+
+func SignalGroup(pgid int32, sig syscall.Signal) ActionResult {
+	return ActionResult{ExitCode: 1, Summary: "Not implemented on Windows yet"}
+}
+
+func ProcessGroup(pid int32) (int32, error) {
+	return 0, errors.New("process groups are not supported on Windows")
+}
+
+func CaptureProcess(pid int32, cmdline string) ProcessContext {
+	return ProcessContext{PID: pid, Cmdline: cmdline, UID: -1, GID: -1}
+}
+
+func SmartRestart(pc ProcessContext, timeout time.Duration, logPath string) ActionResult {
+	return ActionResult{ExitCode: 1, Summary: "Not implemented on Windows yet"}
+}
+
+func RestartContainer(runtime, containerID string, timeout time.Duration) ActionResult {
+	return ActionResult{ExitCode: 1, Summary: "Not implemented on Windows yet"}
+}
+
+func StopContainer(runtime, containerID string, timeout time.Duration) ActionResult {
+	return ActionResult{ExitCode: 1, Summary: "Not implemented on Windows yet"}
+}
+
+func RestartComposeService(opt ComposeRestart, timeout time.Duration) ActionResult {
 	return ActionResult{ExitCode: 1, Summary: "Not implemented on Windows yet"}
 }
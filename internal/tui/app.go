@@ -14,6 +14,7 @@ type Options struct {
 	Docker   bool
 	Actions  bool
 	Force    bool
+	NoDaemon bool // always inspect live, even when a daemon monitors the ports
 }
 
 func Run(opts Options) error {
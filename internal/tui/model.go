@@ -8,6 +8,8 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/pratik-anurag/portik/internal/audit"
+	"github.com/pratik-anurag/portik/internal/daemon"
 	"github.com/pratik-anurag/portik/internal/history"
 	"github.com/pratik-anurag/portik/internal/inspect"
 	"github.com/pratik-anurag/portik/internal/model"
@@ -76,8 +78,14 @@ type refreshMsg struct {
 	err   error
 }
 type actionDoneMsg struct {
-	res sys.ActionResult
-	err error
+	res      sys.ActionResult
+	err      error
+	auditErr error // the action happened but could not be recorded
+}
+
+// audited records e with the outcome res in the audit log.
+func audited(e audit.Entry, res sys.ActionResult) actionDoneMsg {
+	return actionDoneMsg{res: res, auditErr: audit.Record(e.Done(res))}
 }
 
 func newModel(opts Options) modelTUI {
@@ -236,6 +244,9 @@ func (m modelTUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.status += " — " + x.res.Details
 			}
 		}
+		if x.auditErr != nil {
+			m.status += " (warning: audit log: " + x.auditErr.Error() + ")"
+		}
 		m.confirming = false
 		m.confirmAct = actionNone
 		m.confirmMsg = ""
@@ -258,16 +269,26 @@ func (m *modelTUI) beginConfirm(kind actionKind) {
 		}
 		m.confirmMsg = fmt.Sprintf("Kill pid %d (%s) on %d/%s? [y/N]", row.PID, dash(row.Owner), row.Port, row.Proto)
 	case actionRestart:
-		if row.PID <= 0 {
+		l, ok := row.Report.PrimaryListener()
+		if !ok || l.PID <= 0 {
 			m.status = "No PID to restart"
 			return
 		}
-		l, ok2 := row.Report.PrimaryListener()
-		if !ok2 || strings.TrimSpace(l.Cmdline) == "" {
-			m.status = "Cannot restart: missing cmdline (try sudo)"
+		// say which path R takes: the service manager, or stopping and re-running
+		svc, managed, err := sys.ManagedBy(l.PID)
+		switch {
+		case err != nil:
+			m.status = "Restart: " + err.Error()
 			return
+		case managed:
+			m.confirmMsg = fmt.Sprintf("Restart pid %d (%s) via %s? [y/N]", l.PID, dash(row.Owner), strings.Join(sys.ServiceArgs(svc), " "))
+		case strings.TrimSpace(l.Cmdline) == "":
+			m.status = "Restart: missing cmdline (try sudo)"
+			return
+		default:
+			pc := sys.CaptureProcess(l.PID, l.Cmdline)
+			m.confirmMsg = fmt.Sprintf("Restart pid %d (%s) by stopping it and re-running: %s? [y/N]", l.PID, dash(row.Owner), pc.Describe())
 		}
-		m.confirmMsg = fmt.Sprintf("Restart pid %d (%s)? [y/N]", row.PID, dash(row.Owner))
 	}
 	m.confirming = true
 	m.confirmAct = kind
@@ -277,6 +298,36 @@ func (m modelTUI) View() string {
 	return renderUI(m)
 }
 
+// daemonReports returns reports for the ports a running daemon monitors, asking it
+// to re-inspect any older than one refresh interval. The daemon records them itself.
+func daemonReports(opts Options, ports []int) map[int]model.Report {
+	if opts.NoDaemon {
+		return nil
+	}
+	cl, err := daemon.Connect()
+	if err != nil {
+		return nil
+	}
+	var served []int
+	for _, p := range ports {
+		if cl.Serves(p, opts.Proto, opts.Docker) {
+			served = append(served, p)
+		}
+	}
+	if len(served) == 0 {
+		return nil
+	}
+	m, err := cl.Reports(served, opts.Proto, opts.Interval)
+	if err != nil {
+		return nil
+	}
+	out := make(map[int]model.Report, len(m))
+	for p, cr := range m {
+		out[p] = cr.Report
+	}
+	return out
+}
+
 func (m modelTUI) refreshCmd() tea.Cmd {
 	opts := m.opts
 	ports := make([]int, len(m.opts.Ports))
@@ -288,18 +339,23 @@ func (m modelTUI) refreshCmd() tea.Cmd {
 	}
 
 	return func() tea.Msg {
-		st, _ := history.Load()
+		st, _ := history.LoadPorts(ports...)
+		cached := daemonReports(opts, ports)
 
 		rows := make([]portRow, 0, len(ports))
 		for _, p := range ports {
 			row := portRow{Port: p, Proto: opts.Proto}
-			rep, err := inspect.InspectPort(p, opts.Proto, inspect.Options{EnableDocker: opts.Docker, IncludeConnections: true})
-			if err != nil {
-				row.Err = err.Error()
-				rows = append(rows, row)
-				continue
+			rep, ok := cached[p]
+			if !ok {
+				var err error
+				rep, err = inspect.InspectPort(p, opts.Proto, inspect.Options{EnableDocker: opts.Docker, IncludeConnections: true})
+				if err != nil {
+					row.Err = err.Error()
+					rows = append(rows, row)
+					continue
+				}
+				_ = history.Record(rep)
 			}
-			_ = history.Record(rep)
 			row.Report = rep
 
 			l, ok := rep.PrimaryListener()
@@ -361,22 +417,42 @@ func (m modelTUI) runActionCmd(kind actionKind) tea.Cmd {
 				}
 			}
 			res := sys.TerminateProcess(row.PID, timeout)
-			return actionDoneMsg{res: res}
+			l, _ := row.Report.PrimaryListener()
+			l.PID = row.PID
+			ae := audit.For(audit.Kill, "tui", row.Report, l)
+			ae.Method, ae.Force = "SIGTERM, SIGKILL after "+timeout.String(), m.opts.Force
+			return audited(ae, res)
 		case actionRestart:
 			l, ok := row.Report.PrimaryListener()
 			if !ok || l.PID <= 0 {
 				return actionDoneMsg{err: fmt.Errorf("no pid")}
 			}
-			if strings.TrimSpace(l.Cmdline) == "" {
-				return actionDoneMsg{err: fmt.Errorf("missing cmdline (try sudo)")}
-			}
 			if !m.opts.Force {
 				if err := sys.EnsureSameUser(l.PID); err != nil {
 					return actionDoneMsg{err: err}
 				}
 			}
-			res := sys.SmartRestart(l.PID, l.Cmdline, timeout)
-			return actionDoneMsg{res: res}
+			ae := audit.For(audit.Restart, "tui", row.Report, l)
+			ae.Force = m.opts.Force
+			if svc, managed, err := sys.ManagedBy(l.PID); err != nil {
+				return actionDoneMsg{err: err}
+			} else if managed {
+				res := sys.RestartService(svc)
+				ae.Method = strings.Join(sys.ServiceArgs(svc), " ")
+				return audited(ae, res)
+			}
+			// only re-running needs the command line; a service manager does not
+			if strings.TrimSpace(l.Cmdline) == "" {
+				return actionDoneMsg{err: fmt.Errorf("missing cmdline (try sudo)")}
+			}
+			logPath, err := sys.RestartLogPath(row.Port)
+			if err != nil {
+				return actionDoneMsg{err: err}
+			}
+			pc := sys.CaptureProcess(l.PID, l.Cmdline)
+			res := sys.SmartRestart(pc, timeout, logPath)
+			ae.Method = "re-run: " + pc.Describe()
+			return audited(ae, res)
 		default:
 			return actionDoneMsg{err: fmt.Errorf("unknown action")}
 		}
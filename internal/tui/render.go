@@ -125,7 +125,7 @@ func renderUI(m modelTUI) string {
 
 	b.WriteString(styles.rule.Render(strings.Repeat("─", width)))
 	b.WriteString("\n")
-	b.WriteString(styles.section.Render("History (last 20)"))
+	b.WriteString(styles.section.Render("History (last 20 events; stats over 24h)"))
 	b.WriteString("\n")
 	b.WriteString(renderHistoryLast20(m, row.Port))
 	b.WriteString("\n")
@@ -213,7 +213,7 @@ func renderHistoryLast20(m modelTUI, port int) string {
 	if len(evs) == 0 {
 		for _, k := range []string{fmt.Sprintf("%d/tcp", port), fmt.Sprintf("%d/udp", port)} {
 			if len(m.store.Ports[k]) > 0 {
-				evs = m.store.Ports[k]
+				key, evs = k, m.store.Ports[k]
 				break
 			}
 		}
@@ -221,19 +221,34 @@ func renderHistoryLast20(m modelTUI, port int) string {
 	if len(evs) == 0 {
 		return "  (no events)\n"
 	}
+	var b strings.Builder
+	now := time.Now()
+	if st := m.store.StatsForKey(key, now.Add(-24*time.Hour), now); st != nil {
+		if len(st.Owners) > 3 {
+			st.Owners = st.Owners[:3]
+		}
+		b.WriteString(history.RenderStats(*st, "  "))
+		b.WriteString("\n")
+	}
 	start := 0
 	if len(evs) > 20 {
 		start = len(evs) - 20
 	}
-	var b strings.Builder
 	for _, e := range evs[start:] {
+		kind := history.KindAcquired
+		if e.IsReleased() {
+			kind = history.KindReleased
+		}
 		lbl := ownerLabelEvent(e)
-		b.WriteString(fmt.Sprintf("  %s  %s\n", e.At.Format("01-02 15:04:05"), trunc(lbl, 48)))
+		b.WriteString(fmt.Sprintf("  %s  %-8s  %s\n", e.At.Format("01-02 15:04:05"), kind, trunc(lbl, 40)))
 	}
 	return b.String()
 }
 
 func ownerLabelEvent(e history.OwnershipEvent) string {
+	if e.IsReleased() {
+		return "free"
+	}
 	if e.DockerMapped {
 		if e.ComposeService != "" {
 			return fmt.Sprintf("docker:%s (svc=%s)", e.ContainerName, e.ComposeService)
@@ -249,7 +264,7 @@ func ownerLabelEvent(e history.OwnershipEvent) string {
 	if e.PID > 0 {
 		return fmt.Sprintf("pid:%d", e.PID)
 	}
-	return "none"
+	return "unknown"
 }
 
 func pidStr(pid int32) string {
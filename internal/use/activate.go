@@ -0,0 +1,5 @@
+package use
+
+// ActivateArgs is how RunWithPort re-invokes portik (after the executable) to
+// start a command with an inherited listener; the command follows "--".
+var ActivateArgs = []string{"use", "activate"}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package use
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// Activate sets LISTEN_PID to this process and replaces it with argv, which
+// keeps the pid and the inherited fd 3. It only returns on failure.
+func Activate(argv []string) error {
+	if len(argv) == 0 {
+		return fmt.Errorf("missing command")
+	}
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		return err
+	}
+	env := append(os.Environ(), "LISTEN_PID="+strconv.Itoa(os.Getpid()))
+	return syscall.Exec(path, argv, env)
+}
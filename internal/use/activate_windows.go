@@ -0,0 +1,9 @@
+//go:build windows
+
+package use
+
+import "errors"
+
+func Activate(argv []string) error {
+	return errors.New("socket activation (--pass-fd) is not supported on Windows")
+}
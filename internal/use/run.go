@@ -1,14 +1,14 @@
 package use
 
 import (
+	"cmp"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"syscall"
-
-	"github.com/pratik-anurag/portik/internal/reserve"
 )
 
 type RunOptions struct {
@@ -22,8 +22,13 @@ type RunOptions struct {
 
 	ExtraEnv []string // additional env KEY=VAL
 
-	// not used to "reserve" (would block child bind); kept for future extensions
-	ReserveHint reserve.FreeOptions
+	// Listener, when set, is the bound socket for Port. It is passed to the child
+	// as fd 3 with the systemd socket activation protocol (LISTEN_FDS=1,
+	// LISTEN_PID, LISTEN_FDNAMES), so there is no window in which another process
+	// can take the port. The caller closes its own copy after RunWithPort.
+	Listener *os.File
+	FDName   string // LISTEN_FDNAMES; default "portik"
+	FDEnv    string // optional variable that also holds the fd number
 
 	Stdout io.Writer
 	Stderr io.Writer
@@ -33,6 +38,7 @@ type RunOptions struct {
 // RunWithPort runs a child process with PORT=<port> set.
 // - If Shell=true: runs `sh -lc "<cmd...>"` so $PORT works.
 // - Else: execs Args directly; if Template=true replaces "{PORT}" in args.
+// - If Listener is set: the child inherits it as fd 3 (socket activation).
 func RunWithPort(opt RunOptions) (int, error) {
 	if opt.Port <= 0 || opt.Port > 65535 {
 		return 0, fmt.Errorf("invalid port: %d", opt.Port)
@@ -43,15 +49,19 @@ func RunWithPort(opt RunOptions) (int, error) {
 	if opt.EnvVarName == "" {
 		opt.EnvVarName = "PORT"
 	}
+	if opt.Listener != nil && opt.Shell {
+		return 0, fmt.Errorf("a listener cannot be passed through a shell: LISTEN_PID would name the shell")
+	}
 
 	var cmd *exec.Cmd
+	var argv []string
 
 	if opt.Shell {
 		// join args into a shell command string (safe enough for dev usage)
 		cmdStr := shellJoin(opt.Args)
-		cmd = exec.Command("sh", "-lc", cmdStr)
+		argv = []string{"sh", "-lc", cmdStr}
 	} else {
-		argv := make([]string, len(opt.Args))
+		argv = make([]string, len(opt.Args))
 		copy(argv, opt.Args)
 		if opt.Template {
 			p := fmt.Sprintf("%d", opt.Port)
@@ -59,6 +69,27 @@ func RunWithPort(opt RunOptions) (int, error) {
 				argv[i] = strings.ReplaceAll(argv[i], "{PORT}", p)
 			}
 		}
+	}
+	env := os.Environ()
+
+	if opt.Listener != nil {
+		// LISTEN_PID must be the pid of the process that inherits the fd, which
+		// is only known after the fork: portik re-executes itself, and the
+		// activator sets it before exec'ing the command in place.
+		exe, err := os.Executable()
+		if err != nil {
+			return 0, err
+		}
+		cmd = exec.Command(exe, append(append(slices.Clone(ActivateArgs), "--"), argv...)...)
+		cmd.ExtraFiles = []*os.File{opt.Listener} // fd 3 = SD_LISTEN_FDS_START
+		env = slices.DeleteFunc(env, func(kv string) bool {
+			return strings.HasPrefix(kv, "LISTEN_FDS=") || strings.HasPrefix(kv, "LISTEN_PID=") || strings.HasPrefix(kv, "LISTEN_FDNAMES=")
+		})
+		env = append(env, "LISTEN_FDS=1", "LISTEN_FDNAMES="+cmp.Or(opt.FDName, "portik"))
+		if opt.FDEnv != "" {
+			env = append(env, opt.FDEnv+"=3")
+		}
+	} else {
 		cmd = exec.Command(argv[0], argv[1:]...)
 	}
 
@@ -74,7 +105,6 @@ func RunWithPort(opt RunOptions) (int, error) {
 	}
 
 	// env
-	env := os.Environ()
 	env = append(env, fmt.Sprintf("%s=%d", opt.EnvVarName, opt.Port))
 	env = append(env, opt.ExtraEnv...)
 	cmd.Env = env
@@ -0,0 +1,94 @@
+//go:build !windows
+
+package use
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// childEnv switches the test binary into the helper process that RunWithPort
+// starts through the activator.
+const childEnv = "PORTIK_USE_TEST_CHILD"
+
+func TestMain(m *testing.M) {
+	// RunWithPort re-executes os.Executable (this test binary) as the activator.
+	if args := os.Args[1:]; len(args) > len(ActivateArgs) && slices.Equal(args[:len(ActivateArgs)], ActivateArgs) {
+		if i := slices.Index(args, "--"); i >= 0 {
+			err := Activate(args[i+1:])
+			fmt.Fprintln(os.Stderr, "activate:", err)
+		}
+		os.Exit(127)
+	}
+	if os.Getenv(childEnv) == "1" {
+		if err := checkActivated(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// checkActivated runs in the helper process: it must have been started with the
+// socket activation protocol and own the listener on $PORT as fd 3.
+func checkActivated() error {
+	if v := os.Getenv("LISTEN_FDS"); v != "1" {
+		return fmt.Errorf("LISTEN_FDS=%q, want 1", v)
+	}
+	if v := os.Getenv("LISTEN_PID"); v != strconv.Itoa(os.Getpid()) {
+		return fmt.Errorf("LISTEN_PID=%q, want own pid %d", v, os.Getpid())
+	}
+	if v := os.Getenv("LISTEN_FDNAMES"); v != "web" {
+		return fmt.Errorf("LISTEN_FDNAMES=%q, want web", v)
+	}
+	if v := os.Getenv("SERVER_FD"); v != "3" {
+		return fmt.Errorf("SERVER_FD=%q, want 3", v)
+	}
+	ln, err := net.FileListener(os.NewFile(3, "listener"))
+	if err != nil {
+		return fmt.Errorf("fd 3 is not a listener: %v", err)
+	}
+	defer ln.Close()
+	if got := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port); got != os.Getenv("PORT") {
+		return fmt.Errorf("fd 3 is bound to port %s, want PORT=%s", got, os.Getenv("PORT"))
+	}
+	return nil
+}
+
+func TestRunWithPortListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stderr bytes.Buffer
+	code, err := RunWithPort(RunOptions{
+		Port:     ln.Addr().(*net.TCPAddr).Port,
+		Proto:    "tcp",
+		Args:     []string{exe},
+		Listener: f,
+		FDName:   "web",
+		FDEnv:    "SERVER_FD",
+		ExtraEnv: []string{childEnv + "=1"},
+		Stderr:   &stderr,
+	})
+	if err != nil || code != 0 {
+		t.Fatalf("child exited %d (%v): %s", code, err, stderr.String())
+	}
+}
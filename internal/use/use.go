@@ -32,6 +32,7 @@ func PickFreePort(opt PickOptions) (int, error) {
 		Proto:    opt.Proto,
 		Bind:     opt.Bind,
 		Attempts: opt.Attempts,
+		Leased:   reserve.Leased(opt.Proto),
 	}
 
 	// No range specified → ephemeral
@@ -54,3 +55,41 @@ func PickFreePort(opt PickOptions) (int, error) {
 
 	return reserve.FindFreeInRange(ctx, freeOpt)
 }
+
+// BindFreePort picks a port like PickFreePort and keeps it bound, for handing
+// to the child with RunOptions.Listener. Another process can still take a
+// picked port before it is bound; a lost race is retried with a new pick.
+func BindFreePort(opt PickOptions) (reserve.Reservation, reserve.Handle, error) {
+	if opt.Bind == "" {
+		opt.Bind = "127.0.0.1"
+	}
+	if opt.PortsSpec == "" {
+		// the kernel picks and binds in one step; skip ports leased on another address
+		leased := reserve.Leased(opt.Proto)
+		for range 8 {
+			res, h, err := reserve.Reserve(context.Background(), opt.Proto, opt.Bind, 0)
+			if err != nil {
+				return res, nil, err
+			}
+			if _, ok := leased[res.Port]; !ok {
+				return res, h, nil
+			}
+			defer h.Close() // held until a port is found so it is not handed out again
+		}
+		return reserve.Reservation{}, nil, fmt.Errorf("no free ephemeral port outside leased ports")
+	}
+
+	var lastErr error
+	for range 5 {
+		port, err := PickFreePort(opt)
+		if err != nil {
+			return reserve.Reservation{}, nil, err
+		}
+		res, h, err := reserve.Reserve(context.Background(), opt.Proto, opt.Bind, port)
+		if err == nil {
+			return res, h, nil
+		}
+		lastErr = err
+	}
+	return reserve.Reservation{}, nil, lastErr
+}
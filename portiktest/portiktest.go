@@ -0,0 +1,76 @@
+// Package portiktest hands out ports to tests through the portik port broker.
+//
+// Picking a "free" port by binding port 0 and closing the listener races with
+// every other test doing the same: two parallel tests, or two test binaries
+// run by `go test ./...`, can be handed the same port before either binds it.
+// Ports from this package are leased: the broker never hands a leased port to
+// anyone else (other tests, `portik free`, `portik use`, `portik reserve`)
+// until the test finishes and t.Cleanup releases it.
+//
+//	func TestServer(t *testing.T) {
+//		t.Parallel()
+//		addr := fmt.Sprintf("127.0.0.1:%d", portiktest.Port(t))
+//		srv := &http.Server{Addr: addr}
+//		...
+//	}
+//
+// Set PORTIK_TEST_PORTS (a ports spec such as 20000-29999) to lease from a
+// fixed range instead of the OS ephemeral range; a range outside the ephemeral
+// one also keeps out processes that do not use the broker. Leases are visible
+// in `portik leases`, labelled with the test name, and the leases of a test
+// binary that crashed are dropped once it has exited.
+package portiktest
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pratik-anurag/portik/internal/ports"
+	"github.com/pratik-anurag/portik/internal/reserve"
+)
+
+// Port returns a TCP port on 127.0.0.1 leased to t until it finishes.
+func Port(t testing.TB) int {
+	t.Helper()
+	return lease(t, "tcp")
+}
+
+// UDPPort returns a UDP port on 127.0.0.1 leased to t until it finishes.
+func UDPPort(t testing.TB) int {
+	t.Helper()
+	return lease(t, "udp")
+}
+
+// Ports returns n distinct TCP ports leased to t until it finishes.
+func Ports(t testing.TB, n int) []int {
+	t.Helper()
+	out := make([]int, n)
+	for i := range out {
+		out[i] = lease(t, "tcp")
+	}
+	return out
+}
+
+func lease(t testing.TB, proto string) int {
+	t.Helper()
+	opt := reserve.AllocOptions{Label: t.Name()}
+	opt.Proto = proto
+	if spec := strings.TrimSpace(os.Getenv("PORTIK_TEST_PORTS")); spec != "" {
+		plist, err := ports.ParseSpec(spec)
+		if err != nil {
+			t.Fatalf("portiktest: PORTIK_TEST_PORTS: %v", err)
+		}
+		opt.RangeStart, opt.RangeEnd = plist[0], plist[len(plist)-1]
+	}
+	l, err := reserve.Allocate(opt)
+	if err != nil {
+		t.Fatalf("portiktest: lease %s port: %v", proto, err)
+	}
+	t.Cleanup(func() {
+		if err := reserve.Release(l); err != nil {
+			t.Logf("portiktest: release %d/%s: %v", l.Port, l.Proto, err)
+		}
+	})
+	return l.Port
+}
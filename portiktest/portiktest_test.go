@@ -0,0 +1,80 @@
+package portiktest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/pratik-anurag/portik/internal/reserve"
+)
+
+func TestPortsAreUniqueAndReleased(t *testing.T) {
+	t.Setenv("PORTIK_LEASE_DIR", t.TempDir())
+	t.Setenv("PORTIK_TEST_PORTS", "")
+
+	type key struct {
+		port  int
+		proto string
+	}
+	var mu sync.Mutex
+	seen := map[key]string{}
+	t.Run("group", func(t *testing.T) {
+		for i := range 16 {
+			t.Run(fmt.Sprint(i), func(t *testing.T) {
+				t.Parallel()
+				got := []key{{UDPPort(t), "udp"}}
+				for _, p := range Ports(t, 2) {
+					got = append(got, key{p, "tcp"})
+				}
+				for _, k := range got {
+					mu.Lock()
+					if other, dup := seen[k]; dup && other != t.Name() {
+						t.Errorf("port %d/%s handed to %s and %s", k.port, k.proto, other, t.Name())
+					}
+					seen[k] = t.Name()
+					mu.Unlock()
+				}
+				// a released port may be handed to a later test
+				t.Cleanup(func() {
+					mu.Lock()
+					defer mu.Unlock()
+					for _, k := range got {
+						delete(seen, k)
+					}
+				})
+				ls, err := reserve.Leases()
+				if err != nil {
+					t.Fatal(err)
+				}
+				mine := 0
+				for _, l := range ls {
+					if l.Label == t.Name() && l.Client {
+						mine++
+					}
+				}
+				if mine != 3 {
+					t.Errorf("want 3 leases labelled %s, got %d", t.Name(), mine)
+				}
+			})
+		}
+	})
+	if ls, _ := reserve.Leases(); len(ls) != 0 {
+		t.Fatalf("leases not released on cleanup: %+v", ls)
+	}
+}
+
+func TestRangeIsExhausted(t *testing.T) {
+	t.Setenv("PORTIK_LEASE_DIR", t.TempDir())
+	// a range other processes are unlikely to use
+	t.Setenv("PORTIK_TEST_PORTS", "47211-47213")
+
+	got := Ports(t, 3)
+	if got[0] == got[1] || got[1] == got[2] || got[0] == got[2] {
+		t.Fatalf("ports not distinct: %v", got)
+	}
+	opt := reserve.AllocOptions{}
+	opt.Proto, opt.RangeStart, opt.RangeEnd = "tcp", 47211, 47213
+	if l, err := reserve.Allocate(opt); err == nil {
+		t.Fatalf("allocated leased port %d", l.Port)
+	}
+}